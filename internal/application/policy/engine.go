@@ -0,0 +1,221 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+)
+
+// Request is a single authorization question
+type Request struct {
+	Subject  Attributes `json:"subject"`
+	Action   string     `json:"action"`
+	Resource Attributes `json:"resource"`
+}
+
+// Decision is the answer to a Request. Rule names the deciding rule and is
+// empty when the default applied.
+type Decision struct {
+	Allowed bool        `json:"allowed"`
+	Rule    string      `json:"rule,omitempty"`
+	Reason  string      `json:"reason"`
+	Trace   []RuleTrace `json:"trace,omitempty"`
+}
+
+// RuleTrace records why a rule did or did not match, for Explain
+type RuleTrace struct {
+	Rule    string `json:"rule"`
+	Effect  Effect `json:"effect"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
+}
+
+// Engine evaluates requests against a policy document. A matching deny rule
+// overrides any allow rule; without a match the document default applies.
+type Engine struct {
+	doc Document
+}
+
+// NewEngine loads the policy file named by PolicyConfig.Path. Without a file
+// every request is allowed, leaving authorization to role permissions.
+func NewEngine(cfg *config.Config) (*Engine, error) {
+	if cfg.Policy.Path == "" {
+		return New(Document{Default: Allow})
+	}
+
+	doc, err := Load(cfg.Policy.Path)
+	if err != nil {
+		return nil, err
+	}
+	return New(*doc)
+}
+
+// New creates an engine for an in-memory document
+func New(doc Document) (*Engine, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return &Engine{doc: doc}, nil
+}
+
+// Evaluate decides the request
+func (e *Engine) Evaluate(req Request) Decision {
+	return e.evaluate(req, false)
+}
+
+// Explain decides the request and records the outcome of every rule
+func (e *Engine) Explain(req Request) Decision {
+	return e.evaluate(req, true)
+}
+
+func (e *Engine) evaluate(req Request, trace bool) Decision {
+	var decision Decision
+	var denied, allowed *Rule
+
+	for i := range e.doc.Rules {
+		rule := &e.doc.Rules[i]
+		matched, reason := rule.match(req)
+		if trace {
+			decision.Trace = append(decision.Trace, RuleTrace{
+				Rule:    rule.ID,
+				Effect:  rule.Effect,
+				Matched: matched,
+				Reason:  reason,
+			})
+		}
+		if !matched {
+			continue
+		}
+
+		switch {
+		case rule.Effect == Deny && denied == nil:
+			denied = rule
+		case rule.Effect == Allow && allowed == nil:
+			allowed = rule
+		}
+
+		// The outcome is settled; only an explanation needs the remaining rules
+		if denied != nil && !trace {
+			break
+		}
+	}
+
+	switch {
+	case denied != nil:
+		decision.Rule = denied.ID
+		decision.Reason = fmt.Sprintf("denied by rule %q", denied.ID)
+	case allowed != nil:
+		decision.Allowed = true
+		decision.Rule = allowed.ID
+		decision.Reason = fmt.Sprintf("allowed by rule %q", allowed.ID)
+	default:
+		decision.Allowed = e.doc.Default == Allow
+		decision.Reason = fmt.Sprintf("no rule matched, default is %s", e.doc.Default)
+	}
+	return decision
+}
+
+// match reports whether the rule applies to the request and why
+func (r *Rule) match(req Request) (bool, string) {
+	if !r.coversAction(req.Action) {
+		return false, fmt.Sprintf("action %q is not covered", req.Action)
+	}
+
+	for i, cond := range r.Conditions {
+		if ok, reason := cond.holds(req); !ok {
+			return false, fmt.Sprintf("condition %d not met: %s", i+1, reason)
+		}
+	}
+	return true, "all conditions met"
+}
+
+func (r *Rule) coversAction(action string) bool {
+	for _, pattern := range r.Actions {
+		if pattern == action || pattern == "*" {
+			return true
+		}
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(action, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// holds evaluates the condition and describes the outcome when it fails
+func (c Condition) holds(req Request) (bool, string) {
+	actual, ok := req.lookup(c.Attribute)
+	if !ok {
+		return false, fmt.Sprintf("%s is missing", c.Attribute)
+	}
+	if c.Operator == OpExists {
+		return true, ""
+	}
+
+	expected := c.Value
+	if c.Ref != "" {
+		if expected, ok = req.lookup(c.Ref); !ok {
+			return false, fmt.Sprintf("%s is missing", c.Ref)
+		}
+	}
+
+	var held bool
+	switch c.Operator {
+	case OpEquals:
+		held = equal(actual, expected)
+	case OpNotEquals:
+		held = !equal(actual, expected)
+	case OpIn:
+		held = contains(expected, actual)
+	case OpNotIn:
+		held = !contains(expected, actual)
+	case OpContains:
+		held = contains(actual, expected)
+	case OpNotContains:
+		held = !contains(actual, expected)
+	}
+
+	if !held {
+		return false, fmt.Sprintf("%s %s %v is false for %v", c.Attribute, c.Operator, expected, actual)
+	}
+	return true, ""
+}
+
+// lookup resolves an attribute path such as "subject.role"
+func (req Request) lookup(path string) (interface{}, bool) {
+	scope, name, _ := strings.Cut(path, ".")
+
+	attrs := req.Subject
+	if scope == "resource" {
+		attrs = req.Resource
+	}
+
+	value, ok := attrs[name]
+	return value, ok && value != nil
+}
+
+// equal compares scalars by their string form, so the string "true" from a
+// policy file equals the boolean attribute true
+func equal(a, b interface{}) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// contains reports whether list holds value. list may be a []interface{}
+// from a policy file or a []string attribute.
+func contains(list, value interface{}) bool {
+	switch items := list.(type) {
+	case []interface{}:
+		for _, item := range items {
+			if equal(item, value) {
+				return true
+			}
+		}
+	case []string:
+		for _, item := range items {
+			if equal(item, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
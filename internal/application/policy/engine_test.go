@@ -0,0 +1,169 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func supportPolicy(t *testing.T) *Engine {
+	doc := Document{
+		Default: Deny,
+		Rules: []Rule{
+			{
+				ID:      "support-reads-own-tenant",
+				Effect:  Allow,
+				Actions: []string{"users:read"},
+				Conditions: []Condition{
+					{Attribute: "subject.roles", Operator: OpContains, Value: "support"},
+					{Attribute: "resource.tenant_id", Operator: OpEquals, Ref: "subject.tenant_id"},
+				},
+			},
+			{
+				ID:      "admins",
+				Effect:  Allow,
+				Actions: []string{"users:*"},
+				Conditions: []Condition{
+					{Attribute: "subject.roles", Operator: OpContains, Value: "admin"},
+				},
+			},
+			{
+				ID:      "no-role-changes-without-mfa",
+				Effect:  Deny,
+				Actions: []string{"users:update_role"},
+				Conditions: []Condition{
+					{Attribute: "subject.amr", Operator: OpNotContains, Value: "otp"},
+				},
+			},
+		},
+	}
+	require.NoError(t, doc.checkReplacedAttributes())
+
+	engine, err := New(doc)
+	require.NoError(t, err)
+	return engine
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	engine := supportPolicy(t)
+
+	support := Attributes{"id": "s1", "roles": []string{"user", "support"}, "tenant_id": "acme"}
+	admin := Attributes{"id": "a1", "roles": []string{"admin"}, "amr": []interface{}{"pwd"}}
+
+	tests := []struct {
+		name    string
+		req     Request
+		allowed bool
+		rule    string
+	}{
+		{
+			name:    "SupportInOwnTenant",
+			req:     Request{Subject: support, Action: "users:read", Resource: Attributes{"tenant_id": "acme"}},
+			allowed: true,
+			rule:    "support-reads-own-tenant",
+		},
+		{
+			name:    "SupportInOtherTenant",
+			req:     Request{Subject: support, Action: "users:read", Resource: Attributes{"tenant_id": "globex"}},
+			allowed: false,
+		},
+		{
+			name:    "SupportWithoutTenantAttribute",
+			req:     Request{Subject: support, Action: "users:read", Resource: Attributes{}},
+			allowed: false,
+		},
+		{
+			name:    "AdminByPrefix",
+			req:     Request{Subject: admin, Action: "users:delete", Resource: Attributes{}},
+			allowed: true,
+			rule:    "admins",
+		},
+		{
+			name:    "DenyOverridesAllow",
+			req:     Request{Subject: admin, Action: "users:update_role", Resource: Attributes{}},
+			allowed: false,
+			rule:    "no-role-changes-without-mfa",
+		},
+		{
+			name:    "AdminWithSecondFactor",
+			req:     Request{Subject: Attributes{"roles": []string{"admin"}, "amr": []interface{}{"pwd", "otp"}}, Action: "users:update_role"},
+			allowed: true,
+			rule:    "admins",
+		},
+		{
+			name:    "DefaultDeny",
+			req:     Request{Subject: support, Action: "users:update", Resource: Attributes{"tenant_id": "acme"}},
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Evaluate(tt.req)
+			assert.Equal(t, tt.allowed, decision.Allowed, decision.Reason)
+			assert.Equal(t, tt.rule, decision.Rule)
+			assert.Empty(t, decision.Trace)
+		})
+	}
+}
+
+func TestEngine_Explain(t *testing.T) {
+	engine := supportPolicy(t)
+
+	decision := engine.Explain(Request{
+		Subject:  Attributes{"roles": []string{"support"}, "tenant_id": "acme"},
+		Action:   "users:read",
+		Resource: Attributes{"tenant_id": "globex"},
+	})
+
+	assert.False(t, decision.Allowed)
+	assert.Contains(t, decision.Reason, "default is deny")
+	require.Len(t, decision.Trace, 3)
+	assert.Contains(t, decision.Trace[0].Reason, "condition 2 not met: resource.tenant_id eq acme is false for globex")
+	assert.Contains(t, decision.Trace[1].Reason, "condition 1 not met")
+	assert.Contains(t, decision.Trace[2].Reason, `action "users:read" is not covered`)
+}
+
+func TestLoad(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "policy.json")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		doc, err := Load(write(t, `{"default":"allow","rules":[{"id":"r","effect":"deny","actions":["*"],
+			"conditions":[{"attribute":"subject.active","operator":"eq","value":false}]}]}`))
+		require.NoError(t, err)
+
+		engine, err := New(*doc)
+		require.NoError(t, err)
+		assert.False(t, engine.Evaluate(Request{Subject: Attributes{"active": false}, Action: "users:read"}).Allowed)
+		assert.True(t, engine.Evaluate(Request{Subject: Attributes{"active": true}, Action: "users:read"}).Allowed)
+	})
+
+	invalid := map[string]string{
+		"MissingDefault":  `{"rules":[]}`,
+		"UnknownEffect":   `{"default":"allow","rules":[{"id":"r","effect":"maybe","actions":["*"]}]}`,
+		"DuplicateID":     `{"default":"allow","rules":[{"id":"r","effect":"deny","actions":["*"]},{"id":"r","effect":"deny","actions":["*"]}]}`,
+		"UnknownOperator": `{"default":"allow","rules":[{"id":"r","effect":"deny","actions":["*"],"conditions":[{"attribute":"subject.roles","operator":"like","value":"a"}]}]}`,
+		"BadPath":         `{"default":"allow","rules":[{"id":"r","effect":"deny","actions":["*"],"conditions":[{"attribute":"roles","operator":"contains","value":"a"}]}]}`,
+		"ValueAndRef":     `{"default":"allow","rules":[{"id":"r","effect":"deny","actions":["*"],"conditions":[{"attribute":"subject.roles","operator":"contains","value":"a","ref":"resource.roles"}]}]}`,
+		"Malformed":       `{"default":`,
+		"ReplacedRole":    `{"default":"allow","rules":[{"id":"r","effect":"deny","actions":["*"],"conditions":[{"attribute":"subject.role","operator":"eq","value":"a"}]}]}`,
+	}
+	for name, content := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(write(t, content))
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("ExampleFile", func(t *testing.T) {
+		_, err := Load(filepath.Join("..", "..", "..", "policy.example.json"))
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,196 @@
+// Package policy evaluates attribute-based authorization rules. Rules are
+// declared in a JSON document and decide whether a subject may perform an
+// action on a resource, on top of the role permissions checked by the
+// router.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+// Effect is the outcome a rule imposes when it matches
+type Effect string
+
+const (
+	Allow Effect = "allow"
+	Deny  Effect = "deny"
+)
+
+// Condition operators
+const (
+	OpEquals    = "eq"
+	OpNotEquals = "ne"
+	OpIn        = "in"
+	OpNotIn     = "not_in"
+	// OpContains and OpNotContains test a list attribute such as subject.amr
+	OpContains    = "contains"
+	OpNotContains = "not_contains"
+	OpExists      = "exists"
+)
+
+// Actions checked on user routes
+const (
+	ActionUsersList           = "users:list"
+	ActionUsersRead           = "users:read"
+	ActionUsersUpdate         = "users:update"
+	ActionUsersDelete         = "users:delete"
+	ActionUsersRestore        = "users:restore"
+	ActionUsersChangePassword = "users:change_password"
+	ActionUsersUpdateRole     = "users:update_role"
+	ActionUsersLogout         = "users:logout"
+	ActionUsersUnlock         = "users:unlock"
+)
+
+// Attributes describe a subject or a resource
+type Attributes map[string]interface{}
+
+// Document is the policy file. Default applies when no rule matches.
+type Document struct {
+	Default Effect `json:"default"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Rule matches a request when it covers the action and all of its
+// conditions hold
+type Rule struct {
+	ID          string `json:"id"`
+	Description string `json:"description,omitempty"`
+	Effect      Effect `json:"effect"`
+	// Actions are exact action names, "*", or prefixes ending in "*" such as "users:*"
+	Actions    []string    `json:"actions"`
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// Condition compares the attribute at Attribute, a path such as
+// "subject.role" or "resource.tenant_id", with either the literal Value or
+// the attribute at Ref. A condition on a missing attribute never holds.
+type Condition struct {
+	Attribute string      `json:"attribute"`
+	Operator  string      `json:"operator"`
+	Value     interface{} `json:"value,omitempty"`
+	Ref       string      `json:"ref,omitempty"`
+}
+
+// UserAttributes describes a user as a subject or resource
+func UserAttributes(user *entity.User) Attributes {
+	attrs := Attributes{
+		"id":             user.ID.String(),
+		"email":          user.Email,
+		"roles":          user.Roles,
+		"active":         user.Active,
+		"email_verified": user.EmailVerified,
+	}
+	if user.ActiveOrganizationID != nil {
+		attrs["tenant_id"] = user.ActiveOrganizationID.String()
+	}
+	return attrs
+}
+
+// Load reads a policy document from a JSON file
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	if err := doc.checkReplacedAttributes(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// replacedAttributes were removed from users; rules written against them
+// would silently stop matching
+var replacedAttributes = map[string]string{
+	"subject.role":  "subject.roles",
+	"resource.role": "resource.roles",
+}
+
+func (d *Document) checkReplacedAttributes() error {
+	for _, rule := range d.Rules {
+		for _, cond := range rule.Conditions {
+			for _, path := range []string{cond.Attribute, cond.Ref} {
+				if replacement, ok := replacedAttributes[path]; ok {
+					return fmt.Errorf("policy rule %q: users hold several roles; test %s with %q instead of %s", rule.ID, replacement, OpContains, path)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Validate reports the first structural problem in the document
+func (d *Document) Validate() error {
+	if d.Default != Allow && d.Default != Deny {
+		return fmt.Errorf("policy default must be %q or %q", Allow, Deny)
+	}
+
+	seen := make(map[string]bool, len(d.Rules))
+	for i, rule := range d.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("policy rule %d has no id", i)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("policy rule %q is defined twice", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Effect != Allow && rule.Effect != Deny {
+			return fmt.Errorf("policy rule %q: effect must be %q or %q", rule.ID, Allow, Deny)
+		}
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("policy rule %q has no actions", rule.ID)
+		}
+
+		for j, cond := range rule.Conditions {
+			if err := cond.validate(); err != nil {
+				return fmt.Errorf("policy rule %q condition %d: %w", rule.ID, j+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Condition) validate() error {
+	if !validPath(c.Attribute) {
+		return fmt.Errorf("attribute %q must start with subject. or resource.", c.Attribute)
+	}
+
+	switch c.Operator {
+	case OpExists:
+		return nil
+	case OpEquals, OpNotEquals, OpContains, OpNotContains:
+	case OpIn, OpNotIn:
+		if _, ok := c.Value.([]interface{}); c.Ref == "" && !ok {
+			return fmt.Errorf("operator %q needs a list value", c.Operator)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", c.Operator)
+	}
+
+	if (c.Value == nil) == (c.Ref == "") {
+		return fmt.Errorf("exactly one of value and ref is required")
+	}
+	if c.Ref != "" && !validPath(c.Ref) {
+		return fmt.Errorf("ref %q must start with subject. or resource.", c.Ref)
+	}
+	return nil
+}
+
+func validPath(path string) bool {
+	return (strings.HasPrefix(path, "subject.") || strings.HasPrefix(path, "resource.")) &&
+		!strings.HasSuffix(path, ".")
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAPIKeyRepository is a mock implementation of repository.APIKeyRepository
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	args := m.Called(ctx, id, usedAt)
+	return args.Error(0)
+}
+
+func TestAPIKeyService_Create(t *testing.T) {
+	ctx := context.Background()
+	user, _ := entity.NewUser("ci@example.com", "password123", "CI")
+
+	t.Run("StoresOnlyTheHash", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		keyRepo := new(MockAPIKeyRepository)
+		service := NewAPIKeyService(userRepo, keyRepo)
+
+		userRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+		keyRepo.On("Create", ctx, mock.AnythingOfType("*entity.APIKey")).Return(nil)
+
+		created, err := service.Create(ctx, user.ID, "deploy", []string{"users:read"}, nil)
+
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(created.Secret, apiKeyPrefix))
+		assert.Equal(t, hashOpaqueToken(created.Secret), created.Key.KeyHash)
+		assert.True(t, strings.HasPrefix(created.Secret, created.Key.Prefix))
+		assert.NotContains(t, created.Key.KeyHash, created.Secret)
+		assert.Equal(t, []string{"users:read"}, created.Key.ScopeList())
+	})
+
+	t.Run("ScopeWithSpace", func(t *testing.T) {
+		keyRepo := new(MockAPIKeyRepository)
+		service := NewAPIKeyService(new(MockUserRepository), keyRepo)
+
+		created, err := service.Create(ctx, user.ID, "deploy", []string{"users:read users:write"}, nil)
+
+		assert.Equal(t, errors.ErrInvalidInput, err)
+		assert.Nil(t, created)
+	})
+
+	t.Run("ExpiryInThePast", func(t *testing.T) {
+		keyRepo := new(MockAPIKeyRepository)
+		service := NewAPIKeyService(new(MockUserRepository), keyRepo)
+
+		past := time.Now().Add(-time.Hour)
+		created, err := service.Create(ctx, user.ID, "deploy", nil, &past)
+
+		assert.Equal(t, errors.ErrInvalidInput, err)
+		assert.Nil(t, created)
+		keyRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	user, _ := entity.NewUser("ci@example.com", "password123", "CI")
+	secret := apiKeyPrefix + "0123456789abcdef"
+
+	newKey := func() *entity.APIKey {
+		return entity.NewAPIKey(user.ID, "deploy", secret[:apiKeyDisplayLength], hashOpaqueToken(secret), []string{"users:read", "users:write"}, nil)
+	}
+
+	t.Run("ReturnsAccessTokenShapedClaims", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		keyRepo := new(MockAPIKeyRepository)
+		service := NewAPIKeyService(userRepo, keyRepo)
+
+		key := newKey()
+		keyRepo.On("FindByHash", ctx, hashOpaqueToken(secret)).Return(key, nil)
+		keyRepo.On("TouchLastUsed", ctx, key.ID, mock.AnythingOfType("time.Time")).Return(nil)
+		userRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+
+		claims, err := service.Authenticate(ctx, secret)
+
+		require.NoError(t, err)
+		sub, err := claims.GetSubject()
+		require.NoError(t, err)
+		assert.Equal(t, user.ID.String(), sub)
+		assert.Equal(t, []interface{}{entity.RoleUser}, claims["roles"])
+		assert.Equal(t, []interface{}{AuthMethodAPIKey}, claims["amr"])
+		assert.Equal(t, "users:read users:write", claims["scope"])
+		assert.Equal(t, key.ID.String(), claims["key_id"])
+		keyRepo.AssertExpectations(t)
+	})
+
+	t.Run("RecentlyUsedKeyIsNotTouched", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		keyRepo := new(MockAPIKeyRepository)
+		service := NewAPIKeyService(userRepo, keyRepo)
+
+		key := newKey()
+		usedAt := time.Now()
+		key.LastUsedAt = &usedAt
+		keyRepo.On("FindByHash", ctx, hashOpaqueToken(secret)).Return(key, nil)
+		userRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+
+		_, err := service.Authenticate(ctx, secret)
+
+		require.NoError(t, err)
+		keyRepo.AssertNotCalled(t, "TouchLastUsed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		expired := newKey()
+		past := time.Now().Add(-time.Minute)
+		expired.ExpiresAt = &past
+
+		revoked := newKey()
+		revoked.RevokedAt = &past
+
+		tests := []struct {
+			name string
+			key  *entity.APIKey
+			err  error
+		}{
+			{"Expired", expired, errors.ErrTokenExpired},
+			{"Revoked", revoked, errors.ErrTokenRevoked},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				keyRepo := new(MockAPIKeyRepository)
+				service := NewAPIKeyService(new(MockUserRepository), keyRepo)
+				keyRepo.On("FindByHash", ctx, hashOpaqueToken(secret)).Return(tt.key, nil)
+
+				claims, err := service.Authenticate(ctx, secret)
+
+				assert.Equal(t, tt.err, err)
+				assert.Nil(t, claims)
+			})
+		}
+	})
+
+	t.Run("InactiveUser", func(t *testing.T) {
+		userRepo := new(MockUserRepository)
+		keyRepo := new(MockAPIKeyRepository)
+		service := NewAPIKeyService(userRepo, keyRepo)
+
+		inactive := *user
+		inactive.Active = false
+		keyRepo.On("FindByHash", ctx, hashOpaqueToken(secret)).Return(newKey(), nil)
+		userRepo.On("FindByID", ctx, user.ID).Return(&inactive, nil)
+
+		_, err := service.Authenticate(ctx, secret)
+
+		assert.Equal(t, errors.ErrUnauthorized, err)
+	})
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+)
+
+type AuditService interface {
+	// Record stores an event; subjectID is nil for actions that don't target
+	// a user
+	Record(ctx context.Context, action string, actorID uuid.UUID, subjectID *uuid.UUID, client ClientInfo) error
+	List(ctx context.Context, page, limit int) ([]*entity.AuditEvent, int64, error)
+}
+
+type auditService struct {
+	auditRepo repository.AuditEventRepository
+}
+
+func NewAuditService(auditRepo repository.AuditEventRepository) AuditService {
+	return &auditService{
+		auditRepo: auditRepo,
+	}
+}
+
+func (s *auditService) Record(ctx context.Context, action string, actorID uuid.UUID, subjectID *uuid.UUID, client ClientInfo) error {
+	return s.auditRepo.Create(ctx, entity.NewAuditEvent(action, actorID, subjectID, client.IP, client.UserAgent))
+}
+
+func (s *auditService) List(ctx context.Context, page, limit int) ([]*entity.AuditEvent, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	return s.auditRepo.List(ctx, page, limit)
+}
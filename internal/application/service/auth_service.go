@@ -2,109 +2,855 @@ package service
 
 import (
 	"context"
+	stdErrors "errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/policy"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
 	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/security"
+	"github.com/rs/zerolog/log"
 )
 
+// refreshTokenSize is the number of random bytes in an opaque refresh token
+const refreshTokenSize = 32
+
+// sessionTouchInterval limits how often the last activity of a session is written
+const sessionTouchInterval = time.Minute
+
+// Values of the token_use claim, which keeps special-purpose tokens from
+// being accepted as access tokens
+const (
+	tokenUseAccess = "access"
+	tokenUseMFA    = "mfa"
+	tokenUseAPIKey = "api_key"
+)
+
+// Authentication method references for the amr claim (RFC 8176)
+const (
+	AuthMethodPassword = "pwd"
+	AuthMethodOTP      = "otp"
+	// AuthMethodFederated marks a login through an external identity
+	// provider; RFC 8176 registers no value for it
+	AuthMethodFederated = "fed"
+	// AuthMethodMagicLink marks a login with a link sent by email; RFC 8176
+	// registers no value for it
+	AuthMethodMagicLink = "email"
+	// AuthMethodAPIKey marks a request authenticated with a personal API key
+	AuthMethodAPIKey = "api_key"
+)
+
+// TenantClaim carries the id of the organization that scopes a token
+const TenantClaim = "tid"
+
+// ClientIDClaim identifies the OAuth client a token was issued to. Tokens
+// from the client credentials grant have no user; their subject is the
+// client itself.
+const ClientIDClaim = "client_id"
+
+// ActorClaim identifies the user acting on behalf of the subject of an
+// impersonation token (RFC 8693)
+const ActorClaim = "act"
+
+// TokenPair is the result of a successful login or refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+	// Scopes restrict the access token; empty means it is not restricted
+	Scopes []string
+}
+
+// LoginResult holds either the issued tokens or, when the user has a second
+// factor enabled, a short-lived challenge token to be exchanged with VerifyMFA
+type LoginResult struct {
+	Tokens       *TokenPair
+	MFAToken     string
+	MFAExpiresIn time.Duration
+}
+
 type AuthService interface {
-	Login(ctx context.Context, email, password string) (string, error)
-	ValidateToken(token string) (*jwt.Token, error)
-	RefreshToken(token string) (string, error)
+	Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error)
+	VerifyMFA(ctx context.Context, mfaToken, code string, client ClientInfo) (*TokenPair, error)
+	// AuthenticateUser completes a login for a user whose first factor was
+	// verified elsewhere, such as an external identity provider. It applies
+	// the same account checks and MFA challenge as Login.
+	AuthenticateUser(ctx context.Context, user *entity.User, authMethods []string, client ClientInfo) (*LoginResult, error)
+	ValidateToken(ctx context.Context, token string) (*jwt.Token, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Logout(ctx context.Context, claims jwt.MapClaims) error
+	LogoutAll(ctx context.Context, userID uuid.UUID) error
+	// Impersonate issues a short-lived access token for target on behalf of
+	// the actor. A restricted actor's token carries its scopes over; API keys
+	// can't impersonate. No refresh token is issued.
+	Impersonate(ctx context.Context, actor jwt.MapClaims, target *entity.User) (*TokenPair, error)
+	// IssueClientToken issues an access token to an authenticated OAuth
+	// client. No refresh token is issued.
+	IssueClientToken(ctx context.Context, client *entity.OAuthClient, scopes []string) (*TokenPair, error)
+	// Downscope issues an access token restricted to scopes for the principal
+	// of claims. A restricted caller can only narrow its scopes further, and
+	// the new token never outlives the caller's. No refresh token is issued.
+	Downscope(ctx context.Context, claims jwt.MapClaims, scopes []string) (*TokenPair, error)
+	JWKS() security.JWKS
 }
 
 type authService struct {
-	config   *config.Config
-	userRepo repository.UserRepository
+	config           *config.Config
+	keys             *security.KeyRing
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	revocationRepo   repository.TokenRevocationRepository
+	sessionRepo      repository.SessionRepository
+	mfaService       MFAService
+	lockoutService   LockoutService
 }
 
-func NewAuthService(cfg *config.Config, userRepo repository.UserRepository) AuthService {
+func NewAuthService(
+	cfg *config.Config,
+	keys *security.KeyRing,
+	userRepo repository.UserRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	revocationRepo repository.TokenRevocationRepository,
+	sessionRepo repository.SessionRepository,
+	mfaService MFAService,
+	lockoutService LockoutService,
+) AuthService {
 	return &authService{
-		config:   cfg,
-		userRepo: userRepo,
+		config:           cfg,
+		keys:             keys,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		revocationRepo:   revocationRepo,
+		sessionRepo:      sessionRepo,
+		mfaService:       mfaService,
+		lockoutService:   lockoutService,
 	}
 }
 
-func (s *authService) Login(ctx context.Context, email, password string) (string, error) {
+func (s *authService) Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error) {
+	if err := s.lockoutService.CheckClient(client); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil {
-		return "", errors.ErrInvalidCredential
+		if err := s.lockoutService.RecordFailure(ctx, nil, client); err != nil {
+			return nil, err
+		}
+		return nil, errors.ErrInvalidCredential
+	}
+
+	// The password is checked before the lock, and a locked account answers
+	// like a wrong password, so neither tells a guesser that it exists
+	passwordErr := user.ComparePassword(password)
+	if err := s.lockoutService.CheckAccount(user); err != nil {
+		return nil, errors.ErrInvalidCredential
 	}
 
-	if err := user.ComparePassword(password); err != nil {
-		return "", errors.ErrInvalidCredential
+	if passwordErr != nil {
+		if err := s.lockoutService.RecordFailure(ctx, user, client); err != nil {
+			return nil, err
+		}
+		return nil, errors.ErrInvalidCredential
+	}
+
+	// Upgrade hashes made with an older algorithm or weaker parameters while
+	// the password is at hand; the login doesn't depend on it
+	if user.PasswordNeedsRehash() {
+		if err := s.rehashPassword(ctx, user, password); err != nil {
+			log.Warn().Err(err).Str("user_id", user.ID.String()).Msg("Failed to upgrade password hash")
+		}
 	}
 
+	return s.completeLogin(ctx, user, []string{AuthMethodPassword}, client)
+}
+
+func (s *authService) rehashPassword(ctx context.Context, user *entity.User, password string) error {
+	if err := user.UpdatePassword(password); err != nil {
+		return err
+	}
+	return s.userRepo.Update(ctx, user)
+}
+
+func (s *authService) AuthenticateUser(ctx context.Context, user *entity.User, authMethods []string, client ClientInfo) (*LoginResult, error) {
+	if err := s.lockoutService.CheckAccount(user); err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, authMethods, client)
+}
+
+// completeLogin runs the checks shared by every kind of first factor and
+// either issues tokens or, when the user has a second factor, a challenge
+func (s *authService) completeLogin(ctx context.Context, user *entity.User, authMethods []string, client ClientInfo) (*LoginResult, error) {
 	if !user.Active {
-		return "", errors.ErrUnauthorized
+		return nil, errors.ErrUnauthorized
 	}
 
-	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":   user.ID.String(),
-		"email": user.Email,
-		"role":  user.Role,
-		"exp":   time.Now().Add(s.config.JWT.ExpirationHours).Unix(),
-		"iat":   time.Now().Unix(),
-	})
+	if s.config.Auth.RequireVerifiedEmail && !user.EmailVerified {
+		return nil, errors.ErrEmailNotVerified
+	}
 
-	tokenString, err := token.SignedString([]byte(s.config.JWT.Secret))
+	mfaEnabled, err := s.mfaService.IsEnabled(ctx, user.ID)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if mfaEnabled {
+		mfaToken, err := s.generateMFAToken(user, authMethods)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFAToken: mfaToken, MFAExpiresIn: s.config.MFA.ChallengeDuration}, nil
 	}
 
-	return tokenString, nil
+	if err := s.lockoutService.RecordSuccess(ctx, user); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.startSession(ctx, user, authMethods, client)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Tokens: tokens}, nil
 }
 
-func (s *authService) ValidateToken(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+func (s *authService) VerifyMFA(ctx context.Context, mfaToken, code string, client ClientInfo) (*TokenPair, error) {
+	_, claims, err := s.parseToken(mfaToken, tokenUseMFA)
+	if err != nil {
+		return nil, err
+	}
+
+	jti, _ := claims["jti"].(string)
+	revoked, err := s.revocationRepo.IsTokenRevoked(ctx, jti)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.ErrTokenRevoked
+	}
+
+	userID, err := claimUserID(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.lockoutService.CheckClient(client); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	if err := s.lockoutService.CheckAccount(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaService.Verify(ctx, userID, code); err != nil {
+		if err == errors.ErrInvalidMFACode {
+			if err := s.lockoutService.RecordFailure(ctx, user, client); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	// The challenge is single-use
+	exp, _ := claims.GetExpirationTime()
+	if err := s.revocationRepo.RevokeToken(ctx, entity.NewRevokedToken(jti, userID, exp.Time)); err != nil {
+		return nil, err
+	}
+
+	if !user.Active {
+		return nil, errors.ErrUnauthorized
+	}
+
+	if err := s.lockoutService.RecordSuccess(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// The challenge remembers how the first factor was verified
+	authMethods := []string{AuthMethodPassword}
+	if amr, ok := claims["amr"].([]interface{}); ok {
+		authMethods = authMethods[:0]
+		for _, method := range amr {
+			if m, ok := method.(string); ok {
+				authMethods = append(authMethods, m)
+			}
+		}
+	}
+
+	return s.startSession(ctx, user, append(authMethods, AuthMethodOTP), client)
+}
+
+func (s *authService) ValidateToken(ctx context.Context, tokenString string) (*jwt.Token, error) {
+	token, claims, err := s.parseToken(tokenString, tokenUseAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRevocation(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	stored, err := s.refreshTokenRepo.FindByHash(ctx, hashOpaqueToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.IsRevoked() {
+		return nil, errors.ErrTokenRevoked
+	}
+
+	// A refresh token that was already exchanged is being replayed, so the
+	// whole family is considered compromised.
+	if stored.IsUsed() {
+		return nil, s.revokeFamily(ctx, stored.FamilyID)
+	}
+
+	if stored.IsExpired() {
+		return nil, errors.ErrTokenExpired
+	}
+
+	if err := s.refreshTokenRepo.MarkUsed(ctx, stored.ID); err != nil {
+		if err == errors.ErrTokenReused {
+			return nil, s.revokeFamily(ctx, stored.FamilyID)
+		}
+		return nil, err
+	}
+
+	// Reload the user so role and status changes are reflected in the new token
+	user, err := s.userRepo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		if err == errors.ErrUserNotFound {
 			return nil, errors.ErrInvalidToken
 		}
-		return []byte(s.config.JWT.Secret), nil
+		return nil, err
+	}
+
+	if !user.Active {
+		return nil, errors.ErrUnauthorized
+	}
+
+	tokens, err := s.issueTokenPair(ctx, user, stored.FamilyID, strings.Fields(stored.AuthMethods))
+	if err != nil {
+		return nil, err
+	}
+
+	// A refresh extends the session along with its refresh token
+	now := time.Now()
+	if err := s.sessionRepo.Touch(ctx, stored.FamilyID, now, now.Add(s.config.JWT.RefreshDuration)); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// JWKS returns the public keys that verify access tokens, including retired
+// keys still in their grace period. Symmetric keys are never published.
+func (s *authService) JWKS() security.JWKS {
+	return s.keys.JWKS()
+}
+
+func (s *authService) Logout(ctx context.Context, claims jwt.MapClaims) error {
+	userID, err := claimUserID(claims)
+	if err != nil {
+		return err
+	}
+
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		exp, err := claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			return errors.ErrInvalidToken
+		}
+		if err := s.revocationRepo.RevokeToken(ctx, entity.NewRevokedToken(jti, userID, exp.Time)); err != nil {
+			return err
+		}
+	}
+
+	// The session id ties the access token to its refresh token family. A
+	// down-scoped token shares the session it was derived from and only
+	// revokes itself.
+	if _, restricted := claims["scope"]; restricted {
+		return nil
+	}
+	if sid, ok := claims["sid"].(string); ok {
+		familyID, err := uuid.Parse(sid)
+		if err != nil {
+			return errors.ErrInvalidToken
+		}
+		if err := s.refreshTokenRepo.RevokeFamily(ctx, familyID); err != nil {
+			return err
+		}
+		// Tokens issued before sessions were recorded have none to revoke
+		if err := s.sessionRepo.Revoke(ctx, userID, familyID); err != nil && err != errors.ErrSessionNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *authService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.revocationRepo.RevokeUserTokens(ctx, userID, time.Now())
+}
+
+func (s *authService) Impersonate(ctx context.Context, actor jwt.MapClaims, target *entity.User) (*TokenPair, error) {
+	// A derived token would outlive the revocation of its API key
+	if tokenUse, _ := actor["token_use"].(string); tokenUse == tokenUseAPIKey {
+		return nil, errors.ErrForbidden
+	}
+
+	actorID, err := claimUserID(actor)
+	if err != nil {
+		return nil, err
+	}
+	actorEmail, _ := actor["email"].(string)
+	authMethods, _ := actor["amr"].([]interface{})
+
+	claims := jwt.MapClaims{
+		"jti":   uuid.NewString(),
+		"sub":   target.ID.String(),
+		"email": target.Email,
+		"roles": target.Roles,
+		// The second factor, if any, was verified by the actor
+		"amr":       authMethods,
+		"token_use": tokenUseAccess,
+		ActorClaim: map[string]interface{}{
+			"sub":   actorID.String(),
+			"email": actorEmail,
+		},
+		"exp": time.Now().Add(s.config.JWT.ImpersonationDuration).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	if target.ActiveOrganizationID != nil {
+		claims[TenantClaim] = target.ActiveOrganizationID.String()
+	}
+	// A restricted actor stays restricted while acting as the target
+	scope, restricted := actor["scope"].(string)
+	if restricted {
+		claims["scope"] = scope
+	}
+
+	accessToken, err := s.keys.Active().Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresIn:   s.config.JWT.ImpersonationDuration,
+		Scopes:      strings.Fields(scope),
+	}, nil
+}
+
+func (s *authService) IssueClientToken(ctx context.Context, client *entity.OAuthClient, scopes []string) (*TokenPair, error) {
+	accessToken, err := s.keys.Active().Sign(jwt.MapClaims{
+		"jti":         uuid.NewString(),
+		"sub":         client.ID.String(),
+		ClientIDClaim: client.ID.String(),
+		// Space separated, as in OAuth 2.0 (RFC 8693 section 4.2)
+		"scope":     strings.Join(scopes, " "),
+		"token_use": tokenUseAccess,
+		"exp":       time.Now().Add(s.config.JWT.ClientTokenDuration).Unix(),
+		"iat":       time.Now().Unix(),
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresIn:   s.config.JWT.ClientTokenDuration,
+		Scopes:      scopes,
+	}, nil
+}
+
+func (s *authService) Downscope(ctx context.Context, claims jwt.MapClaims, scopes []string) (*TokenPair, error) {
+	// A derived token would outlive the revocation of its API key
+	if tokenUse, _ := claims["token_use"].(string); tokenUse == tokenUseAPIKey {
+		return nil, errors.ErrForbidden
+	}
+
+	if len(scopes) == 0 {
+		return nil, errors.ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !entity.IsValidScope(scope) || !TokenAllowsScope(claims, scope) {
+			return nil, errors.ErrInvalidScope
+		}
+	}
+
+	expiresAt := time.Now().Add(s.config.JWT.ExpirationHours)
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil && exp.Before(expiresAt) {
+		expiresAt = exp.Time
+	}
 
+	restrictedClaims := jwt.MapClaims{
+		"jti":       uuid.NewString(),
+		"scope":     strings.Join(scopes, " "),
+		"token_use": tokenUseAccess,
+		"exp":       expiresAt.Unix(),
+		"iat":       time.Now().Unix(),
+	}
+	// The principal, its session and its tenant carry over; role is the
+	// claim of tokens issued before users had several roles
+	for _, name := range []string{"sub", "email", "roles", "role", "amr", "sid", TenantClaim, ClientIDClaim, ActorClaim} {
+		if value, ok := claims[name]; ok {
+			restrictedClaims[name] = value
+		}
+	}
+
+	accessToken, err := s.keys.Active().Sign(restrictedClaims)
 	if err != nil {
-		if err == jwt.ErrTokenExpired {
-			return nil, errors.ErrTokenExpired
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresIn:   time.Until(expiresAt).Round(time.Second),
+		Scopes:      scopes,
+	}, nil
+}
+
+// checkRevocation rejects tokens denied individually by jti, by a revoked
+// session or in bulk by a logout of all sessions. Impersonation tokens are
+// also rejected when the actor logs out of all sessions.
+func (s *authService) checkRevocation(ctx context.Context, claims jwt.MapClaims) error {
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		revoked, err := s.revocationRepo.IsTokenRevoked(ctx, jti)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return errors.ErrTokenRevoked
 		}
-		return nil, errors.ErrInvalidToken
 	}
 
-	if !token.Valid {
-		return nil, errors.ErrInvalidToken
+	userID, err := claimUserID(claims)
+	if err != nil {
+		return err
+	}
+	if err := s.checkUserRevocation(ctx, claims, userID); err != nil {
+		return err
 	}
 
-	return token, nil
+	if sid, ok := claims["sid"].(string); ok {
+		if err := s.checkSession(ctx, sid); err != nil {
+			return err
+		}
+	}
+
+	if actorID, ok, err := ClaimActorID(claims); err != nil {
+		return err
+	} else if ok {
+		return s.checkUserRevocation(ctx, claims, actorID)
+	}
+
+	return nil
+}
+
+// checkUserRevocation rejects tokens issued before the user's last logout of
+// all sessions. iat only has second precision, so a token issued in the same
+// second as a bulk revocation is rejected as well.
+func (s *authService) checkUserRevocation(ctx context.Context, claims jwt.MapClaims, userID uuid.UUID) error {
+	revokedBefore, err := s.revocationRepo.UserTokensRevokedBefore(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if revokedBefore.IsZero() {
+		return nil
+	}
+
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil || iat.Unix() <= revokedBefore.Unix() {
+		return errors.ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// checkSession rejects tokens of a revoked or expired session and records the
+// session's activity. Tokens issued before sessions were recorded have no
+// session and are left to expire.
+func (s *authService) checkSession(ctx context.Context, sid string) error {
+	sessionID, err := uuid.Parse(sid)
+	if err != nil {
+		return errors.ErrInvalidToken
+	}
+
+	session, err := s.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		if err == errors.ErrSessionNotFound {
+			return nil
+		}
+		return err
+	}
+	if session.IsRevoked() || session.IsExpired() {
+		return errors.ErrTokenRevoked
+	}
+
+	// Activity is informational, so it is written at most once per interval
+	// and a failed write doesn't fail the request
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessionRepo.Touch(ctx, session.ID, now, time.Time{}); err != nil {
+			log.Warn().Err(err).Str("session_id", session.ID.String()).Msg("Failed to record session activity")
+		}
+	}
+
+	return nil
+}
+
+// startSession records a new session for the client and issues the first
+// token pair of its refresh token family
+func (s *authService) startSession(ctx context.Context, user *entity.User, authMethods []string, client ClientInfo) (*TokenPair, error) {
+	session := entity.NewSession(uuid.New(), user.ID, client.UserAgent, client.IP, s.config.JWT.RefreshDuration)
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokenPair(ctx, user, session.ID, authMethods)
+}
+
+// issueTokenPair signs a new access token and persists a new refresh token in the given family
+func (s *authService) issueTokenPair(ctx context.Context, user *entity.User, familyID uuid.UUID, authMethods []string) (*TokenPair, error) {
+	accessToken, err := s.generateAccessToken(user, familyID, authMethods)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateOpaqueToken(refreshTokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := entity.NewRefreshToken(user.ID, familyID, hashOpaqueToken(refreshToken), s.config.JWT.RefreshDuration)
+	stored.AuthMethods = strings.Join(authMethods, " ")
+	if err := s.refreshTokenRepo.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.config.JWT.ExpirationHours,
+	}, nil
 }
 
-func (s *authService) RefreshToken(tokenString string) (string, error) {
-	token, err := s.ValidateToken(tokenString)
-	if err != nil && err != errors.ErrTokenExpired {
-		return "", err
+func (s *authService) generateAccessToken(user *entity.User, familyID uuid.UUID, authMethods []string) (string, error) {
+	claims := UserClaims(user, authMethods)
+	claims["jti"] = uuid.NewString()
+	claims["sid"] = familyID.String()
+	claims["exp"] = time.Now().Add(s.config.JWT.ExpirationHours).Unix()
+	claims["iat"] = time.Now().Unix()
+	return s.keys.Active().Sign(claims)
+}
+
+// UserClaims are the claims that describe the user in their access tokens
+func UserClaims(user *entity.User, authMethods []string) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"sub":       user.ID.String(),
+		"email":     user.Email,
+		"roles":     user.Roles,
+		"amr":       authMethods,
+		"token_use": tokenUseAccess,
+	}
+	if user.ActiveOrganizationID != nil {
+		claims[TenantClaim] = user.ActiveOrganizationID.String()
+	}
+	return claims
+}
+
+// generateMFAToken issues the challenge that proves the first factor succeeded
+func (s *authService) generateMFAToken(user *entity.User, authMethods []string) (string, error) {
+	return s.keys.Active().Sign(jwt.MapClaims{
+		"jti":       uuid.NewString(),
+		"sub":       user.ID.String(),
+		"amr":       authMethods,
+		"token_use": tokenUseMFA,
+		"exp":       time.Now().Add(s.config.MFA.ChallengeDuration).Unix(),
+		"iat":       time.Now().Unix(),
+	})
+}
+
+// parseToken verifies a token's signature and expiry and checks that it was
+// issued for the expected use
+func (s *authService) parseToken(tokenString, use string) (*jwt.Token, jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, s.verificationKey)
+	if err != nil {
+		if stdErrors.Is(err, jwt.ErrTokenExpired) {
+			return nil, nil, errors.ErrTokenExpired
+		}
+		return nil, nil, errors.ErrInvalidToken
+	}
+
+	if !token.Valid {
+		return nil, nil, errors.ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", errors.ErrInvalidToken
+		return nil, nil, errors.ErrInvalidToken
 	}
 
-	// Create new token
-	newToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":   claims["sub"],
-		"email": claims["email"],
-		"role":  claims["role"],
-		"exp":   time.Now().Add(s.config.JWT.ExpirationHours).Unix(),
-		"iat":   time.Now().Unix(),
-	})
+	// Access tokens issued before token_use was introduced carry no claim
+	tokenUse, _ := claims["token_use"].(string)
+	if tokenUse != use && !(tokenUse == "" && use == tokenUseAccess) {
+		return nil, nil, errors.ErrInvalidToken
+	}
+
+	return token, claims, nil
+}
+
+// verificationKey selects the key for a token by its kid header. The token's
+// alg must match the key's algorithm, which rules out "none" and HMAC tokens
+// forged with a published public key.
+func (s *authService) verificationKey(token *jwt.Token) (interface{}, error) {
+	key := s.keys.Active()
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok = s.keys.Lookup(kid); !ok {
+			return nil, errors.ErrInvalidToken
+		}
+	}
+
+	if token.Method.Alg() != key.Algorithm() {
+		return nil, errors.ErrInvalidToken
+	}
+	return key.VerifyKey(), nil
+}
+
+// revokeFamily revokes every refresh token in the family and reports the reuse
+func (s *authService) revokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+	return errors.ErrTokenReused
+}
 
-	tokenString, err = newToken.SignedString([]byte(s.config.JWT.Secret))
+func claimUserID(claims jwt.MapClaims) (uuid.UUID, error) {
+	sub, err := claims.GetSubject()
 	if err != nil {
-		return "", err
+		return uuid.Nil, errors.ErrInvalidToken
 	}
 
-	return tokenString, nil
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return uuid.Nil, errors.ErrInvalidToken
+	}
+	return userID, nil
+}
+
+// TokenAllowsScope reports whether the claims permit the scope. Tokens
+// without a scope claim are not restricted; the claim is space separated
+// (RFC 6749 section 3.3).
+func TokenAllowsScope(claims jwt.MapClaims, scope string) bool {
+	granted, restricted := claims["scope"].(string)
+	if !restricted {
+		return true
+	}
+	for _, s := range strings.Fields(granted) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ClaimClientID returns the id of the OAuth client a token was issued to.
+// ok is false for tokens that belong to a user.
+func ClaimClientID(claims jwt.MapClaims) (clientID uuid.UUID, ok bool) {
+	id, _ := claims[ClientIDClaim].(string)
+	clientID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return clientID, true
+}
+
+// ClaimRoles returns the names of the roles of the token's user. Tokens
+// issued before users had several roles carry a single role claim instead.
+func ClaimRoles(claims jwt.MapClaims) []string {
+	switch roles := claims["roles"].(type) {
+	case []string:
+		return roles
+	case []interface{}:
+		names := make([]string, 0, len(roles))
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	if role, ok := claims["role"].(string); ok {
+		return []string{role}
+	}
+	return nil
+}
+
+// SubjectAttributes describes the principal of a token for policy
+// evaluation. The token subject becomes "id".
+func SubjectAttributes(claims jwt.MapClaims) policy.Attributes {
+	attrs := policy.Attributes{}
+	for _, name := range []string{"email", "amr", "scope", "token_use"} {
+		if value, ok := claims[name]; ok {
+			attrs[name] = value
+		}
+	}
+	if roles := ClaimRoles(claims); roles != nil {
+		attrs["roles"] = roles
+	}
+	if sub, err := claims.GetSubject(); err == nil {
+		attrs["id"] = sub
+	}
+	if tid, ok := claims[TenantClaim]; ok {
+		attrs["tenant_id"] = tid
+	}
+	if clientID, ok := ClaimClientID(claims); ok {
+		attrs["client_id"] = clientID.String()
+	}
+	if actorID, ok, err := ClaimActorID(claims); err == nil && ok {
+		attrs["actor_id"] = actorID.String()
+	}
+	return attrs
+}
+
+// ClaimActorID returns the id of the user acting through an impersonation
+// token. ok is false for tokens used by their own subject.
+func ClaimActorID(claims jwt.MapClaims) (actorID uuid.UUID, ok bool, err error) {
+	act, present := claims[ActorClaim]
+	if !present {
+		return uuid.Nil, false, nil
+	}
+
+	actor, isMap := act.(map[string]interface{})
+	if !isMap {
+		return uuid.Nil, false, errors.ErrInvalidToken
+	}
+	sub, _ := actor["sub"].(string)
+	actorID, err = uuid.Parse(sub)
+	if err != nil {
+		return uuid.Nil, false, errors.ErrInvalidToken
+	}
+	return actorID, true, nil
 }
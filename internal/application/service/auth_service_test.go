@@ -0,0 +1,643 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/cache"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MockRefreshTokenRepository is a mock implementation of repository.RefreshTokenRepository
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) Create(ctx context.Context, token *entity.RefreshToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	args := m.Called(ctx, familyID)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// MockTokenRevocationRepository is a mock implementation of repository.TokenRevocationRepository
+type MockTokenRevocationRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRevocationRepository) RevokeToken(ctx context.Context, token *entity.RevokedToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevocationRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	args := m.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRevocationRepository) RevokeUserTokens(ctx context.Context, userID uuid.UUID, before time.Time) error {
+	args := m.Called(ctx, userID, before)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevocationRepository) UserTokensRevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+// MockSessionRepository is a mock implementation of repository.SessionRepository
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) Create(ctx context.Context, session *entity.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.Session, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]*entity.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) Touch(ctx context.Context, id uuid.UUID, seenAt, expiresAt time.Time) error {
+	args := m.Called(ctx, id, seenAt, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// MockMFAService is a mock implementation of MFAService
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*TOTPEnrollment, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TOTPEnrollment), args.Error(1)
+}
+
+func (m *MockMFAService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+// MockLockoutService is a mock implementation of LockoutService
+type MockLockoutService struct {
+	mock.Mock
+}
+
+func (m *MockLockoutService) CheckClient(client ClientInfo) error {
+	args := m.Called(client)
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) CheckAccount(user *entity.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) RecordFailure(ctx context.Context, user *entity.User, client ClientInfo) error {
+	args := m.Called(ctx, user, client)
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) RecordSuccess(ctx context.Context, user *entity.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) Status(ctx context.Context, userID uuid.UUID) (*LockStatus, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*LockStatus), args.Error(1)
+}
+
+func (m *MockLockoutService) Unlock(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// newAllowingLockoutService returns a lockout service that never throttles
+func newAllowingLockoutService() *MockLockoutService {
+	lockout := new(MockLockoutService)
+	lockout.On("CheckClient", mock.Anything).Return(nil)
+	lockout.On("CheckAccount", mock.Anything).Return(nil)
+	lockout.On("RecordFailure", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	lockout.On("RecordSuccess", mock.Anything, mock.Anything).Return(nil)
+	return lockout
+}
+
+// newTestSessionRepository records sessions without checking them; tokens
+// are treated as issued before sessions were tracked
+func newTestSessionRepository() *MockSessionRepository {
+	sessions := new(MockSessionRepository)
+	sessions.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+	sessions.On("FindByID", mock.Anything, mock.Anything).Return(nil, errors.ErrSessionNotFound).Maybe()
+	sessions.On("Touch", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	sessions.On("Revoke", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	sessions.On("RevokeAllForUser", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return sessions
+}
+
+func newDisabledMFAService() *MockMFAService {
+	mfa := new(MockMFAService)
+	mfa.On("IsEnabled", mock.Anything, mock.Anything).Return(false, nil)
+	return mfa
+}
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		JWT: config.JWTConfig{
+			Secret:           "test-secret",
+			ExpirationHours:  time.Hour,
+			RefreshDuration:  24 * time.Hour,
+			SigningAlgorithm: "HS256",
+		},
+		MFA: config.MFAConfig{
+			ChallengeDuration: 5 * time.Minute,
+		},
+		Mail: config.MailConfig{
+			Workers:   1,
+			QueueSize: 10,
+		},
+	}
+}
+
+func newTestKeyRing() *security.KeyRing {
+	key, _ := security.NewHMACKey("test", "HS256", []byte("test-secret"))
+	return security.NewKeyRing(key)
+}
+
+func TestAuthService_Login(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockRefreshTokenRepository)
+	service := NewAuthService(newTestConfig(), newTestKeyRing(), mockUserRepo, mockTokenRepo, new(MockTokenRevocationRepository), newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		user, _ := entity.NewUser("login@example.com", "password123", "Login User")
+		mockUserRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+		mockTokenRepo.On("Create", ctx, mock.AnythingOfType("*entity.RefreshToken")).Return(nil).Once()
+
+		result, err := service.Login(ctx, user.Email, "password123", ClientInfo{})
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.MFAToken)
+		tokens := result.Tokens
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.NotEmpty(t, tokens.RefreshToken)
+
+		stored := mockTokenRepo.Calls[len(mockTokenRepo.Calls)-1].Arguments.Get(1).(*entity.RefreshToken)
+		assert.Equal(t, user.ID, stored.UserID)
+		assert.Equal(t, hashOpaqueToken(tokens.RefreshToken), stored.TokenHash)
+		assert.NotEqual(t, tokens.RefreshToken, stored.TokenHash)
+		mockUserRepo.AssertExpectations(t)
+		mockTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("RehashesLegacyPassword", func(t *testing.T) {
+		user, _ := entity.NewUser("legacy@example.com", "password123", "Legacy User")
+		legacy, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+		user.Password = string(legacy)
+		mockUserRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+		mockUserRepo.On("Update", ctx, user).Return(nil).Once()
+		mockTokenRepo.On("Create", ctx, mock.AnythingOfType("*entity.RefreshToken")).Return(nil).Once()
+
+		_, err := service.Login(ctx, user.Email, "password123", ClientInfo{})
+
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(user.Password, "$argon2id$"))
+		assert.False(t, user.PasswordNeedsRehash())
+		assert.NoError(t, user.ComparePassword("password123"))
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("InvalidPassword", func(t *testing.T) {
+		user, _ := entity.NewUser("wrong@example.com", "password123", "Wrong User")
+		mockUserRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+
+		result, err := service.Login(ctx, user.Email, "not-the-password", ClientInfo{})
+
+		assert.Equal(t, errors.ErrInvalidCredential, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("LockedAccount", func(t *testing.T) {
+		lockedUntil := time.Now().Add(time.Hour)
+		user, _ := entity.NewUser("locked@example.com", "password123", "Locked User")
+		user.RecordFailedLogin(5, lockedUntil)
+		cfg := newTestLockoutConfig()
+		userRepo := new(MockUserRepository)
+		service := NewAuthService(cfg, newTestKeyRing(), userRepo, mockTokenRepo, new(MockTokenRevocationRepository), newTestSessionRepository(), newDisabledMFAService(), NewLockoutService(cfg, userRepo, cache.NewMemoryCache(cfg)))
+		userRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+
+		// Answered like a wrong password, whether or not the password is right
+		for _, password := range []string{"password123", "not-the-password"} {
+			result, err := service.Login(ctx, user.Email, password, ClientInfo{})
+
+			assert.Equal(t, errors.ErrInvalidCredential, err)
+			assert.Nil(t, result)
+		}
+		userRepo.AssertNotCalled(t, "IncrementFailedLogins", mock.Anything, mock.Anything)
+	})
+
+	t.Run("UnverifiedEmail", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Auth.RequireVerifiedEmail = true
+		service := NewAuthService(cfg, newTestKeyRing(), mockUserRepo, mockTokenRepo, new(MockTokenRevocationRepository), newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+
+		user, _ := entity.NewUser("unverified@example.com", "password123", "Unverified User")
+		mockUserRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+
+		result, err := service.Login(ctx, user.Email, "password123", ClientInfo{})
+
+		assert.Equal(t, errors.ErrEmailNotVerified, err)
+		assert.Nil(t, result)
+	})
+}
+
+func TestAuthService_RefreshToken(t *testing.T) {
+	ctx := context.Background()
+	user, _ := entity.NewUser("refresh@example.com", "password123", "Refresh User")
+
+	t.Run("RotatesToken", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockRefreshTokenRepository)
+		service := NewAuthService(newTestConfig(), newTestKeyRing(), mockUserRepo, mockTokenRepo, new(MockTokenRevocationRepository), newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+
+		stored := entity.NewRefreshToken(user.ID, uuid.New(), hashOpaqueToken("current"), time.Hour)
+		mockTokenRepo.On("FindByHash", ctx, hashOpaqueToken("current")).Return(stored, nil)
+		mockTokenRepo.On("MarkUsed", ctx, stored.ID).Return(nil)
+		mockTokenRepo.On("Create", ctx, mock.MatchedBy(func(t *entity.RefreshToken) bool {
+			return t.FamilyID == stored.FamilyID && t.ID != stored.ID
+		})).Return(nil)
+		mockUserRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+
+		tokens, err := service.RefreshToken(ctx, "current")
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, "current", tokens.RefreshToken)
+		mockTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("ReuseRevokesFamily", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockRefreshTokenRepository)
+		service := NewAuthService(newTestConfig(), newTestKeyRing(), mockUserRepo, mockTokenRepo, new(MockTokenRevocationRepository), newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+
+		usedAt := time.Now()
+		stored := entity.NewRefreshToken(user.ID, uuid.New(), hashOpaqueToken("replayed"), time.Hour)
+		stored.UsedAt = &usedAt
+		mockTokenRepo.On("FindByHash", ctx, hashOpaqueToken("replayed")).Return(stored, nil)
+		mockTokenRepo.On("RevokeFamily", ctx, stored.FamilyID).Return(nil)
+
+		tokens, err := service.RefreshToken(ctx, "replayed")
+
+		assert.Equal(t, errors.ErrTokenReused, err)
+		assert.Nil(t, tokens)
+		mockTokenRepo.AssertExpectations(t)
+		mockTokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockRefreshTokenRepository)
+		service := NewAuthService(newTestConfig(), newTestKeyRing(), mockUserRepo, mockTokenRepo, new(MockTokenRevocationRepository), newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+
+		stored := entity.NewRefreshToken(user.ID, uuid.New(), hashOpaqueToken("expired"), -time.Minute)
+		mockTokenRepo.On("FindByHash", ctx, hashOpaqueToken("expired")).Return(stored, nil)
+
+		tokens, err := service.RefreshToken(ctx, "expired")
+
+		assert.Equal(t, errors.ErrTokenExpired, err)
+		assert.Nil(t, tokens)
+	})
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	ctx := context.Background()
+	user, _ := entity.NewUser("logout@example.com", "password123", "Logout User")
+
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockRefreshTokenRepository)
+	mockRevocationRepo := new(MockTokenRevocationRepository)
+	service := NewAuthService(newTestConfig(), newTestKeyRing(), mockUserRepo, mockTokenRepo, mockRevocationRepo, newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+
+	mockUserRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+	mockTokenRepo.On("Create", ctx, mock.AnythingOfType("*entity.RefreshToken")).Return(nil)
+	result, err := service.Login(ctx, user.Email, "password123", ClientInfo{})
+	assert.NoError(t, err)
+	tokens := result.Tokens
+
+	mockRevocationRepo.On("IsTokenRevoked", ctx, mock.AnythingOfType("string")).Return(false, nil).Once()
+	mockRevocationRepo.On("UserTokensRevokedBefore", ctx, user.ID).Return(time.Time{}, nil)
+	token, err := service.ValidateToken(ctx, tokens.AccessToken)
+	assert.NoError(t, err)
+
+	claims := token.Claims.(jwt.MapClaims)
+	familyID := uuid.MustParse(claims["sid"].(string))
+	mockRevocationRepo.On("RevokeToken", ctx, mock.MatchedBy(func(r *entity.RevokedToken) bool {
+		return r.JTI == claims["jti"] && r.UserID == user.ID
+	})).Return(nil)
+	mockTokenRepo.On("RevokeFamily", ctx, familyID).Return(nil)
+
+	assert.NoError(t, service.Logout(ctx, claims))
+
+	mockRevocationRepo.On("IsTokenRevoked", ctx, claims["jti"]).Return(true, nil).Once()
+	_, err = service.ValidateToken(ctx, tokens.AccessToken)
+	assert.Equal(t, errors.ErrTokenRevoked, err)
+	mockRevocationRepo.AssertExpectations(t)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	ctx := context.Background()
+	user, _ := entity.NewUser("session@example.com", "password123", "Session User")
+	client := ClientInfo{IP: "203.0.113.7", UserAgent: "test-agent"}
+
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockRefreshTokenRepository)
+	mockRevocationRepo := new(MockTokenRevocationRepository)
+	mockSessionRepo := new(MockSessionRepository)
+	service := NewAuthService(newTestConfig(), newTestKeyRing(), mockUserRepo, mockTokenRepo, mockRevocationRepo, mockSessionRepo, newDisabledMFAService(), newAllowingLockoutService())
+
+	mockUserRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+	mockTokenRepo.On("Create", ctx, mock.AnythingOfType("*entity.RefreshToken")).Return(nil)
+	mockSessionRepo.On("Create", ctx, mock.AnythingOfType("*entity.Session")).Return(nil).Once()
+	mockRevocationRepo.On("IsTokenRevoked", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	mockRevocationRepo.On("UserTokensRevokedBefore", ctx, user.ID).Return(time.Time{}, nil)
+
+	result, err := service.Login(ctx, user.Email, "password123", client)
+	require.NoError(t, err)
+
+	session := mockSessionRepo.Calls[0].Arguments.Get(1).(*entity.Session)
+	assert.Equal(t, user.ID, session.UserID)
+	assert.Equal(t, client.IP, session.IP)
+	assert.Equal(t, client.UserAgent, session.UserAgent)
+
+	t.Run("ActiveSession", func(t *testing.T) {
+		mockSessionRepo.On("FindByID", ctx, session.ID).Return(session, nil).Once()
+
+		token, err := service.ValidateToken(ctx, result.Tokens.AccessToken)
+
+		require.NoError(t, err)
+		assert.Equal(t, session.ID.String(), token.Claims.(jwt.MapClaims)["sid"])
+		mockSessionRepo.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("RecordsActivity", func(t *testing.T) {
+		idle := *session
+		idle.LastSeenAt = time.Now().Add(-time.Hour)
+		mockSessionRepo.On("FindByID", ctx, session.ID).Return(&idle, nil).Once()
+		mockSessionRepo.On("Touch", ctx, session.ID, mock.AnythingOfType("time.Time"), time.Time{}).Return(nil).Once()
+
+		_, err := service.ValidateToken(ctx, result.Tokens.AccessToken)
+
+		assert.NoError(t, err)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("RevokedSession", func(t *testing.T) {
+		revoked := *session
+		revokedAt := time.Now()
+		revoked.RevokedAt = &revokedAt
+		mockSessionRepo.On("FindByID", ctx, session.ID).Return(&revoked, nil).Once()
+
+		_, err := service.ValidateToken(ctx, result.Tokens.AccessToken)
+
+		assert.Equal(t, errors.ErrTokenRevoked, err)
+	})
+}
+
+func TestAuthService_VerifyMFA(t *testing.T) {
+	ctx := context.Background()
+	user, _ := entity.NewUser("mfa@example.com", "password123", "MFA User")
+
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockRefreshTokenRepository)
+	mockRevocationRepo := new(MockTokenRevocationRepository)
+	mockMFA := new(MockMFAService)
+	service := NewAuthService(newTestConfig(), newTestKeyRing(), mockUserRepo, mockTokenRepo, mockRevocationRepo, newTestSessionRepository(), mockMFA, newAllowingLockoutService())
+
+	mockUserRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+	mockUserRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+	mockMFA.On("IsEnabled", ctx, user.ID).Return(true, nil)
+
+	result, err := service.Login(ctx, user.Email, "password123", ClientInfo{})
+	assert.NoError(t, err)
+	assert.Nil(t, result.Tokens)
+	assert.NotEmpty(t, result.MFAToken)
+
+	// The challenge must not work as an access token
+	_, err = service.ValidateToken(ctx, result.MFAToken)
+	assert.Equal(t, errors.ErrInvalidToken, err)
+
+	t.Run("InvalidCode", func(t *testing.T) {
+		mockRevocationRepo.On("IsTokenRevoked", ctx, mock.AnythingOfType("string")).Return(false, nil).Once()
+		mockMFA.On("Verify", ctx, user.ID, "000000").Return(errors.ErrInvalidMFACode).Once()
+
+		tokens, err := service.VerifyMFA(ctx, result.MFAToken, "000000", ClientInfo{})
+
+		assert.Equal(t, errors.ErrInvalidMFACode, err)
+		assert.Nil(t, tokens)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockRevocationRepo.On("IsTokenRevoked", ctx, mock.AnythingOfType("string")).Return(false, nil).Once()
+		mockMFA.On("Verify", ctx, user.ID, "123456").Return(nil).Once()
+		mockRevocationRepo.On("RevokeToken", ctx, mock.AnythingOfType("*entity.RevokedToken")).Return(nil).Once()
+		mockTokenRepo.On("Create", ctx, mock.MatchedBy(func(t *entity.RefreshToken) bool {
+			return t.AuthMethods == "pwd otp"
+		})).Return(nil).Once()
+
+		tokens, err := service.VerifyMFA(ctx, result.MFAToken, "123456", ClientInfo{})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+		mockRevocationRepo.AssertExpectations(t)
+		mockTokenRepo.AssertExpectations(t)
+	})
+}
+
+func TestSubjectAttributes(t *testing.T) {
+	ctx := context.Background()
+	user, _ := entity.NewUser("subject@example.com", "password123", "Subject User")
+	organizationID := uuid.New()
+	user.ActiveOrganizationID = &organizationID
+
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockRefreshTokenRepository)
+	mockRevocationRepo := new(MockTokenRevocationRepository)
+	service := NewAuthService(newTestConfig(), newTestKeyRing(), mockUserRepo, mockTokenRepo, mockRevocationRepo, newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+	mockUserRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+	mockTokenRepo.On("Create", ctx, mock.AnythingOfType("*entity.RefreshToken")).Return(nil)
+	mockRevocationRepo.On("IsTokenRevoked", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	mockRevocationRepo.On("UserTokensRevokedBefore", ctx, user.ID).Return(time.Time{}, nil)
+
+	result, err := service.Login(ctx, user.Email, "password123", ClientInfo{})
+	require.NoError(t, err)
+	token, err := service.ValidateToken(ctx, result.Tokens.AccessToken)
+	require.NoError(t, err)
+
+	// A password login is described just like the claims of the user
+	fromToken := SubjectAttributes(token.Claims.(jwt.MapClaims))
+	fromUser := SubjectAttributes(UserClaims(user, []string{AuthMethodPassword}))
+	require.Len(t, fromToken, len(fromUser))
+	for name, value := range fromUser {
+		assert.Equal(t, fmt.Sprint(value), fmt.Sprint(fromToken[name]), name)
+	}
+	assert.Equal(t, user.ID.String(), fromUser["id"])
+	assert.Equal(t, organizationID.String(), fromUser["tenant_id"])
+	assert.Equal(t, tokenUseAccess, fromUser["token_use"])
+}
+
+func TestClaimRoles(t *testing.T) {
+	assert.Equal(t, []string{"admin", "support"}, ClaimRoles(jwt.MapClaims{"roles": []interface{}{"admin", "support"}}))
+	assert.Equal(t, []string{"support"}, ClaimRoles(jwt.MapClaims{"roles": []string{"support"}}))
+	// Tokens issued while users had a single role
+	assert.Equal(t, []string{"admin"}, ClaimRoles(jwt.MapClaims{"role": "admin"}))
+	assert.Nil(t, ClaimRoles(jwt.MapClaims{ClientIDClaim: uuid.NewString()}))
+}
+
+func TestAuthService_Downscope(t *testing.T) {
+	ctx := context.Background()
+	user, _ := entity.NewUser("scoped@example.com", "password123", "Scoped User")
+
+	mockRevocationRepo := new(MockTokenRevocationRepository)
+	service := NewAuthService(newTestConfig(), newTestKeyRing(), new(MockUserRepository), new(MockRefreshTokenRepository), mockRevocationRepo, newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+	mockRevocationRepo.On("IsTokenRevoked", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	mockRevocationRepo.On("UserTokensRevokedBefore", ctx, user.ID).Return(time.Time{}, nil)
+
+	expiresAt := time.Now().Add(10 * time.Minute)
+	claims := jwt.MapClaims{
+		"sub":       user.ID.String(),
+		"email":     user.Email,
+		"roles":     []interface{}{entity.RoleUser},
+		"sid":       uuid.NewString(),
+		"token_use": tokenUseAccess,
+		"exp":       float64(expiresAt.Unix()),
+	}
+
+	t.Run("Unrestricted Token", func(t *testing.T) {
+		tokens, err := service.Downscope(ctx, claims, []string{entity.PermissionUsersRead})
+		require.NoError(t, err)
+		assert.Empty(t, tokens.RefreshToken)
+		assert.Equal(t, []string{entity.PermissionUsersRead}, tokens.Scopes)
+
+		token, err := service.ValidateToken(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		scoped := token.Claims.(jwt.MapClaims)
+		assert.Equal(t, entity.PermissionUsersRead, scoped["scope"])
+		assert.Equal(t, claims["sid"], scoped["sid"])
+		assert.Equal(t, claims["roles"], scoped["roles"])
+
+		// The derived token never outlives the presented one
+		exp, err := scoped.GetExpirationTime()
+		require.NoError(t, err)
+		assert.Equal(t, expiresAt.Unix(), exp.Unix())
+
+		assert.True(t, TokenAllowsScope(scoped, entity.PermissionUsersRead))
+		assert.False(t, TokenAllowsScope(scoped, entity.PermissionUsersWrite))
+		assert.True(t, TokenAllowsScope(claims, entity.PermissionUsersWrite))
+	})
+
+	t.Run("Narrowing A Restricted Token", func(t *testing.T) {
+		restricted := jwt.MapClaims{}
+		for k, v := range claims {
+			restricted[k] = v
+		}
+		restricted["scope"] = "users:read account"
+
+		_, err := service.Downscope(ctx, restricted, []string{entity.ScopeAccount})
+		assert.NoError(t, err)
+
+		_, err = service.Downscope(ctx, restricted, []string{entity.PermissionUsersWrite})
+		assert.Equal(t, errors.ErrInvalidScope, err)
+	})
+
+	t.Run("Unknown Scope", func(t *testing.T) {
+		_, err := service.Downscope(ctx, claims, []string{"everything"})
+		assert.Equal(t, errors.ErrInvalidScope, err)
+
+		_, err = service.Downscope(ctx, claims, nil)
+		assert.Equal(t, errors.ErrInvalidScope, err)
+	})
+
+	t.Run("API Key", func(t *testing.T) {
+		_, err := service.Downscope(ctx, jwt.MapClaims{"sub": user.ID.String(), "token_use": tokenUseAPIKey}, []string{entity.PermissionUsersRead})
+		assert.Equal(t, errors.ErrForbidden, err)
+	})
+}
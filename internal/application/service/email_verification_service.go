@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/mailer"
+	"github.com/rs/zerolog/log"
+)
+
+type EmailVerificationService interface {
+	// SendVerification emails a verification link to the user. Delivery
+	// failures are logged rather than returned; the user can ask for a resend.
+	SendVerification(ctx context.Context, user *entity.User) error
+	// Resend sends a fresh link to an unverified account. It reports success
+	// for unknown or already verified addresses.
+	Resend(ctx context.Context, email string) error
+	Verify(ctx context.Context, token string) error
+}
+
+type emailVerificationService struct {
+	config    *config.Config
+	userRepo  repository.UserRepository
+	tokenRepo repository.OneTimeTokenRepository
+	mailer    mailer.Mailer
+}
+
+func NewEmailVerificationService(
+	cfg *config.Config,
+	userRepo repository.UserRepository,
+	tokenRepo repository.OneTimeTokenRepository,
+	m mailer.Mailer,
+) EmailVerificationService {
+	return &emailVerificationService{
+		config:    cfg,
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		mailer:    m,
+	}
+}
+
+func (s *emailVerificationService) SendVerification(ctx context.Context, user *entity.User) error {
+	token, err := issueOneTimeToken(ctx, s.tokenRepo, user.ID, entity.TokenPurposeEmailVerification, s.config.Auth.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.config.App.PublicURL + "/verify-email?token=" + url.QueryEscape(token)
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by following the link below. It expires in %s.\n\n%s\n\nIf you did not create an account you can ignore this email.\n",
+			user.Name, s.config.Auth.EmailVerificationTTL, link,
+		),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to send verification email")
+	}
+
+	return nil
+}
+
+func (s *emailVerificationService) Resend(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if err == errors.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	latest, err := s.tokenRepo.FindLatest(ctx, user.ID, entity.TokenPurposeEmailVerification)
+	if err != nil && err != errors.ErrNotFound {
+		return err
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < s.config.Auth.VerificationResendInterval {
+		return errors.ErrTooManyRequests
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+func (s *emailVerificationService) Verify(ctx context.Context, token string) error {
+	record, err := s.tokenRepo.FindByHash(ctx, entity.TokenPurposeEmailVerification, hashOpaqueToken(token))
+	if err != nil {
+		return err
+	}
+
+	if record.IsUsed() {
+		return errors.ErrInvalidToken
+	}
+
+	if record.IsExpired() {
+		return errors.ErrTokenExpired
+	}
+
+	if err := s.tokenRepo.MarkUsed(ctx, record.ID); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, record.UserID)
+	if err != nil {
+		if err == errors.ErrUserNotFound {
+			return errors.ErrInvalidToken
+		}
+		return err
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+
+	user.MarkEmailVerified()
+	return s.userRepo.Update(ctx, user)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerificationService_Verify(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		service := NewEmailVerificationService(cfg, mockUserRepo, mockTokenRepo, mailer.NewLogMailer("no-reply@example.com"))
+
+		user, _ := entity.NewUser("verify@example.com", "password123", "Verify User")
+		record := entity.NewOneTimeToken(user.ID, entity.TokenPurposeEmailVerification, hashOpaqueToken("raw-token"), time.Hour)
+
+		mockTokenRepo.On("FindByHash", ctx, entity.TokenPurposeEmailVerification, record.TokenHash).Return(record, nil)
+		mockTokenRepo.On("MarkUsed", ctx, record.ID).Return(nil)
+		mockUserRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+		mockUserRepo.On("Update", ctx, user).Return(nil)
+
+		err := service.Verify(ctx, "raw-token")
+
+		assert.NoError(t, err)
+		assert.True(t, user.EmailVerified)
+		assert.NotNil(t, user.VerifiedAt)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		service := NewEmailVerificationService(cfg, new(MockUserRepository), mockTokenRepo, mailer.NewLogMailer("no-reply@example.com"))
+
+		record := entity.NewOneTimeToken(uuid.New(), entity.TokenPurposeEmailVerification, hashOpaqueToken("old-token"), -time.Minute)
+		mockTokenRepo.On("FindByHash", ctx, entity.TokenPurposeEmailVerification, record.TokenHash).Return(record, nil)
+
+		err := service.Verify(ctx, "old-token")
+
+		assert.Equal(t, errors.ErrTokenExpired, err)
+	})
+}
+
+func TestEmailVerificationService_Resend(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+	cfg.Auth.VerificationResendInterval = time.Minute
+	cfg.Auth.EmailVerificationTTL = 24 * time.Hour
+
+	t.Run("Throttled", func(t *testing.T) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		service := NewEmailVerificationService(cfg, mockUserRepo, mockTokenRepo, mailer.NewLogMailer("no-reply@example.com"))
+
+		user, _ := entity.NewUser("throttle@example.com", "password123", "Throttle User")
+		recent := entity.NewOneTimeToken(user.ID, entity.TokenPurposeEmailVerification, "hash", time.Hour)
+		mockUserRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+		mockTokenRepo.On("FindLatest", ctx, user.ID, entity.TokenPurposeEmailVerification).Return(recent, nil)
+
+		err := service.Resend(ctx, user.Email)
+
+		assert.Equal(t, errors.ErrTooManyRequests, err)
+		mockTokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("AfterInterval", func(t *testing.T) {
+		mailDir := t.TempDir()
+		m, _ := mailer.NewFileMailer("no-reply@example.com", mailDir)
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		service := NewEmailVerificationService(cfg, mockUserRepo, mockTokenRepo, m)
+
+		user, _ := entity.NewUser("resend@example.com", "password123", "Resend User")
+		old := entity.NewOneTimeToken(user.ID, entity.TokenPurposeEmailVerification, "hash", time.Hour)
+		old.CreatedAt = time.Now().Add(-2 * time.Minute)
+		mockUserRepo.On("FindByEmail", ctx, user.Email).Return(user, nil)
+		mockTokenRepo.On("FindLatest", ctx, user.ID, entity.TokenPurposeEmailVerification).Return(old, nil)
+		mockTokenRepo.On("InvalidateForUser", ctx, user.ID, entity.TokenPurposeEmailVerification).Return(nil)
+		mockTokenRepo.On("Create", ctx, mock.AnythingOfType("*entity.OneTimeToken")).Return(nil)
+
+		err := service.Resend(ctx, user.Email)
+
+		assert.NoError(t, err)
+		messages := readMail(t, mailDir)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "/verify-email?token=")
+	})
+}
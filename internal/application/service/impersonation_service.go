@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+)
+
+type ImpersonationService interface {
+	// Start issues a token to act as the target user. The actor's roles, and
+	// the scopes of a restricted actor token, must grant every permission the
+	// target's roles grant, so impersonation never escalates privileges.
+	Start(ctx context.Context, actor jwt.MapClaims, targetID uuid.UUID, client ClientInfo) (*TokenPair, error)
+	// Stop revokes the impersonation token the claims belong to
+	Stop(ctx context.Context, claims jwt.MapClaims, client ClientInfo) error
+}
+
+type impersonationService struct {
+	userRepo     repository.UserRepository
+	authService  AuthService
+	roleService  RoleService
+	auditService AuditService
+}
+
+func NewImpersonationService(
+	userRepo repository.UserRepository,
+	authService AuthService,
+	roleService RoleService,
+	auditService AuditService,
+) ImpersonationService {
+	return &impersonationService{
+		userRepo:     userRepo,
+		authService:  authService,
+		roleService:  roleService,
+		auditService: auditService,
+	}
+}
+
+func (s *impersonationService) Start(ctx context.Context, actor jwt.MapClaims, targetID uuid.UUID, client ClientInfo) (*TokenPair, error) {
+	if _, impersonating, err := ClaimActorID(actor); err != nil {
+		return nil, err
+	} else if impersonating {
+		return nil, errors.ErrImpersonating
+	}
+
+	// Only people act as other users
+	if _, isClient := ClaimClientID(actor); isClient {
+		return nil, errors.ErrForbidden
+	}
+
+	actorID, err := claimUserID(actor)
+	if err != nil {
+		return nil, err
+	}
+	if actorID == targetID {
+		return nil, errors.ErrInvalidInput
+	}
+
+	target, err := s.userRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	actorRoles := ClaimRoles(actor)
+	for _, permission := range entity.Permissions {
+		granted, err := s.roleService.HasPermission(ctx, target.Roles, permission.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !granted {
+			continue
+		}
+
+		if !TokenAllowsScope(actor, permission.Name) {
+			return nil, errors.ErrForbidden
+		}
+		granted, err = s.roleService.HasPermission(ctx, actorRoles, permission.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !granted {
+			return nil, errors.ErrForbidden
+		}
+	}
+
+	tokens, err := s.authService.Impersonate(ctx, actor, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// A session that can't be audited is not handed out
+	if err := s.auditService.Record(ctx, entity.AuditImpersonationStart, actorID, &target.ID, client); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *impersonationService) Stop(ctx context.Context, claims jwt.MapClaims, client ClientInfo) error {
+	actorID, impersonating, err := ClaimActorID(claims)
+	if err != nil {
+		return err
+	}
+	if !impersonating {
+		return errors.ErrNotImpersonating
+	}
+
+	targetID, err := claimUserID(claims)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authService.Logout(ctx, claims); err != nil {
+		return err
+	}
+
+	return s.auditService.Record(ctx, entity.AuditImpersonationStop, actorID, &targetID, client)
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAuditEventRepository is a mock implementation of repository.AuditEventRepository
+type MockAuditEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditEventRepository) Create(ctx context.Context, event *entity.AuditEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockAuditEventRepository) List(ctx context.Context, page, limit int) ([]*entity.AuditEvent, int64, error) {
+	args := m.Called(ctx, page, limit)
+	return args.Get(0).([]*entity.AuditEvent), args.Get(1).(int64), args.Error(2)
+}
+
+type impersonationTestEnv struct {
+	service        ImpersonationService
+	authService    AuthService
+	userRepo       *MockUserRepository
+	roleRepo       *MockRoleRepository
+	revocationRepo *MockTokenRevocationRepository
+	auditRepo      *MockAuditEventRepository
+}
+
+func newImpersonationTestEnv(t *testing.T) *impersonationTestEnv {
+	cfg := newTestConfig()
+	cfg.JWT.ImpersonationDuration = 15 * time.Minute
+
+	e := &impersonationTestEnv{
+		userRepo:       new(MockUserRepository),
+		roleRepo:       new(MockRoleRepository),
+		revocationRepo: new(MockTokenRevocationRepository),
+		auditRepo:      new(MockAuditEventRepository),
+	}
+	roleService := newSeededRoleService(t, e.roleRepo, e.userRepo)
+	e.authService = NewAuthService(cfg, newTestKeyRing(), e.userRepo, new(MockRefreshTokenRepository), e.revocationRepo, newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+	e.service = NewImpersonationService(e.userRepo, e.authService, roleService, NewAuditService(e.auditRepo))
+	return e
+}
+
+func actorClaims(user *entity.User) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   user.ID.String(),
+		"email": user.Email,
+		"roles": user.Roles,
+		"amr":   []interface{}{AuthMethodPassword},
+	}
+}
+
+func TestImpersonationService_Start(t *testing.T) {
+	ctx := context.Background()
+	support := entity.NewRole("support", "", []*entity.Permission{
+		{Name: entity.PermissionUsersRead},
+		{Name: entity.PermissionUsersImpersonate},
+	})
+	customer := entity.NewRole(entity.RoleUser, "", nil)
+	admin := entity.NewRole(entity.RoleAdmin, "", entity.Permissions)
+
+	actor, _ := entity.NewUser("support@example.com", "password123", "Support")
+	actor.SetRoles([]string{support.Name})
+
+	t.Run("Success", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+		target, _ := entity.NewUser("customer@example.com", "password123", "Customer")
+		e.roleRepo.On("FindByName", ctx, support.Name).Return(support, nil)
+		e.roleRepo.On("FindByName", ctx, customer.Name).Return(customer, nil)
+		e.userRepo.On("FindByID", ctx, target.ID).Return(target, nil)
+		e.auditRepo.On("Create", ctx, mock.MatchedBy(func(event *entity.AuditEvent) bool {
+			return event.Action == entity.AuditImpersonationStart && event.ActorID == actor.ID &&
+				*event.SubjectID == target.ID && event.IP == "203.0.113.7"
+		})).Return(nil).Once()
+
+		tokens, err := e.service.Start(ctx, actorClaims(actor), target.ID, ClientInfo{IP: "203.0.113.7"})
+		require.NoError(t, err)
+		assert.Empty(t, tokens.RefreshToken)
+		assert.Equal(t, 15*time.Minute, tokens.ExpiresIn)
+
+		e.revocationRepo.On("IsTokenRevoked", ctx, mock.AnythingOfType("string")).Return(false, nil)
+		e.revocationRepo.On("UserTokensRevokedBefore", ctx, mock.Anything).Return(time.Time{}, nil)
+		token, err := e.authService.ValidateToken(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+
+		claims := token.Claims.(jwt.MapClaims)
+		assert.Equal(t, target.ID.String(), claims["sub"])
+		assert.Equal(t, []interface{}{customer.Name}, claims["roles"])
+		assert.NotContains(t, claims, "sid")
+		actorID, ok, err := ClaimActorID(claims)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, actor.ID, actorID)
+		e.revocationRepo.AssertCalled(t, "UserTokensRevokedBefore", ctx, actor.ID)
+		e.auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("Target With More Privileges", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+		target, _ := entity.NewUser("admin@example.com", "password123", "Admin")
+		target.SetRoles([]string{entity.RoleAdmin})
+		e.roleRepo.On("FindByName", ctx, support.Name).Return(support, nil)
+		e.roleRepo.On("FindByName", ctx, admin.Name).Return(admin, nil)
+		e.userRepo.On("FindByID", ctx, target.ID).Return(target, nil)
+
+		_, err := e.service.Start(ctx, actorClaims(actor), target.ID, ClientInfo{})
+		assert.Equal(t, errors.ErrForbidden, err)
+		e.auditRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Target With Another Role", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+		billing := entity.NewRole("billing", "", []*entity.Permission{{Name: entity.PermissionUsersWrite}})
+		target, _ := entity.NewUser("billing@example.com", "password123", "Billing")
+		target.SetRoles([]string{customer.Name, billing.Name})
+		e.roleRepo.On("FindByName", ctx, support.Name).Return(support, nil)
+		e.roleRepo.On("FindByName", ctx, customer.Name).Return(customer, nil)
+		e.roleRepo.On("FindByName", ctx, billing.Name).Return(billing, nil)
+		e.userRepo.On("FindByID", ctx, target.ID).Return(target, nil)
+
+		_, err := e.service.Start(ctx, actorClaims(actor), target.ID, ClientInfo{})
+		assert.Equal(t, errors.ErrForbidden, err)
+		e.auditRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Restricted Actor", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+		target, _ := entity.NewUser("customer@example.com", "password123", "Customer")
+		e.roleRepo.On("FindByName", ctx, support.Name).Return(support, nil)
+		e.roleRepo.On("FindByName", ctx, customer.Name).Return(customer, nil)
+		e.userRepo.On("FindByID", ctx, target.ID).Return(target, nil)
+		e.auditRepo.On("Create", ctx, mock.Anything).Return(nil)
+		claims := actorClaims(actor)
+		claims["scope"] = entity.PermissionUsersImpersonate
+
+		tokens, err := e.service.Start(ctx, claims, target.ID, ClientInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{entity.PermissionUsersImpersonate}, tokens.Scopes)
+
+		e.revocationRepo.On("IsTokenRevoked", ctx, mock.AnythingOfType("string")).Return(false, nil)
+		e.revocationRepo.On("UserTokensRevokedBefore", ctx, mock.Anything).Return(time.Time{}, nil)
+		token, err := e.authService.ValidateToken(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, entity.PermissionUsersImpersonate, token.Claims.(jwt.MapClaims)["scope"])
+	})
+
+	t.Run("Restricted Actor Without The Target's Permissions", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+		viewer := entity.NewRole("viewer", "", []*entity.Permission{{Name: entity.PermissionUsersRead}})
+		target, _ := entity.NewUser("viewer@example.com", "password123", "Viewer")
+		target.SetRoles([]string{viewer.Name})
+		e.roleRepo.On("FindByName", ctx, support.Name).Return(support, nil)
+		e.roleRepo.On("FindByName", ctx, viewer.Name).Return(viewer, nil)
+		e.userRepo.On("FindByID", ctx, target.ID).Return(target, nil)
+		claims := actorClaims(actor)
+		claims["scope"] = entity.PermissionUsersImpersonate
+
+		_, err := e.service.Start(ctx, claims, target.ID, ClientInfo{})
+		assert.Equal(t, errors.ErrForbidden, err)
+		e.auditRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("API Key", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+		target, _ := entity.NewUser("customer@example.com", "password123", "Customer")
+		e.roleRepo.On("FindByName", ctx, support.Name).Return(support, nil)
+		e.roleRepo.On("FindByName", ctx, customer.Name).Return(customer, nil)
+		e.userRepo.On("FindByID", ctx, target.ID).Return(target, nil)
+		claims := actorClaims(actor)
+		claims["token_use"] = tokenUseAPIKey
+
+		_, err := e.service.Start(ctx, claims, target.ID, ClientInfo{})
+		assert.Equal(t, errors.ErrForbidden, err)
+		e.auditRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already Impersonating", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+		claims := actorClaims(actor)
+		claims[ActorClaim] = map[string]interface{}{"sub": uuid.NewString()}
+
+		_, err := e.service.Start(ctx, claims, uuid.New(), ClientInfo{})
+		assert.Equal(t, errors.ErrImpersonating, err)
+	})
+
+	t.Run("Self", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+
+		_, err := e.service.Start(ctx, actorClaims(actor), actor.ID, ClientInfo{})
+		assert.Equal(t, errors.ErrInvalidInput, err)
+	})
+
+	t.Run("Audit Failure", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+		target, _ := entity.NewUser("customer@example.com", "password123", "Customer")
+		e.roleRepo.On("FindByName", ctx, support.Name).Return(support, nil)
+		e.roleRepo.On("FindByName", ctx, customer.Name).Return(customer, nil)
+		e.userRepo.On("FindByID", ctx, target.ID).Return(target, nil)
+		e.auditRepo.On("Create", ctx, mock.Anything).Return(assert.AnError)
+
+		tokens, err := e.service.Start(ctx, actorClaims(actor), target.ID, ClientInfo{})
+		assert.Error(t, err)
+		assert.Nil(t, tokens)
+	})
+}
+
+func TestImpersonationService_Stop(t *testing.T) {
+	ctx := context.Background()
+	actorID := uuid.New()
+	targetID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+		claims := jwt.MapClaims{
+			"jti":      "impersonation-jti",
+			"sub":      targetID.String(),
+			"exp":      float64(time.Now().Add(time.Minute).Unix()),
+			ActorClaim: map[string]interface{}{"sub": actorID.String()},
+		}
+		e.revocationRepo.On("RevokeToken", ctx, mock.MatchedBy(func(r *entity.RevokedToken) bool {
+			return r.JTI == "impersonation-jti" && r.UserID == targetID
+		})).Return(nil).Once()
+		e.auditRepo.On("Create", ctx, mock.MatchedBy(func(event *entity.AuditEvent) bool {
+			return event.Action == entity.AuditImpersonationStop && event.ActorID == actorID && *event.SubjectID == targetID
+		})).Return(nil).Once()
+
+		assert.NoError(t, e.service.Stop(ctx, claims, ClientInfo{}))
+		e.revocationRepo.AssertExpectations(t)
+		e.auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("Not Impersonating", func(t *testing.T) {
+		e := newImpersonationTestEnv(t)
+
+		err := e.service.Stop(ctx, jwt.MapClaims{"sub": targetID.String()}, ClientInfo{})
+		assert.Equal(t, errors.ErrNotImpersonating, err)
+	})
+}
+
+func TestAuthService_ImpersonationRevokedWithActor(t *testing.T) {
+	ctx := context.Background()
+	e := newImpersonationTestEnv(t)
+	actor, _ := entity.NewUser("support@example.com", "password123", "Support")
+	target, _ := entity.NewUser("customer@example.com", "password123", "Customer")
+
+	tokens, err := e.authService.Impersonate(ctx, actorClaims(actor), target)
+	require.NoError(t, err)
+
+	e.revocationRepo.On("IsTokenRevoked", ctx, mock.AnythingOfType("string")).Return(false, nil)
+	e.revocationRepo.On("UserTokensRevokedBefore", ctx, target.ID).Return(time.Time{}, nil)
+	e.revocationRepo.On("UserTokensRevokedBefore", ctx, actor.ID).Return(time.Now().Add(time.Second), nil)
+
+	_, err = e.authService.ValidateToken(ctx, tokens.AccessToken)
+	assert.Equal(t, errors.ErrTokenRevoked, err)
+}
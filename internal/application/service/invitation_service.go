@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/mailer"
+	"github.com/rs/zerolog/log"
+)
+
+type InvitationService interface {
+	// Create emails an invitation link and supersedes earlier pending
+	// invitations to the address. Invitations made within an organization
+	// also make the invitee a member of it. Inviting with a role other than
+	// the default one requires the users:role permission.
+	Create(ctx context.Context, inviterID uuid.UUID, email, role string) (*entity.Invitation, error)
+	// InviteMember emails an invitation to join the organization with
+	// orgRole. The invitee only becomes a member by accepting it, whether or
+	// not the address has an account. Accounts created from it get the
+	// default role.
+	InviteMember(ctx context.Context, inviterID, organizationID uuid.UUID, email, orgRole string) (*entity.Invitation, error)
+	List(ctx context.Context) ([]*entity.Invitation, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	// Accept redeems an invitation. An account is created from name and
+	// password with the invited role, or an existing account with the
+	// invited address is attached. Existing accounts keep their role, since
+	// roles are global and the invitation may come from one organization.
+	Accept(ctx context.Context, token, name, password string) (*entity.User, error)
+}
+
+type invitationService struct {
+	config         *config.Config
+	invitationRepo repository.InvitationRepository
+	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
+	orgRepo        repository.OrganizationRepository
+	roleService    RoleService
+	passwordPolicy PasswordPolicy
+	mailer         mailer.Mailer
+}
+
+func NewInvitationService(
+	cfg *config.Config,
+	invitationRepo repository.InvitationRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	orgRepo repository.OrganizationRepository,
+	roleService RoleService,
+	passwordPolicy PasswordPolicy,
+	m mailer.Mailer,
+) InvitationService {
+	return &invitationService{
+		config:         cfg,
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		orgRepo:        orgRepo,
+		roleService:    roleService,
+		passwordPolicy: passwordPolicy,
+		mailer:         m,
+	}
+}
+
+func (s *invitationService) Create(ctx context.Context, inviterID uuid.UUID, email, role string) (*entity.Invitation, error) {
+	inviter, err := s.userRepo.FindByID(ctx, inviterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.roleRepo.FindByName(ctx, role); err != nil {
+		if err == errors.ErrRoleNotFound {
+			return nil, errors.ErrInvalidRole
+		}
+		return nil, err
+	}
+
+	// Otherwise an invitation could grant more than the inviter may assign
+	if role != entity.RoleUser {
+		granted, err := s.roleService.HasPermission(ctx, inviter.Roles, entity.PermissionUsersRole)
+		if err != nil {
+			return nil, err
+		}
+		if !granted {
+			return nil, errors.ErrForbidden
+		}
+	}
+
+	var organizationID *uuid.UUID
+	if id, ok := tenant.FromContext(ctx); ok {
+		organizationID = &id
+	}
+
+	return s.invite(ctx, inviter, email, role, organizationID, entity.OrgRoleMember)
+}
+
+func (s *invitationService) InviteMember(ctx context.Context, inviterID, organizationID uuid.UUID, email, orgRole string) (*entity.Invitation, error) {
+	ctx = tenant.WithID(ctx, organizationID)
+
+	inviter, err := s.userRepo.FindByID(ctx, inviterID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.invite(ctx, inviter, email, entity.RoleUser, &organizationID, orgRole)
+}
+
+// invite supersedes the pending invitations to the address, then stores and
+// emails a new one
+func (s *invitationService) invite(ctx context.Context, inviter *entity.User, email, role string, organizationID *uuid.UUID, orgRole string) (*entity.Invitation, error) {
+	if err := s.invitationRepo.RevokePending(ctx, email); err != nil {
+		return nil, err
+	}
+
+	token, err := generateOpaqueToken(oneTimeTokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := entity.NewInvitation(email, role, organizationID, orgRole, inviter.ID, hashOpaqueToken(token), s.config.Auth.InvitationTTL)
+	if err := s.invitationRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	link := s.config.App.PublicURL + "/accept-invitation?token=" + url.QueryEscape(token)
+	msg := &mailer.Message{
+		To:      email,
+		Subject: "You have been invited",
+		Body: fmt.Sprintf(
+			"Hi,\n\n%s has invited you to join. Follow the link below to accept the invitation. It expires in %s.\n\n%s\n\nIf you were not expecting this invitation you can ignore this email.\n",
+			inviter.Name, s.config.Auth.InvitationTTL, link,
+		),
+	}
+
+	// The invitation can be sent again by creating a new one
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Error().Err(err).Str("invitation_id", invitation.ID.String()).Msg("Failed to send invitation email")
+	}
+
+	return invitation, nil
+}
+
+func (s *invitationService) List(ctx context.Context) ([]*entity.Invitation, error) {
+	return s.invitationRepo.List(ctx)
+}
+
+func (s *invitationService) Revoke(ctx context.Context, id uuid.UUID) error {
+	return s.invitationRepo.Revoke(ctx, id)
+}
+
+func (s *invitationService) Accept(ctx context.Context, token, name, password string) (*entity.User, error) {
+	invitation, err := s.invitationRepo.FindByHash(ctx, hashOpaqueToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation.IsAccepted() || invitation.IsRevoked() {
+		return nil, errors.ErrInvalidToken
+	}
+
+	if invitation.IsExpired() {
+		return nil, errors.ErrTokenExpired
+	}
+
+	// Invitees are not members of any organization yet
+	ctx = tenant.Unscoped(ctx)
+
+	user, err := s.userRepo.FindByEmail(ctx, invitation.Email)
+	if err != nil && err != errors.ErrUserNotFound {
+		return nil, err
+	}
+	newUser := user == nil
+	if newUser {
+		if name == "" || password == "" {
+			return nil, errors.ErrInvalidInput
+		}
+		if err := s.passwordPolicy.Check(ctx, &entity.User{Email: invitation.Email, Name: name}, password); err != nil {
+			return nil, err
+		}
+		if user, err = entity.NewUser(invitation.Email, password, name); err != nil {
+			return nil, err
+		}
+		user.SetRoles([]string{invitation.Role})
+	}
+	if !user.EmailVerified {
+		// Following the emailed link proves the address
+		user.MarkEmailVerified()
+	}
+
+	var membership *entity.Membership
+	if invitation.OrganizationID != nil {
+		if membership, err = s.join(ctx, user, *invitation.OrganizationID, invitation.OrgRole); err != nil {
+			return nil, err
+		}
+	}
+
+	// Claims the invitation together with the changes, so a concurrent
+	// accept fails and a failed one leaves the invitation usable
+	if err := s.invitationRepo.Accept(ctx, invitation.ID, user, newUser, membership); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("invitation_id", invitation.ID.String()).
+		Str("user_id", user.ID.String()).
+		Strs("roles", user.Roles).
+		Msg("Invitation accepted")
+	return user, nil
+}
+
+// join selects the organization if the user has no active one and returns
+// the membership to add, or nil if the user is already a member, who keeps
+// the role
+func (s *invitationService) join(ctx context.Context, user *entity.User, organizationID uuid.UUID, role string) (*entity.Membership, error) {
+	var membership *entity.Membership
+	_, err := s.orgRepo.FindMembership(ctx, organizationID, user.ID)
+	switch err {
+	case nil:
+	case errors.ErrNotMember:
+		membership = entity.NewMembership(organizationID, user.ID, role)
+	default:
+		return nil, err
+	}
+
+	if user.ActiveOrganizationID == nil {
+		user.SetActiveOrganization(&organizationID)
+	}
+	return membership, nil
+}
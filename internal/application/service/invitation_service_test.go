@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockInvitationRepository is a mock implementation of repository.InvitationRepository
+type MockInvitationRepository struct {
+	mock.Mock
+}
+
+func (m *MockInvitationRepository) Create(ctx context.Context, invitation *entity.Invitation) error {
+	args := m.Called(ctx, invitation)
+	return args.Error(0)
+}
+
+func (m *MockInvitationRepository) FindByHash(ctx context.Context, tokenHash string) (*entity.Invitation, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Invitation), args.Error(1)
+}
+
+func (m *MockInvitationRepository) List(ctx context.Context) ([]*entity.Invitation, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*entity.Invitation), args.Error(1)
+}
+
+func (m *MockInvitationRepository) Accept(ctx context.Context, id uuid.UUID, user *entity.User, newUser bool, membership *entity.Membership) error {
+	args := m.Called(ctx, id, user, newUser, membership)
+	return args.Error(0)
+}
+
+func (m *MockInvitationRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockInvitationRepository) RevokePending(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+type invitationTestEnv struct {
+	service        InvitationService
+	invitationRepo *MockInvitationRepository
+	userRepo       *MockUserRepository
+	roleRepo       *MockRoleRepository
+	orgRepo        *MockOrganizationRepository
+	mailDir        string
+}
+
+func newInvitationTestEnv(t *testing.T) *invitationTestEnv {
+	cfg := newTestConfig()
+	cfg.App.PublicURL = "https://app.example.com"
+	cfg.Auth.InviteOnly = true
+	cfg.Auth.InvitationTTL = 24 * time.Hour
+
+	e := &invitationTestEnv{
+		invitationRepo: new(MockInvitationRepository),
+		userRepo:       new(MockUserRepository),
+		roleRepo:       new(MockRoleRepository),
+		orgRepo:        new(MockOrganizationRepository),
+		mailDir:        t.TempDir(),
+	}
+	m, err := mailer.NewFileMailer("no-reply@example.com", e.mailDir)
+	require.NoError(t, err)
+
+	roleService := newSeededRoleService(t, e.roleRepo, e.userRepo)
+	e.service = NewInvitationService(cfg, e.invitationRepo, e.userRepo, e.roleRepo, e.orgRepo, roleService, newAllowingPasswordPolicy(), m)
+	return e
+}
+
+func TestInvitationService_Create(t *testing.T) {
+	orgID := uuid.New()
+	ctx := tenant.WithID(context.Background(), orgID)
+	support := entity.NewRole("support", "", []*entity.Permission{{Name: entity.PermissionUsersInvite}})
+	admin := entity.NewRole(entity.RoleAdmin, "", entity.Permissions)
+
+	t.Run("Success", func(t *testing.T) {
+		e := newInvitationTestEnv(t)
+		inviter, _ := entity.NewUser("admin@example.com", "password123", "Admin")
+		inviter.SetRoles([]string{entity.RoleAdmin})
+		e.userRepo.On("FindByID", ctx, inviter.ID).Return(inviter, nil)
+		e.roleRepo.On("FindByName", ctx, "support").Return(support, nil)
+		e.roleRepo.On("FindByName", ctx, entity.RoleAdmin).Return(admin, nil)
+		e.invitationRepo.On("RevokePending", ctx, "new@example.com").Return(nil)
+		e.invitationRepo.On("Create", ctx, mock.AnythingOfType("*entity.Invitation")).Return(nil)
+
+		invitation, err := e.service.Create(ctx, inviter.ID, "new@example.com", "support")
+
+		require.NoError(t, err)
+		assert.Equal(t, "support", invitation.Role)
+		assert.Equal(t, orgID, *invitation.OrganizationID)
+		assert.True(t, invitation.IsPending())
+
+		messages := readMail(t, e.mailDir)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "https://app.example.com/accept-invitation?token=")
+		token := resetLinkPattern.FindStringSubmatch(messages[0])[1]
+		assert.Equal(t, hashOpaqueToken(token), invitation.TokenHash)
+	})
+
+	t.Run("RoleNeedsPermission", func(t *testing.T) {
+		e := newInvitationTestEnv(t)
+		inviter, _ := entity.NewUser("support@example.com", "password123", "Support")
+		inviter.SetRoles([]string{"support"})
+		e.userRepo.On("FindByID", ctx, inviter.ID).Return(inviter, nil)
+		e.roleRepo.On("FindByName", ctx, entity.RoleAdmin).Return(admin, nil)
+		e.roleRepo.On("FindByName", ctx, "support").Return(support, nil)
+
+		_, err := e.service.Create(ctx, inviter.ID, "new@example.com", entity.RoleAdmin)
+
+		assert.Equal(t, errors.ErrForbidden, err)
+		e.invitationRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("UnknownRole", func(t *testing.T) {
+		e := newInvitationTestEnv(t)
+		inviter, _ := entity.NewUser("admin@example.com", "password123", "Admin")
+		e.userRepo.On("FindByID", ctx, inviter.ID).Return(inviter, nil)
+		e.roleRepo.On("FindByName", ctx, "superuser").Return(nil, errors.ErrRoleNotFound)
+
+		_, err := e.service.Create(ctx, inviter.ID, "new@example.com", "superuser")
+
+		assert.Equal(t, errors.ErrInvalidRole, err)
+	})
+}
+
+func TestInvitationService_Accept(t *testing.T) {
+	ctx := context.Background()
+	unscoped := tenant.Unscoped(ctx)
+	orgID := uuid.New()
+
+	newInvitation := func(email string) (*entity.Invitation, string) {
+		token, _ := generateOpaqueToken(oneTimeTokenSize)
+		return entity.NewInvitation(email, "support", &orgID, entity.OrgRoleMember, uuid.New(), hashOpaqueToken(token), time.Hour), token
+	}
+
+	t.Run("CreatesUser", func(t *testing.T) {
+		e := newInvitationTestEnv(t)
+		invitation, token := newInvitation("new@example.com")
+		e.invitationRepo.On("FindByHash", ctx, invitation.TokenHash).Return(invitation, nil)
+		e.userRepo.On("FindByEmail", unscoped, invitation.Email).Return(nil, errors.ErrUserNotFound)
+		e.orgRepo.On("FindMembership", unscoped, orgID, mock.Anything).Return(nil, errors.ErrNotMember)
+		e.invitationRepo.On("Accept", unscoped, invitation.ID, mock.AnythingOfType("*entity.User"), true, mock.AnythingOfType("*entity.Membership")).Return(nil)
+
+		user, err := e.service.Accept(ctx, token, "New User", "password123")
+
+		require.NoError(t, err)
+		assert.Equal(t, invitation.Email, user.Email)
+		assert.Equal(t, []string{"support"}, user.Roles)
+		assert.True(t, user.EmailVerified)
+		assert.Equal(t, orgID, *user.ActiveOrganizationID)
+		call := e.invitationRepo.Calls[1]
+		assert.Same(t, user, call.Arguments.Get(2))
+		membership := call.Arguments.Get(4).(*entity.Membership)
+		assert.Equal(t, user.ID, membership.UserID)
+		assert.Equal(t, entity.OrgRoleMember, membership.Role)
+	})
+
+	t.Run("AttachesExistingUser", func(t *testing.T) {
+		e := newInvitationTestEnv(t)
+		invitation, token := newInvitation("existing@example.com")
+		invitation.OrgRole = entity.OrgRoleAdmin
+		existing, _ := entity.NewUser(invitation.Email, "password123", "Existing")
+		existing.SetRoles([]string{entity.RoleAdmin})
+		e.invitationRepo.On("FindByHash", ctx, invitation.TokenHash).Return(invitation, nil)
+		e.userRepo.On("FindByEmail", unscoped, invitation.Email).Return(existing, nil)
+		e.orgRepo.On("FindMembership", unscoped, orgID, existing.ID).Return(nil, errors.ErrNotMember)
+		e.invitationRepo.On("Accept", unscoped, invitation.ID, existing, false, mock.AnythingOfType("*entity.Membership")).Return(nil)
+
+		user, err := e.service.Accept(ctx, token, "", "")
+
+		require.NoError(t, err)
+		assert.Equal(t, existing.ID, user.ID)
+		assert.Equal(t, []string{entity.RoleAdmin}, user.Roles)
+		assert.True(t, user.EmailVerified)
+		membership := e.invitationRepo.Calls[1].Arguments.Get(4).(*entity.Membership)
+		assert.Equal(t, entity.OrgRoleAdmin, membership.Role)
+	})
+
+	t.Run("ExistingMember", func(t *testing.T) {
+		e := newInvitationTestEnv(t)
+		invitation, token := newInvitation("member@example.com")
+		existing, _ := entity.NewUser(invitation.Email, "password123", "Member")
+		e.invitationRepo.On("FindByHash", ctx, invitation.TokenHash).Return(invitation, nil)
+		e.userRepo.On("FindByEmail", unscoped, invitation.Email).Return(existing, nil)
+		e.orgRepo.On("FindMembership", unscoped, orgID, existing.ID).Return(entity.NewMembership(orgID, existing.ID, entity.OrgRoleOwner), nil)
+		e.invitationRepo.On("Accept", unscoped, invitation.ID, existing, false, (*entity.Membership)(nil)).Return(nil)
+
+		_, err := e.service.Accept(ctx, token, "", "")
+
+		require.NoError(t, err)
+		e.invitationRepo.AssertExpectations(t)
+	})
+
+	t.Run("ClaimedMeanwhile", func(t *testing.T) {
+		e := newInvitationTestEnv(t)
+		invitation, token := newInvitation("new@example.com")
+		e.invitationRepo.On("FindByHash", ctx, invitation.TokenHash).Return(invitation, nil)
+		e.userRepo.On("FindByEmail", unscoped, invitation.Email).Return(nil, errors.ErrUserNotFound)
+		e.orgRepo.On("FindMembership", unscoped, orgID, mock.Anything).Return(nil, errors.ErrNotMember)
+		e.invitationRepo.On("Accept", unscoped, invitation.ID, mock.Anything, true, mock.Anything).Return(errors.ErrInvalidToken)
+
+		_, err := e.service.Accept(ctx, token, "New User", "password123")
+
+		assert.Equal(t, errors.ErrInvalidToken, err)
+	})
+
+	t.Run("NewUserNeedsPassword", func(t *testing.T) {
+		e := newInvitationTestEnv(t)
+		invitation, token := newInvitation("new@example.com")
+		e.invitationRepo.On("FindByHash", ctx, invitation.TokenHash).Return(invitation, nil)
+		e.userRepo.On("FindByEmail", unscoped, invitation.Email).Return(nil, errors.ErrUserNotFound)
+
+		_, err := e.service.Accept(ctx, token, "New User", "")
+
+		assert.Equal(t, errors.ErrInvalidInput, err)
+		e.invitationRepo.AssertNotCalled(t, "Accept", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unusable", func(t *testing.T) {
+		accepted, acceptedToken := newInvitation("a@example.com")
+		now := time.Now()
+		accepted.AcceptedAt = &now
+		revoked, revokedToken := newInvitation("b@example.com")
+		revoked.RevokedAt = &now
+		expired, expiredToken := newInvitation("c@example.com")
+		expired.ExpiresAt = now.Add(-time.Minute)
+
+		tests := []struct {
+			name       string
+			invitation *entity.Invitation
+			token      string
+			err        error
+		}{
+			{"Accepted", accepted, acceptedToken, errors.ErrInvalidToken},
+			{"Revoked", revoked, revokedToken, errors.ErrInvalidToken},
+			{"Expired", expired, expiredToken, errors.ErrTokenExpired},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				e := newInvitationTestEnv(t)
+				e.invitationRepo.On("FindByHash", ctx, tt.invitation.TokenHash).Return(tt.invitation, nil)
+
+				_, err := e.service.Accept(ctx, tt.token, "Name", "password123")
+
+				assert.Equal(t, tt.err, err)
+			})
+		}
+	})
+}
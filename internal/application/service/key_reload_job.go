@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/rs/zerolog/log"
+)
+
+// KeyReloadJob keeps the key ring in step with the stored signing keys when
+// several instances share the database. Keys created, promoted or retired
+// through one instance are otherwise unknown to the others until they restart.
+type KeyReloadJob struct {
+	config     *config.Config
+	keyService KeyService
+}
+
+func NewKeyReloadJob(cfg *config.Config, keyService KeyService) *KeyReloadJob {
+	return &KeyReloadJob{
+		config:     cfg,
+		keyService: keyService,
+	}
+}
+
+// Start reloads the keys every JWT.KeyReloadInterval until ctx is done. It
+// does nothing when the interval is zero.
+func (j *KeyReloadJob) Start(ctx context.Context) {
+	interval := j.config.JWT.KeyReloadInterval
+	if interval <= 0 {
+		return
+	}
+
+	go j.run(ctx, interval)
+}
+
+func (j *KeyReloadJob) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// The current keys stay in use until a reload succeeds
+		if err := j.keyService.Reload(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to reload signing keys")
+		}
+	}
+}
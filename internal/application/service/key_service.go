@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/security"
+)
+
+// KeyService manages the lifecycle of JWT signing keys. A rotation creates a
+// pending key (published in the JWKS so verifiers can cache it), promotes it
+// to sign new tokens, and leaves the previous key verifying for a grace period.
+type KeyService interface {
+	List(ctx context.Context) ([]*entity.SigningKey, error)
+	Create(ctx context.Context) (*entity.SigningKey, error)
+	Promote(ctx context.Context, kid string, gracePeriod time.Duration) (*entity.SigningKey, error)
+	Retire(ctx context.Context, kid string) error
+	// Reload replaces the key ring with the persisted keys, picking up
+	// changes made through other instances
+	Reload(ctx context.Context) error
+}
+
+type keyService struct {
+	config    *config.Config
+	keyRepo   repository.SigningKeyRepository
+	encrypter *security.KeyEncrypter
+	ring      *security.KeyRing
+}
+
+func NewKeyService(cfg *config.Config, keyRepo repository.SigningKeyRepository, encrypter *security.KeyEncrypter, ring *security.KeyRing) KeyService {
+	return &keyService{
+		config:    cfg,
+		keyRepo:   keyRepo,
+		encrypter: encrypter,
+		ring:      ring,
+	}
+}
+
+// LoadKeyRing builds the key ring from the persisted keys. A configured key
+// that has not been seen before is promoted as if it had been rotated in
+// through the API, so replacing JWT_SECRET or JWT_PRIVATE_KEY_PATH no longer
+// invalidates every outstanding token. Keys stored in plaintext by earlier
+// versions are encrypted first.
+func LoadKeyRing(cfg *config.Config, keyRepo repository.SigningKeyRepository, encrypter *security.KeyEncrypter) (*security.KeyRing, error) {
+	ctx := context.Background()
+
+	configured, err := security.LoadSigningKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := encryptStoredKeys(ctx, keyRepo, encrypter); err != nil {
+		return nil, err
+	}
+
+	if _, err := keyRepo.FindByID(ctx, configured.ID); err != nil {
+		if err != errors.ErrSigningKeyNotFound {
+			return nil, err
+		}
+
+		record, err := newKeyRecord(encrypter, configured)
+		if err != nil {
+			return nil, err
+		}
+		if err := keyRepo.Create(ctx, record); err != nil {
+			return nil, err
+		}
+		if err := promoteKey(ctx, keyRepo, record, cfg.JWT.KeyGracePeriod); err != nil {
+			return nil, err
+		}
+	}
+
+	ring := security.NewKeyRing(configured)
+	if err := reloadKeyRing(ctx, keyRepo, encrypter, ring); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+func (s *keyService) List(ctx context.Context) ([]*entity.SigningKey, error) {
+	return s.keyRepo.List(ctx)
+}
+
+func (s *keyService) Create(ctx context.Context) (*entity.SigningKey, error) {
+	key, err := security.GenerateSigningKey(s.config.JWT.SigningAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := newKeyRecord(s.encrypter, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.keyRepo.Create(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return record, reloadKeyRing(ctx, s.keyRepo, s.encrypter, s.ring)
+}
+
+func (s *keyService) Promote(ctx context.Context, kid string, gracePeriod time.Duration) (*entity.SigningKey, error) {
+	record, err := s.keyRepo.FindByID(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Status == entity.SigningKeyActive {
+		return record, nil
+	}
+	if record.IsExpired() {
+		return nil, errors.ErrSigningKeyExpired
+	}
+
+	if gracePeriod <= 0 {
+		gracePeriod = s.config.JWT.KeyGracePeriod
+	}
+
+	if err := promoteKey(ctx, s.keyRepo, record, gracePeriod); err != nil {
+		return nil, err
+	}
+
+	return record, reloadKeyRing(ctx, s.keyRepo, s.encrypter, s.ring)
+}
+
+// Retire immediately stops a non-active key from verifying, e.g. after a compromise
+func (s *keyService) Retire(ctx context.Context, kid string) error {
+	record, err := s.keyRepo.FindByID(ctx, kid)
+	if err != nil {
+		return err
+	}
+
+	if record.Status == entity.SigningKeyActive {
+		return errors.ErrSigningKeyActive
+	}
+
+	record.Retire(0)
+	if err := s.keyRepo.SaveAll(ctx, record); err != nil {
+		return err
+	}
+
+	return reloadKeyRing(ctx, s.keyRepo, s.encrypter, s.ring)
+}
+
+func (s *keyService) Reload(ctx context.Context) error {
+	return reloadKeyRing(ctx, s.keyRepo, s.encrypter, s.ring)
+}
+
+// promoteKey makes record the active key and retires the previous one
+func promoteKey(ctx context.Context, keyRepo repository.SigningKeyRepository, record *entity.SigningKey, gracePeriod time.Duration) error {
+	keys, err := keyRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	changed := []*entity.SigningKey{record}
+	for _, key := range keys {
+		if key.Status == entity.SigningKeyActive && key.ID != record.ID {
+			key.Retire(gracePeriod)
+			changed = append(changed, key)
+		}
+	}
+
+	record.Activate()
+	return keyRepo.SaveAll(ctx, changed...)
+}
+
+// newKeyRecord prepares key for storage with its material encrypted
+func newKeyRecord(encrypter *security.KeyEncrypter, key *security.SigningKey) (*entity.SigningKey, error) {
+	material, err := key.Material()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := encrypter.Seal(key.ID, material)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewSigningKey(key.ID, key.Algorithm(), sealed), nil
+}
+
+// encryptStoredKeys encrypts the material of keys stored in plaintext
+func encryptStoredKeys(ctx context.Context, keyRepo repository.SigningKeyRepository, encrypter *security.KeyEncrypter) error {
+	records, err := keyRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var plaintext []*entity.SigningKey
+	for _, record := range records {
+		if record.Encrypted {
+			continue
+		}
+
+		sealed, err := encrypter.Seal(record.ID, record.Material)
+		if err != nil {
+			return err
+		}
+		record.Material = sealed
+		record.Encrypted = true
+		plaintext = append(plaintext, record)
+	}
+
+	if len(plaintext) == 0 {
+		return nil
+	}
+	return keyRepo.SaveAll(ctx, plaintext...)
+}
+
+// reloadKeyRing replaces the ring contents with the persisted keys
+func reloadKeyRing(ctx context.Context, keyRepo repository.SigningKeyRepository, encrypter *security.KeyEncrypter, ring *security.KeyRing) error {
+	records, err := keyRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var active *security.SigningKey
+	verifying := make(map[*security.SigningKey]*time.Time)
+
+	for _, record := range records {
+		if record.IsExpired() {
+			continue
+		}
+
+		material, err := encrypter.Open(record.ID, record.Material)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key %s: %w", record.ID, err)
+		}
+
+		key, err := security.NewSigningKeyFromMaterial(record.ID, record.Algorithm, material)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key %s: %w", record.ID, err)
+		}
+
+		if record.Status == entity.SigningKeyActive {
+			active = key
+		} else {
+			verifying[key] = record.ExpiresAt
+		}
+	}
+
+	if active == nil {
+		return fmt.Errorf("no active signing key")
+	}
+
+	ring.Replace(active, verifying)
+	return nil
+}
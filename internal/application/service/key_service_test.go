@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockSigningKeyRepository is a mock implementation of repository.SigningKeyRepository
+type MockSigningKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockSigningKeyRepository) Create(ctx context.Context, key *entity.SigningKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockSigningKeyRepository) FindByID(ctx context.Context, id string) (*entity.SigningKey, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.SigningKey), args.Error(1)
+}
+
+func (m *MockSigningKeyRepository) List(ctx context.Context) ([]*entity.SigningKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*entity.SigningKey), args.Error(1)
+}
+
+func (m *MockSigningKeyRepository) SaveAll(ctx context.Context, keys ...*entity.SigningKey) error {
+	args := m.Called(ctx, keys)
+	return args.Error(0)
+}
+
+func newTestKeyEncrypter(t *testing.T) *security.KeyEncrypter {
+	cfg := newTestConfig()
+	cfg.JWT.KeyEncryptionKey = "test-key-encryption-key"
+	encrypter, err := security.NewKeyEncrypter(cfg)
+	require.NoError(t, err)
+	return encrypter
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+	encrypter := newTestKeyEncrypter(t)
+	configured, err := security.LoadSigningKey(cfg)
+	require.NoError(t, err)
+
+	t.Run("Stores The Configured Key Encrypted", func(t *testing.T) {
+		keyRepo := new(MockSigningKeyRepository)
+		// Filled in by Create for the final reload
+		stored := make([]*entity.SigningKey, 1)
+		keyRepo.On("List", ctx).Return([]*entity.SigningKey{}, nil).Twice()
+		keyRepo.On("FindByID", ctx, configured.ID).Return(nil, errors.ErrSigningKeyNotFound)
+		keyRepo.On("Create", ctx, mock.AnythingOfType("*entity.SigningKey")).Run(func(args mock.Arguments) {
+			stored[0] = args.Get(1).(*entity.SigningKey)
+		}).Return(nil)
+		keyRepo.On("SaveAll", ctx, mock.Anything).Return(nil)
+		keyRepo.On("List", ctx).Return(stored, nil)
+
+		ring, err := LoadKeyRing(cfg, keyRepo, encrypter)
+
+		require.NoError(t, err)
+		assert.Equal(t, configured.ID, ring.Active().ID)
+		assert.True(t, stored[0].Encrypted)
+		assert.NotContains(t, string(stored[0].Material), cfg.JWT.Secret)
+		keyRepo.AssertExpectations(t)
+	})
+
+	t.Run("Encrypts Plaintext Keys", func(t *testing.T) {
+		keyRepo := new(MockSigningKeyRepository)
+		legacy := entity.NewSigningKey(configured.ID, configured.Algorithm(), []byte(cfg.JWT.Secret))
+		legacy.Encrypted = false
+		legacy.Activate()
+		keyRepo.On("List", ctx).Return([]*entity.SigningKey{legacy}, nil)
+		keyRepo.On("SaveAll", ctx, []*entity.SigningKey{legacy}).Return(nil).Once()
+		keyRepo.On("FindByID", ctx, configured.ID).Return(legacy, nil)
+
+		ring, err := LoadKeyRing(cfg, keyRepo, encrypter)
+
+		require.NoError(t, err)
+		assert.Equal(t, configured.ID, ring.Active().ID)
+		assert.True(t, legacy.Encrypted)
+		material, err := encrypter.Open(legacy.ID, legacy.Material)
+		require.NoError(t, err)
+		assert.Equal(t, cfg.JWT.Secret, string(material))
+		keyRepo.AssertExpectations(t)
+	})
+
+	t.Run("Wrong Encryption Key", func(t *testing.T) {
+		keyRepo := new(MockSigningKeyRepository)
+		sealed, err := encrypter.Seal(configured.ID, []byte(cfg.JWT.Secret))
+		require.NoError(t, err)
+		record := entity.NewSigningKey(configured.ID, configured.Algorithm(), sealed)
+		record.Activate()
+		keyRepo.On("List", ctx).Return([]*entity.SigningKey{record}, nil)
+		keyRepo.On("FindByID", ctx, configured.ID).Return(record, nil)
+
+		other := newTestConfig()
+		other.JWT.KeyEncryptionKey = "another-key"
+		otherEncrypter, err := security.NewKeyEncrypter(other)
+		require.NoError(t, err)
+
+		_, err = LoadKeyRing(cfg, keyRepo, otherEncrypter)
+		assert.Error(t, err)
+	})
+}
+
+func TestKeyService_Reload(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+	encrypter := newTestKeyEncrypter(t)
+	configured, err := security.LoadSigningKey(cfg)
+	require.NoError(t, err)
+
+	// Another instance rotated in a new key and retired the configured one
+	rotated, err := security.GenerateSigningKey("HS256")
+	require.NoError(t, err)
+	newRecord, err := newKeyRecord(encrypter, rotated)
+	require.NoError(t, err)
+	newRecord.Activate()
+	oldRecord, err := newKeyRecord(encrypter, configured)
+	require.NoError(t, err)
+	oldRecord.Retire(time.Hour)
+
+	keyRepo := new(MockSigningKeyRepository)
+	keyRepo.On("List", mock.Anything).Return([]*entity.SigningKey{oldRecord, newRecord}, nil)
+
+	t.Run("Reload", func(t *testing.T) {
+		ring := security.NewKeyRing(configured)
+		service := NewKeyService(cfg, keyRepo, encrypter, ring)
+
+		require.NoError(t, service.Reload(ctx))
+
+		assert.Equal(t, rotated.ID, ring.Active().ID)
+		_, ok := ring.Lookup(configured.ID)
+		assert.True(t, ok)
+	})
+
+	t.Run("Job", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.JWT.KeyReloadInterval = 10 * time.Millisecond
+		ring := security.NewKeyRing(configured)
+		jobCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		NewKeyReloadJob(cfg, NewKeyService(cfg, keyRepo, encrypter, ring)).Start(jobCtx)
+
+		assert.Eventually(t, func() bool {
+			_, ok := ring.Lookup(rotated.ID)
+			return ok
+		}, time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/cache"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestLockoutConfig() *config.Config {
+	cfg := newTestConfig()
+	cfg.Lockout = config.LockoutConfig{
+		MaxAttempts:   3,
+		Duration:      15 * time.Minute,
+		DelayBase:     time.Second,
+		IPMaxAttempts: 5,
+		IPWindow:      time.Minute,
+	}
+	return cfg
+}
+
+func TestLockoutService_AccountLockout(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestLockoutConfig()
+	mockUserRepo := new(MockUserRepository)
+	service := NewLockoutService(cfg, mockUserRepo, cache.NewMemoryCache(cfg))
+	client := ClientInfo{IP: "192.0.2.1"}
+
+	user, _ := entity.NewUser("lock@example.com", "password123", "Lock User")
+	mockUserRepo.On("IncrementFailedLogins", ctx, user.ID).Return(1, nil).Once()
+	mockUserRepo.On("IncrementFailedLogins", ctx, user.ID).Return(2, nil).Once()
+	mockUserRepo.On("IncrementFailedLogins", ctx, user.ID).Return(3, nil).Once()
+	mockUserRepo.On("ExtendLock", ctx, user.ID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockUserRepo.On("ResetFailedLogins", ctx, user.ID).Return(nil)
+
+	assert.NoError(t, service.CheckAccount(user))
+
+	// The first failures only delay the next attempt, doubling each time
+	assert.NoError(t, service.RecordFailure(ctx, user, client))
+	assert.Equal(t, errors.ErrTooManyRequests, service.CheckAccount(user))
+	assert.WithinDuration(t, time.Now().Add(time.Second), *user.LockedUntil, 100*time.Millisecond)
+
+	assert.NoError(t, service.RecordFailure(ctx, user, client))
+	assert.WithinDuration(t, time.Now().Add(2*time.Second), *user.LockedUntil, 100*time.Millisecond)
+
+	// Reaching the threshold locks the account
+	assert.NoError(t, service.RecordFailure(ctx, user, client))
+	assert.Equal(t, errors.ErrAccountLocked, service.CheckAccount(user))
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), *user.LockedUntil, time.Second)
+
+	mockUserRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+	status, err := service.Status(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, status.Locked)
+	assert.Equal(t, 3, status.FailedAttempts)
+
+	assert.NoError(t, service.Unlock(ctx, user.ID))
+	mockUserRepo.AssertCalled(t, "ResetFailedLogins", ctx, user.ID)
+	mockUserRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestLockoutService_CountsStoredAttempts(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestLockoutConfig()
+	mockUserRepo := new(MockUserRepository)
+	service := NewLockoutService(cfg, mockUserRepo, cache.NewMemoryCache(cfg))
+
+	// A stale copy of the user, read before parallel failures were counted
+	user, _ := entity.NewUser("stale@example.com", "password123", "Stale User")
+	mockUserRepo.On("IncrementFailedLogins", ctx, user.ID).Return(5, nil)
+	mockUserRepo.On("ExtendLock", ctx, user.ID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	assert.NoError(t, service.RecordFailure(ctx, user, ClientInfo{}))
+
+	assert.Equal(t, errors.ErrAccountLocked, service.CheckAccount(user))
+	lockedUntil := mockUserRepo.Calls[1].Arguments.Get(2).(time.Time)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), lockedUntil, time.Second)
+}
+
+func TestLockoutService_RecordSuccess(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestLockoutConfig()
+	mockUserRepo := new(MockUserRepository)
+	service := NewLockoutService(cfg, mockUserRepo, cache.NewMemoryCache(cfg))
+
+	user, _ := entity.NewUser("ok@example.com", "password123", "OK User")
+
+	// Nothing to reset, nothing written
+	assert.NoError(t, service.RecordSuccess(ctx, user))
+	mockUserRepo.AssertNotCalled(t, "ResetFailedLogins", mock.Anything, mock.Anything)
+
+	mockUserRepo.On("ResetFailedLogins", ctx, user.ID).Return(nil)
+	user.RecordFailedLogin(1, time.Time{})
+	assert.NoError(t, service.RecordSuccess(ctx, user))
+	assert.Equal(t, 0, user.FailedLogins)
+	mockUserRepo.AssertNumberOfCalls(t, "ResetFailedLogins", 1)
+}
+
+func TestLockoutService_ClientThrottle(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestLockoutConfig()
+	service := NewLockoutService(cfg, new(MockUserRepository), cache.NewMemoryCache(cfg))
+	client := ClientInfo{IP: "198.51.100.7"}
+
+	// Failures against unknown emails still count for the IP
+	for i := 0; i < cfg.Lockout.IPMaxAttempts; i++ {
+		assert.NoError(t, service.CheckClient(client))
+		assert.NoError(t, service.RecordFailure(ctx, nil, client))
+	}
+
+	assert.Equal(t, errors.ErrTooManyRequests, service.CheckClient(client))
+	assert.NoError(t, service.CheckClient(ClientInfo{IP: "198.51.100.8"}))
+}
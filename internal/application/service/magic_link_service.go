@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/mailer"
+	"github.com/rs/zerolog/log"
+)
+
+type MagicLinkService interface {
+	// Request emails a single-use login link if the address belongs to an
+	// active user. It reports success either way so callers cannot probe for
+	// accounts. The lookup and the email are left to the mail queue, so the
+	// response time does not tell them apart either.
+	Request(ctx context.Context, email string, client ClientInfo) error
+	// Verify redeems a login link and logs its user in like any other first
+	// factor, including the MFA challenge
+	Verify(ctx context.Context, token string, client ClientInfo) (*LoginResult, error)
+}
+
+type magicLinkService struct {
+	config         *config.Config
+	userRepo       repository.UserRepository
+	tokenRepo      repository.OneTimeTokenRepository
+	mailer         mailer.Mailer
+	mailQueue      *MailQueue
+	authService    AuthService
+	lockoutService LockoutService
+}
+
+func NewMagicLinkService(
+	cfg *config.Config,
+	userRepo repository.UserRepository,
+	tokenRepo repository.OneTimeTokenRepository,
+	m mailer.Mailer,
+	mailQueue *MailQueue,
+	authService AuthService,
+	lockoutService LockoutService,
+) MagicLinkService {
+	return &magicLinkService{
+		config:         cfg,
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		mailer:         m,
+		mailQueue:      mailQueue,
+		authService:    authService,
+		lockoutService: lockoutService,
+	}
+}
+
+func (s *magicLinkService) Request(ctx context.Context, email string, client ClientInfo) error {
+	if !s.config.Auth.MagicLinkEnabled {
+		return errors.ErrMagicLinkDisabled
+	}
+
+	if err := s.lockoutService.CheckClient(client); err != nil {
+		return err
+	}
+
+	// The work outlives the request, which is done once it is handed off
+	ctx = context.WithoutCancel(ctx)
+	queued := s.mailQueue.Enqueue(func() {
+		if err := s.sendLink(ctx, email); err != nil {
+			log.Error().Err(err).Msg("Failed to send magic link")
+		}
+	})
+	if !queued {
+		log.Warn().Msg("Mail queue is full; magic link dropped")
+	}
+	return nil
+}
+
+// sendLink issues a login token for the user with the email address and
+// mails the link. Unknown, inactive and locked users are skipped, as are
+// repeated requests.
+func (s *magicLinkService) sendLink(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if err == errors.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+
+	// Locked accounts get no link either; it could not be used
+	if !user.Active || s.lockoutService.CheckAccount(user) != nil {
+		return nil
+	}
+
+	// Silently drop repeated requests so the inbox can't be flooded
+	latest, err := s.tokenRepo.FindLatest(ctx, user.ID, entity.TokenPurposeMagicLink)
+	if err != nil && err != errors.ErrNotFound {
+		return err
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < s.config.Auth.MagicLinkResendInterval {
+		return nil
+	}
+
+	token, err := issueOneTimeToken(ctx, s.tokenRepo, user.ID, entity.TokenPurposeMagicLink, s.config.Auth.MagicLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.config.App.PublicURL + "/magic-link?token=" + url.QueryEscape(token)
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to log in. It expires in %s and can only be used once.\n\n%s\n\nIf you did not try to log in you can ignore this email.\n",
+			user.Name, s.config.Auth.MagicLinkTTL, link,
+		),
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+func (s *magicLinkService) Verify(ctx context.Context, token string, client ClientInfo) (*LoginResult, error) {
+	if !s.config.Auth.MagicLinkEnabled {
+		return nil, errors.ErrMagicLinkDisabled
+	}
+
+	if err := s.lockoutService.CheckClient(client); err != nil {
+		return nil, err
+	}
+
+	record, err := s.tokenRepo.FindByHash(ctx, entity.TokenPurposeMagicLink, hashOpaqueToken(token))
+	if err != nil {
+		if err == errors.ErrInvalidToken {
+			// Guessed links count against the client like wrong passwords
+			if err := s.lockoutService.RecordFailure(ctx, nil, client); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if record.IsUsed() {
+		return nil, errors.ErrInvalidToken
+	}
+
+	if record.IsExpired() {
+		return nil, errors.ErrTokenExpired
+	}
+
+	if err := s.tokenRepo.MarkUsed(ctx, record.ID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, record.UserID)
+	if err != nil {
+		if err == errors.ErrUserNotFound {
+			return nil, errors.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	// Following the link proves control of the address
+	if !user.EmailVerified {
+		user.MarkEmailVerified()
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.authService.AuthenticateUser(ctx, user, []string{AuthMethodMagicLink}, client)
+}
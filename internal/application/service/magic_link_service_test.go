@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMagicLinkService_Request(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+	cfg.App.PublicURL = "https://app.example.com"
+	cfg.Auth.MagicLinkEnabled = true
+	cfg.Auth.MagicLinkTTL = 15 * time.Minute
+	cfg.Auth.MagicLinkResendInterval = time.Minute
+
+	t.Run("KnownEmail", func(t *testing.T) {
+		mailDir := t.TempDir()
+		m, _ := mailer.NewFileMailer("no-reply@example.com", mailDir)
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		queue := NewMailQueue(cfg)
+		service := NewMagicLinkService(cfg, mockUserRepo, mockTokenRepo, m, queue, nil, newAllowingLockoutService())
+
+		user, _ := entity.NewUser("magic@example.com", "password123", "Magic User")
+		// The request only hands the work off, so its context is not passed on
+		mockUserRepo.On("FindByEmail", mock.Anything, user.Email).Return(user, nil)
+		mockTokenRepo.On("FindLatest", mock.Anything, user.ID, entity.TokenPurposeMagicLink).Return(nil, errors.ErrNotFound)
+		mockTokenRepo.On("InvalidateForUser", mock.Anything, user.ID, entity.TokenPurposeMagicLink).Return(nil)
+		mockTokenRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.OneTimeToken")).Return(nil)
+
+		err := service.Request(ctx, user.Email, ClientInfo{})
+
+		assert.NoError(t, err)
+		require.NoError(t, queue.Shutdown(ctx))
+		messages := readMail(t, mailDir)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "https://app.example.com/magic-link?token=")
+
+		token := resetLinkPattern.FindStringSubmatch(messages[0])[1]
+		stored := mockTokenRepo.Calls[2].Arguments.Get(1).(*entity.OneTimeToken)
+		assert.Equal(t, hashOpaqueToken(token), stored.TokenHash)
+		assert.Equal(t, entity.TokenPurposeMagicLink, stored.Purpose)
+		mockTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("RecentlySent", func(t *testing.T) {
+		mailDir := t.TempDir()
+		m, _ := mailer.NewFileMailer("no-reply@example.com", mailDir)
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		queue := NewMailQueue(cfg)
+		service := NewMagicLinkService(cfg, mockUserRepo, mockTokenRepo, m, queue, nil, newAllowingLockoutService())
+
+		user, _ := entity.NewUser("again@example.com", "password123", "Again User")
+		latest := entity.NewOneTimeToken(user.ID, entity.TokenPurposeMagicLink, "hash", time.Hour)
+		mockUserRepo.On("FindByEmail", mock.Anything, user.Email).Return(user, nil)
+		mockTokenRepo.On("FindLatest", mock.Anything, user.ID, entity.TokenPurposeMagicLink).Return(latest, nil)
+
+		err := service.Request(ctx, user.Email, ClientInfo{})
+
+		assert.NoError(t, err)
+		require.NoError(t, queue.Shutdown(ctx))
+		assert.Empty(t, readMail(t, mailDir))
+		mockTokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("UnknownEmail", func(t *testing.T) {
+		mailDir := t.TempDir()
+		m, _ := mailer.NewFileMailer("no-reply@example.com", mailDir)
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		queue := NewMailQueue(cfg)
+		service := NewMagicLinkService(cfg, mockUserRepo, mockTokenRepo, m, queue, nil, newAllowingLockoutService())
+		mockUserRepo.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, errors.ErrUserNotFound)
+
+		err := service.Request(ctx, "nobody@example.com", ClientInfo{})
+
+		assert.NoError(t, err)
+		require.NoError(t, queue.Shutdown(ctx))
+		mockUserRepo.AssertExpectations(t)
+		assert.Empty(t, readMail(t, mailDir))
+		mockTokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Auth.MagicLinkEnabled = false
+		service := NewMagicLinkService(cfg, new(MockUserRepository), new(MockOneTimeTokenRepository), mailer.NewLogMailer("no-reply@example.com"), NewMailQueue(cfg), nil, newAllowingLockoutService())
+
+		err := service.Request(ctx, "magic@example.com", ClientInfo{})
+
+		assert.Equal(t, errors.ErrMagicLinkDisabled, err)
+	})
+}
+
+func TestMagicLinkService_Verify(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+	cfg.Auth.MagicLinkEnabled = true
+
+	newService := func() (MagicLinkService, *MockUserRepository, *MockOneTimeTokenRepository, *MockLockoutService) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		mockRefreshRepo := new(MockRefreshTokenRepository)
+		mockRefreshRepo.On("Create", ctx, mock.AnythingOfType("*entity.RefreshToken")).Return(nil)
+		lockout := newAllowingLockoutService()
+		auth := NewAuthService(cfg, newTestKeyRing(), mockUserRepo, mockRefreshRepo, new(MockTokenRevocationRepository), newTestSessionRepository(), newDisabledMFAService(), lockout)
+		service := NewMagicLinkService(cfg, mockUserRepo, mockTokenRepo, mailer.NewLogMailer("no-reply@example.com"), NewMailQueue(cfg), auth, lockout)
+		return service, mockUserRepo, mockTokenRepo, lockout
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		service, mockUserRepo, mockTokenRepo, _ := newService()
+		user, _ := entity.NewUser("magic@example.com", "password123", "Magic User")
+		record := entity.NewOneTimeToken(user.ID, entity.TokenPurposeMagicLink, hashOpaqueToken("raw-token"), time.Hour)
+
+		mockTokenRepo.On("FindByHash", ctx, entity.TokenPurposeMagicLink, record.TokenHash).Return(record, nil)
+		mockTokenRepo.On("MarkUsed", ctx, record.ID).Return(nil)
+		mockUserRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+		mockUserRepo.On("Update", ctx, user).Return(nil)
+
+		result, err := service.Verify(ctx, "raw-token", ClientInfo{})
+
+		require.NoError(t, err)
+		require.NotNil(t, result.Tokens)
+		assert.True(t, user.EmailVerified)
+
+		claims := jwt.MapClaims{}
+		_, _, err = jwt.NewParser().ParseUnverified(result.Tokens.AccessToken, claims)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{AuthMethodMagicLink}, claims["amr"])
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("UnknownToken", func(t *testing.T) {
+		service, _, mockTokenRepo, lockout := newService()
+		mockTokenRepo.On("FindByHash", ctx, entity.TokenPurposeMagicLink, hashOpaqueToken("guessed")).Return(nil, errors.ErrInvalidToken)
+
+		result, err := service.Verify(ctx, "guessed", ClientInfo{IP: "203.0.113.9"})
+
+		assert.Equal(t, errors.ErrInvalidToken, err)
+		assert.Nil(t, result)
+		lockout.AssertCalled(t, "RecordFailure", ctx, (*entity.User)(nil), ClientInfo{IP: "203.0.113.9"})
+	})
+
+	t.Run("AlreadyUsed", func(t *testing.T) {
+		service, _, mockTokenRepo, _ := newService()
+		record := entity.NewOneTimeToken(uuid.New(), entity.TokenPurposeMagicLink, hashOpaqueToken("used-token"), time.Hour)
+		usedAt := time.Now()
+		record.UsedAt = &usedAt
+
+		mockTokenRepo.On("FindByHash", ctx, entity.TokenPurposeMagicLink, record.TokenHash).Return(record, nil)
+
+		result, err := service.Verify(ctx, "used-token", ClientInfo{})
+
+		assert.Equal(t, errors.ErrInvalidToken, err)
+		assert.Nil(t, result)
+		mockTokenRepo.AssertNotCalled(t, "MarkUsed", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		service, _, mockTokenRepo, _ := newService()
+		record := entity.NewOneTimeToken(uuid.New(), entity.TokenPurposeMagicLink, hashOpaqueToken("old-token"), -time.Minute)
+
+		mockTokenRepo.On("FindByHash", ctx, entity.TokenPurposeMagicLink, record.TokenHash).Return(record, nil)
+
+		result, err := service.Verify(ctx, "old-token", ClientInfo{})
+
+		assert.Equal(t, errors.ErrTokenExpired, err)
+		assert.Nil(t, result)
+	})
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+)
+
+// MailQueue runs the work behind emails that are requested anonymously, such
+// as looking up the account and sending the message, after the request has
+// been answered. A fixed number of workers take jobs from a bounded queue, so
+// a flood of requests can't start unbounded sends.
+type MailQueue struct {
+	jobs   chan func()
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewMailQueue(cfg *config.Config) *MailQueue {
+	q := &MailQueue{
+		jobs: make(chan func(), max(cfg.Mail.QueueSize, 0)),
+	}
+
+	for i := 0; i < max(cfg.Mail.Workers, 1); i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue hands the job to a worker. It reports false, dropping the job, when
+// the queue is full or shut down.
+func (q *MailQueue) Enqueue(job func()) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return false
+	}
+
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown stops accepting jobs and waits until the queued ones are done or
+// ctx ends
+func (q *MailQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *MailQueue) work() {
+	defer q.wg.Done()
+
+	for job := range q.jobs {
+		job()
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailQueue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Drains On Shutdown", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Mail.Workers = 2
+		cfg.Mail.QueueSize = 10
+		queue := NewMailQueue(cfg)
+
+		var done atomic.Int32
+		for i := 0; i < 10; i++ {
+			require.True(t, queue.Enqueue(func() {
+				time.Sleep(10 * time.Millisecond)
+				done.Add(1)
+			}))
+		}
+
+		require.NoError(t, queue.Shutdown(ctx))
+		assert.Equal(t, int32(10), done.Load())
+		assert.False(t, queue.Enqueue(func() {}))
+	})
+
+	t.Run("Drops When Full", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Mail.Workers = 1
+		cfg.Mail.QueueSize = 1
+		queue := NewMailQueue(cfg)
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		require.True(t, queue.Enqueue(func() {
+			close(started)
+			<-release
+		}))
+		<-started
+		require.True(t, queue.Enqueue(func() {}))
+
+		assert.False(t, queue.Enqueue(func() {}))
+		close(release)
+		require.NoError(t, queue.Shutdown(ctx))
+	})
+
+	t.Run("Shutdown Deadline", func(t *testing.T) {
+		queue := NewMailQueue(newTestConfig())
+		release := make(chan struct{})
+		defer close(release)
+		require.True(t, queue.Enqueue(func() { <-release }))
+
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, queue.Shutdown(timeout), context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/security"
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes issued on enrolment
+	recoveryCodeCount = 10
+	// totpSkew is the number of 30 second steps of clock drift tolerated
+	totpSkew = 1
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment carries the secrets shown to the user exactly once
+type TOTPEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+	RecoveryCodes   []string
+}
+
+type MFAService interface {
+	EnrollTOTP(ctx context.Context, userID uuid.UUID) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) error
+	DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error
+	IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error)
+	// Verify accepts either a current TOTP code or an unused recovery code
+	Verify(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+type mfaService struct {
+	config   *config.Config
+	userRepo repository.UserRepository
+	mfaRepo  repository.MFARepository
+}
+
+func NewMFAService(cfg *config.Config, userRepo repository.UserRepository, mfaRepo repository.MFARepository) MFAService {
+	return &mfaService{
+		config:   cfg,
+		userRepo: userRepo,
+		mfaRepo:  mfaRepo,
+	}
+}
+
+func (s *mfaService) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*TOTPEnrollment, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// An unconfirmed enrolment may be restarted, a confirmed one must be disabled first
+	factor, err := s.mfaRepo.FindTOTPByUserID(ctx, userID)
+	switch {
+	case err == nil && factor.IsConfirmed():
+		return nil, errors.ErrMFAAlreadyEnabled
+	case err == nil:
+	case err == errors.ErrMFANotEnrolled:
+		factor = entity.NewTOTPFactor(userID, "")
+	default:
+		return nil, err
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	factor.Secret = secret
+	factor.UpdatedAt = time.Now()
+
+	if err := s.mfaRepo.SaveTOTP(ctx, factor); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.regenerateRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: security.TOTPProvisioningURI(s.config.MFA.Issuer, user.Email, secret),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+func (s *mfaService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	factor, err := s.mfaRepo.FindTOTPByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if factor.IsConfirmed() {
+		return errors.ErrMFAAlreadyEnabled
+	}
+
+	step, ok := security.ValidateTOTP(factor.Secret, normalizeMFACode(code), time.Now(), totpSkew)
+	if !ok {
+		return errors.ErrInvalidMFACode
+	}
+
+	factor.Confirm(step)
+	return s.mfaRepo.SaveTOTP(ctx, factor)
+}
+
+func (s *mfaService) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.mfaRepo.DeleteByUserID(ctx, userID)
+}
+
+func (s *mfaService) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	factor, err := s.mfaRepo.FindTOTPByUserID(ctx, userID)
+	if err != nil {
+		if err == errors.ErrMFANotEnrolled {
+			return false, nil
+		}
+		return false, err
+	}
+	return factor.IsConfirmed(), nil
+}
+
+func (s *mfaService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	factor, err := s.mfaRepo.FindTOTPByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !factor.IsConfirmed() {
+		return errors.ErrMFANotEnrolled
+	}
+
+	code = normalizeMFACode(code)
+	if step, ok := security.ValidateTOTP(factor.Secret, code, time.Now(), totpSkew); ok {
+		// Each code is accepted once, even within its validity window
+		return s.mfaRepo.UseTOTPStep(ctx, factor.ID, step)
+	}
+
+	return s.mfaRepo.UseRecoveryCode(ctx, userID, hashOpaqueToken(code))
+}
+
+func (s *mfaService) regenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]*entity.RecoveryCode, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+
+		codes[i] = raw[:4] + "-" + raw[4:]
+		records[i] = entity.NewRecoveryCode(userID, hashOpaqueToken(raw))
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeMFACode strips the separators users tend to type along with a code
+func normalizeMFACode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
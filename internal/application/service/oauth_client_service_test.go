@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockOAuthClientRepository is a mock implementation of repository.OAuthClientRepository
+type MockOAuthClientRepository struct {
+	mock.Mock
+}
+
+func (m *MockOAuthClientRepository) Create(ctx context.Context, client *entity.OAuthClient) error {
+	args := m.Called(ctx, client)
+	return args.Error(0)
+}
+
+func (m *MockOAuthClientRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.OAuthClient, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.OAuthClient), args.Error(1)
+}
+
+func (m *MockOAuthClientRepository) List(ctx context.Context) ([]*entity.OAuthClient, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*entity.OAuthClient), args.Error(1)
+}
+
+func (m *MockOAuthClientRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+type oauthClientTestEnv struct {
+	service        OAuthClientService
+	authService    AuthService
+	clientRepo     *MockOAuthClientRepository
+	refreshRepo    *MockRefreshTokenRepository
+	revocationRepo *MockTokenRevocationRepository
+}
+
+func newOAuthClientTestEnv() *oauthClientTestEnv {
+	cfg := newTestConfig()
+	cfg.JWT.ClientTokenDuration = time.Hour
+
+	e := &oauthClientTestEnv{
+		clientRepo:     new(MockOAuthClientRepository),
+		refreshRepo:    new(MockRefreshTokenRepository),
+		revocationRepo: new(MockTokenRevocationRepository),
+	}
+	e.authService = NewAuthService(cfg, newTestKeyRing(), new(MockUserRepository), e.refreshRepo, e.revocationRepo, newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+	e.service = NewOAuthClientService(e.clientRepo, e.authService)
+	return e
+}
+
+func TestOAuthClientService_Create(t *testing.T) {
+	ctx := context.Background()
+	adminID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		e := newOAuthClientTestEnv()
+		e.clientRepo.On("Create", ctx, mock.AnythingOfType("*entity.OAuthClient")).Return(nil)
+
+		created, err := e.service.Create(ctx, "billing", []string{entity.PermissionUsersRead}, adminID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, created.Secret)
+		assert.Equal(t, hashOpaqueToken(created.Secret), created.Client.SecretHash)
+		assert.Equal(t, []string{entity.PermissionUsersRead}, created.Client.ScopeList())
+		assert.Equal(t, adminID, created.Client.CreatedBy)
+	})
+
+	t.Run("Unknown Scope", func(t *testing.T) {
+		e := newOAuthClientTestEnv()
+
+		_, err := e.service.Create(ctx, "billing", []string{"users:everything"}, adminID)
+		assert.Equal(t, errors.ErrInvalidScope, err)
+		e.clientRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("No Scopes", func(t *testing.T) {
+		e := newOAuthClientTestEnv()
+
+		_, err := e.service.Create(ctx, "billing", nil, adminID)
+		assert.Equal(t, errors.ErrInvalidScope, err)
+	})
+}
+
+func TestOAuthClientService_ClientCredentials(t *testing.T) {
+	ctx := context.Background()
+	client := entity.NewOAuthClient("billing", hashOpaqueToken("s3cret"), []string{entity.PermissionUsersRead, entity.PermissionUsersLogout}, uuid.New())
+
+	t.Run("All Allowed Scopes", func(t *testing.T) {
+		e := newOAuthClientTestEnv()
+		e.clientRepo.On("FindByID", ctx, client.ID).Return(client, nil)
+
+		tokens, err := e.service.ClientCredentials(ctx, client.ID.String(), "s3cret", nil)
+		require.NoError(t, err)
+		assert.Empty(t, tokens.RefreshToken)
+		assert.Equal(t, time.Hour, tokens.ExpiresIn)
+		assert.Equal(t, client.ScopeList(), tokens.Scopes)
+
+		e.revocationRepo.On("IsTokenRevoked", ctx, mock.AnythingOfType("string")).Return(false, nil)
+		e.revocationRepo.On("UserTokensRevokedBefore", ctx, client.ID).Return(time.Time{}, nil)
+		token, err := e.authService.ValidateToken(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+
+		claims := token.Claims.(jwt.MapClaims)
+		clientID, ok := ClaimClientID(claims)
+		assert.True(t, ok)
+		assert.Equal(t, client.ID, clientID)
+		assert.Equal(t, "users:read users:logout", claims["scope"])
+		assert.NotContains(t, claims, "roles")
+	})
+
+	t.Run("Requested Subset", func(t *testing.T) {
+		e := newOAuthClientTestEnv()
+		e.clientRepo.On("FindByID", ctx, client.ID).Return(client, nil)
+
+		tokens, err := e.service.ClientCredentials(ctx, client.ID.String(), "s3cret", []string{entity.PermissionUsersRead})
+		require.NoError(t, err)
+		assert.Equal(t, []string{entity.PermissionUsersRead}, tokens.Scopes)
+	})
+
+	t.Run("Scope Not Allowed", func(t *testing.T) {
+		e := newOAuthClientTestEnv()
+		e.clientRepo.On("FindByID", ctx, client.ID).Return(client, nil)
+
+		_, err := e.service.ClientCredentials(ctx, client.ID.String(), "s3cret", []string{entity.PermissionUsersDelete})
+		assert.Equal(t, errors.ErrInvalidScope, err)
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		e := newOAuthClientTestEnv()
+		e.clientRepo.On("FindByID", ctx, client.ID).Return(client, nil)
+
+		_, err := e.service.ClientCredentials(ctx, client.ID.String(), "wrong", nil)
+		assert.Equal(t, errors.ErrInvalidClient, err)
+	})
+
+	t.Run("Unknown Client", func(t *testing.T) {
+		e := newOAuthClientTestEnv()
+		id := uuid.New()
+		e.clientRepo.On("FindByID", ctx, id).Return(nil, errors.ErrClientNotFound)
+
+		_, err := e.service.ClientCredentials(ctx, id.String(), "s3cret", nil)
+		assert.Equal(t, errors.ErrInvalidClient, err)
+
+		_, err = e.service.ClientCredentials(ctx, "not-a-client-id", "s3cret", nil)
+		assert.Equal(t, errors.ErrInvalidClient, err)
+	})
+}
+
+func TestOAuthClientService_Delete(t *testing.T) {
+	ctx := context.Background()
+	e := newOAuthClientTestEnv()
+	id := uuid.New()
+	e.clientRepo.On("Delete", ctx, id).Return(nil)
+	e.refreshRepo.On("RevokeAllForUser", ctx, id).Return(nil)
+	e.revocationRepo.On("RevokeUserTokens", ctx, id, mock.AnythingOfType("time.Time")).Return(nil)
+
+	assert.NoError(t, e.service.Delete(ctx, id))
+	e.revocationRepo.AssertExpectations(t)
+}
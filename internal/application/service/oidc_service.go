@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/cache"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/oidc"
+	"github.com/rs/zerolog/log"
+)
+
+// oidcState is kept server-side between the redirect to the provider and the
+// callback. The state parameter is its cache key.
+type oidcState struct {
+	Nonce        string
+	CodeVerifier string
+}
+
+type OIDCService interface {
+	// Begin starts an authorization code flow with PKCE and returns the URL
+	// of the provider's login page
+	Begin(ctx context.Context) (string, error)
+	// Complete redeems the code delivered to the callback, links the external
+	// identity to a local user and logs that user in
+	Complete(ctx context.Context, state, code string, client ClientInfo) (*LoginResult, error)
+}
+
+type oidcService struct {
+	config              *config.Config
+	provider            *oidc.Provider
+	cache               cache.Cache
+	userRepo            repository.UserRepository
+	identityRepo        repository.UserIdentityRepository
+	verificationService EmailVerificationService
+	authService         AuthService
+}
+
+func NewOIDCService(
+	cfg *config.Config,
+	provider *oidc.Provider,
+	c cache.Cache,
+	userRepo repository.UserRepository,
+	identityRepo repository.UserIdentityRepository,
+	verificationService EmailVerificationService,
+	authService AuthService,
+) OIDCService {
+	return &oidcService{
+		config:              cfg,
+		provider:            provider,
+		cache:               c,
+		userRepo:            userRepo,
+		identityRepo:        identityRepo,
+		verificationService: verificationService,
+		authService:         authService,
+	}
+}
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+func (s *oidcService) Begin(ctx context.Context) (string, error) {
+	if !s.config.OIDC.Enabled {
+		return "", errors.ErrExternalLoginDisabled
+	}
+
+	state, err := generateOpaqueToken(oneTimeTokenSize)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := generateOpaqueToken(oneTimeTokenSize)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := s.provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		log.Error().Err(err).Str("issuer", s.provider.Issuer()).Msg("Failed to start OIDC login")
+		return "", errors.ErrExternalLoginFailed
+	}
+
+	s.cache.Set(oidcStateKey(state), &oidcState{Nonce: nonce, CodeVerifier: verifier}, s.config.OIDC.StateTTL)
+	return authURL, nil
+}
+
+func (s *oidcService) Complete(ctx context.Context, state, code string, client ClientInfo) (*LoginResult, error) {
+	if !s.config.OIDC.Enabled {
+		return nil, errors.ErrExternalLoginDisabled
+	}
+
+	// Each state is good for one callback
+	cached, ok := s.cache.Get(oidcStateKey(state))
+	if !ok {
+		return nil, errors.ErrInvalidToken
+	}
+	s.cache.Delete(oidcStateKey(state))
+	flow := cached.(*oidcState)
+
+	token, err := s.provider.Exchange(ctx, code, flow.CodeVerifier)
+	if err != nil {
+		log.Warn().Err(err).Str("issuer", s.provider.Issuer()).Msg("OIDC code exchange failed")
+		return nil, errors.ErrExternalLoginFailed
+	}
+
+	idToken, err := s.provider.VerifyIDToken(ctx, token.IDToken, flow.Nonce)
+	if err != nil {
+		log.Warn().Err(err).Str("issuer", s.provider.Issuer()).Msg("OIDC ID token rejected")
+		return nil, errors.ErrExternalLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.AuthenticateUser(ctx, user, []string{AuthMethodFederated}, client)
+}
+
+// resolveUser finds the user linked to the identity. Unlinked identities are
+// attached to the account with the same email only if the provider verified
+// that email; otherwise anyone could register the address at their provider
+// and take over the account.
+func (s *oidcService) resolveUser(ctx context.Context, idToken *oidc.IDToken) (*entity.User, error) {
+	identity, err := s.identityRepo.FindBySubject(ctx, idToken.Issuer, idToken.Subject)
+	if err == nil {
+		user, err := s.userRepo.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		identity.RecordLogin(idToken.Email)
+		if err := s.identityRepo.Update(ctx, identity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if err != errors.ErrNotFound {
+		return nil, err
+	}
+
+	if idToken.Email == "" {
+		return nil, errors.ErrExternalLoginFailed
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, idToken.Email)
+	switch {
+	case err == nil:
+		if !idToken.EmailVerified {
+			return nil, errors.ErrUserAlreadyExists
+		}
+	case err == errors.ErrUserNotFound:
+		// Invite-only mode applies to external logins as well
+		if !s.config.OIDC.AutoCreateUsers || s.config.Auth.InviteOnly {
+			return nil, errors.ErrExternalLoginFailed
+		}
+		if user, err = s.createUser(ctx, idToken); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	identity = entity.NewUserIdentity(user.ID, idToken.Issuer, idToken.Subject, idToken.Email)
+	identity.RecordLogin(idToken.Email)
+	if err := s.identityRepo.Create(ctx, identity); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("user_id", user.ID.String()).
+		Str("issuer", idToken.Issuer).
+		Msg("Linked external identity")
+	return user, nil
+}
+
+// createUser registers a user for a first-time external login. The random
+// password is never disclosed; the user can set one through a password reset.
+func (s *oidcService) createUser(ctx context.Context, idToken *oidc.IDToken) (*entity.User, error) {
+	password, err := generateOpaqueToken(oneTimeTokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	name := idToken.Name
+	if name == "" {
+		name = idToken.Email
+	}
+
+	user, err := entity.NewUser(idToken.Email, password, name)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.EmailVerified {
+		user.MarkEmailVerified()
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if !user.EmailVerified {
+		if err := s.verificationService.SendVerification(ctx, user); err != nil {
+			log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to issue email verification token")
+		}
+	}
+
+	return user, nil
+}
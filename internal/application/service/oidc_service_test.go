@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/cache"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/oidc"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockUserIdentityRepository is a mock implementation of repository.UserIdentityRepository
+type MockUserIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserIdentityRepository) Create(ctx context.Context, identity *entity.UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockUserIdentityRepository) Update(ctx context.Context, identity *entity.UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockUserIdentityRepository) FindBySubject(ctx context.Context, issuer, subject string) (*entity.UserIdentity, error) {
+	args := m.Called(ctx, issuer, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.UserIdentity), args.Error(1)
+}
+
+type oidcTestEnv struct {
+	idp          *oidctest.Provider
+	service      OIDCService
+	userRepo     *MockUserRepository
+	identityRepo *MockUserIdentityRepository
+}
+
+func newOIDCTestEnv(t *testing.T) *oidcTestEnv {
+	idp := oidctest.NewProvider("client", "secret")
+	t.Cleanup(idp.Close)
+
+	cfg := newTestConfig()
+	cfg.OIDC = config.OIDCConfig{
+		Enabled:         true,
+		StateTTL:        10 * time.Minute,
+		AutoCreateUsers: true,
+	}
+
+	userRepo := new(MockUserRepository)
+	identityRepo := new(MockUserIdentityRepository)
+	refreshRepo := new(MockRefreshTokenRepository)
+	refreshRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.RefreshToken")).Return(nil)
+
+	auth := NewAuthService(cfg, newTestKeyRing(), userRepo, refreshRepo, new(MockTokenRevocationRepository), newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+	provider := oidc.New(idp.Config("http://localhost/callback"), http.DefaultClient)
+	service := NewOIDCService(cfg, provider, cache.NewMemoryCache(cfg), userRepo, identityRepo, new(MockEmailVerificationService), auth)
+
+	return &oidcTestEnv{idp: idp, service: service, userRepo: userRepo, identityRepo: identityRepo}
+}
+
+// login runs the browser part of the flow and returns the callback parameters
+func (e *oidcTestEnv) login(t *testing.T, user oidctest.User) (state, code string) {
+	e.idp.SetUser(user)
+
+	authURL, err := e.service.Begin(context.Background())
+	require.NoError(t, err)
+
+	code, state, err = e.idp.Authorize(authURL)
+	require.NoError(t, err)
+	return state, code
+}
+
+func TestOIDCService_Complete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CreatesUserOnFirstLogin", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		state, code := env.login(t, oidctest.User{Subject: "alice", Email: "alice@example.com", EmailVerified: true, Name: "Alice"})
+
+		env.identityRepo.On("FindBySubject", ctx, env.idp.Issuer(), "alice").Return(nil, errors.ErrNotFound)
+		env.userRepo.On("FindByEmail", ctx, "alice@example.com").Return(nil, errors.ErrUserNotFound)
+		env.userRepo.On("Create", ctx, mock.AnythingOfType("*entity.User")).Return(nil)
+		env.identityRepo.On("Create", ctx, mock.AnythingOfType("*entity.UserIdentity")).Return(nil)
+
+		result, err := env.service.Complete(ctx, state, code, ClientInfo{})
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, result.Tokens.AccessToken)
+
+		created := env.userRepo.Calls[1].Arguments.Get(1).(*entity.User)
+		assert.Equal(t, "Alice", created.Name)
+		assert.True(t, created.EmailVerified)
+
+		identity := env.identityRepo.Calls[1].Arguments.Get(1).(*entity.UserIdentity)
+		assert.Equal(t, created.ID, identity.UserID)
+		assert.Equal(t, "alice", identity.Subject)
+	})
+
+	t.Run("ReturningIdentity", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		state, code := env.login(t, oidctest.User{Subject: "bob", Email: "bob@example.com", EmailVerified: true})
+
+		user, _ := entity.NewUser("bob@example.com", "password123", "Bob")
+		identity := entity.NewUserIdentity(user.ID, env.idp.Issuer(), "bob", "bob@example.com")
+		env.identityRepo.On("FindBySubject", ctx, env.idp.Issuer(), "bob").Return(identity, nil)
+		env.identityRepo.On("Update", ctx, identity).Return(nil)
+		env.userRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+
+		result, err := env.service.Complete(ctx, state, code, ClientInfo{})
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, result.Tokens.AccessToken)
+		assert.NotNil(t, identity.LastLoginAt)
+		env.userRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("UnverifiedEmailOfExistingUser", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		state, code := env.login(t, oidctest.User{Subject: "mallory", Email: "victim@example.com", EmailVerified: false})
+
+		victim, _ := entity.NewUser("victim@example.com", "password123", "Victim")
+		env.identityRepo.On("FindBySubject", ctx, env.idp.Issuer(), "mallory").Return(nil, errors.ErrNotFound)
+		env.userRepo.On("FindByEmail", ctx, "victim@example.com").Return(victim, nil)
+
+		result, err := env.service.Complete(ctx, state, code, ClientInfo{})
+
+		assert.Equal(t, errors.ErrUserAlreadyExists, err)
+		assert.Nil(t, result)
+		env.identityRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("StateIsSingleUse", func(t *testing.T) {
+		env := newOIDCTestEnv(t)
+		state, code := env.login(t, oidctest.User{Subject: "carol", Email: "carol@example.com", EmailVerified: true})
+
+		user, _ := entity.NewUser("carol@example.com", "password123", "Carol")
+		identity := entity.NewUserIdentity(user.ID, env.idp.Issuer(), "carol", "carol@example.com")
+		env.identityRepo.On("FindBySubject", ctx, env.idp.Issuer(), "carol").Return(identity, nil)
+		env.identityRepo.On("Update", ctx, identity).Return(nil)
+		env.userRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+
+		_, err := env.service.Complete(ctx, state, code, ClientInfo{})
+		require.NoError(t, err)
+
+		_, err = env.service.Complete(ctx, state, code, ClientInfo{})
+		assert.Equal(t, errors.ErrInvalidToken, err)
+	})
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+)
+
+type OrganizationService interface {
+	// Create stores a new organization owned by the user. It becomes the
+	// user's active organization if they have none.
+	Create(ctx context.Context, userID uuid.UUID, name string) (*entity.Organization, error)
+	// ListForUser returns the user's memberships with their organizations
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]*entity.Membership, error)
+	// Switch makes the organization the user's active one and exchanges the
+	// refresh token for a token pair scoped to it
+	Switch(ctx context.Context, userID, organizationID uuid.UUID, refreshToken string) (*TokenPair, error)
+	ListMembers(ctx context.Context, userID, organizationID uuid.UUID) ([]*entity.Membership, error)
+	// InviteMember invites the address to join the organization with the
+	// role; it takes an owner or admin, and only owners invite owners. The
+	// outcome does not reveal whether the address has an account.
+	InviteMember(ctx context.Context, actorID, organizationID uuid.UUID, email, role string) error
+	// RemoveMember removes a user from the organization. Members may always
+	// leave; removing others takes an owner or admin. Tokens scoped to the
+	// organization stop working as the membership is checked on every
+	// request.
+	RemoveMember(ctx context.Context, actorID, organizationID, userID uuid.UUID) error
+	// CheckMembership returns errors.ErrNotMember unless the user belongs to
+	// the organization
+	CheckMembership(ctx context.Context, organizationID, userID uuid.UUID) error
+}
+
+type organizationService struct {
+	orgRepo           repository.OrganizationRepository
+	userRepo          repository.UserRepository
+	authService       AuthService
+	invitationService InvitationService
+}
+
+func NewOrganizationService(
+	orgRepo repository.OrganizationRepository,
+	userRepo repository.UserRepository,
+	authService AuthService,
+	invitationService InvitationService,
+) OrganizationService {
+	return &organizationService{
+		orgRepo:           orgRepo,
+		userRepo:          userRepo,
+		authService:       authService,
+		invitationService: invitationService,
+	}
+}
+
+func (s *organizationService) Create(ctx context.Context, userID uuid.UUID, name string) (*entity.Organization, error) {
+	// The user is looked up by id, so the lookup needs no tenant scope
+	ctx = tenant.Unscoped(ctx)
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	organization := entity.NewOrganization(name)
+	if err := s.orgRepo.Create(ctx, organization, entity.NewMembership(organization.ID, userID, entity.OrgRoleOwner)); err != nil {
+		return nil, err
+	}
+
+	if user.ActiveOrganizationID == nil {
+		user.SetActiveOrganization(&organization.ID)
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return organization, nil
+}
+
+func (s *organizationService) ListForUser(ctx context.Context, userID uuid.UUID) ([]*entity.Membership, error) {
+	return s.orgRepo.ListByUser(ctx, userID)
+}
+
+func (s *organizationService) Switch(ctx context.Context, userID, organizationID uuid.UUID, refreshToken string) (*TokenPair, error) {
+	if _, err := s.membership(ctx, organizationID, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(tenant.Unscoped(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.SetActiveOrganization(&organizationID)
+	if err := s.userRepo.Update(tenant.Unscoped(ctx), user); err != nil {
+		return nil, err
+	}
+
+	// Refreshing reads the active organization from the user
+	return s.authService.RefreshToken(ctx, refreshToken)
+}
+
+func (s *organizationService) ListMembers(ctx context.Context, userID, organizationID uuid.UUID) ([]*entity.Membership, error) {
+	if _, err := s.membership(ctx, organizationID, userID); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.ListMembers(ctx, organizationID)
+}
+
+func (s *organizationService) InviteMember(ctx context.Context, actorID, organizationID uuid.UUID, email, role string) error {
+	if !entity.IsValidOrgRole(role) {
+		return errors.ErrInvalidRole
+	}
+
+	actor, err := s.membership(ctx, organizationID, actorID)
+	if err != nil {
+		return err
+	}
+	if !actor.CanManage() || (role == entity.OrgRoleOwner && actor.Role != entity.OrgRoleOwner) {
+		return errors.ErrForbidden
+	}
+
+	// Accounts join by accepting, so the address is not looked up here
+	_, err = s.invitationService.InviteMember(ctx, actorID, organizationID, email, role)
+	return err
+}
+
+func (s *organizationService) RemoveMember(ctx context.Context, actorID, organizationID, userID uuid.UUID) error {
+	actor, err := s.membership(ctx, organizationID, actorID)
+	if err != nil {
+		return err
+	}
+
+	target := actor
+	if userID != actorID {
+		if !actor.CanManage() {
+			return errors.ErrForbidden
+		}
+		if target, err = s.orgRepo.FindMembership(ctx, organizationID, userID); err != nil {
+			return err
+		}
+		if target.Role == entity.OrgRoleOwner && actor.Role != entity.OrgRoleOwner {
+			return errors.ErrForbidden
+		}
+	}
+
+	if target.Role == entity.OrgRoleOwner {
+		owners, err := s.orgRepo.CountByRole(ctx, organizationID, entity.OrgRoleOwner)
+		if err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return errors.ErrLastOwner
+		}
+	}
+
+	if err := s.orgRepo.RemoveMember(ctx, organizationID, userID); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByID(tenant.Unscoped(ctx), userID)
+	if err != nil {
+		return err
+	}
+	if user.ActiveOrganizationID == nil || *user.ActiveOrganizationID != organizationID {
+		return nil
+	}
+
+	// Issued tokens still name the organization, so they must go
+	user.SetActiveOrganization(nil)
+	if err := s.userRepo.Update(tenant.Unscoped(ctx), user); err != nil {
+		return err
+	}
+	return s.authService.LogoutAll(ctx, userID)
+}
+
+func (s *organizationService) CheckMembership(ctx context.Context, organizationID, userID uuid.UUID) error {
+	_, err := s.orgRepo.FindMembership(ctx, organizationID, userID)
+	return err
+}
+
+// membership returns the user's membership, hiding organizations the user
+// does not belong to
+func (s *organizationService) membership(ctx context.Context, organizationID, userID uuid.UUID) (*entity.Membership, error) {
+	membership, err := s.orgRepo.FindMembership(ctx, organizationID, userID)
+	if err == errors.ErrNotMember {
+		return nil, errors.ErrOrganizationNotFound
+	}
+	return membership, err
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockOrganizationRepository is a mock implementation of repository.OrganizationRepository
+type MockOrganizationRepository struct {
+	mock.Mock
+}
+
+func (m *MockOrganizationRepository) Create(ctx context.Context, organization *entity.Organization, owner *entity.Membership) error {
+	args := m.Called(ctx, organization, owner)
+	return args.Error(0)
+}
+
+func (m *MockOrganizationRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.Organization, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Organization), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) AddMember(ctx context.Context, membership *entity.Membership) error {
+	args := m.Called(ctx, membership)
+	return args.Error(0)
+}
+
+func (m *MockOrganizationRepository) RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error {
+	args := m.Called(ctx, organizationID, userID)
+	return args.Error(0)
+}
+
+func (m *MockOrganizationRepository) FindMembership(ctx context.Context, organizationID, userID uuid.UUID) (*entity.Membership, error) {
+	args := m.Called(ctx, organizationID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Membership), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*entity.Membership, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*entity.Membership), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) ListMembers(ctx context.Context, organizationID uuid.UUID) ([]*entity.Membership, error) {
+	args := m.Called(ctx, organizationID)
+	return args.Get(0).([]*entity.Membership), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) CountByRole(ctx context.Context, organizationID uuid.UUID, role string) (int64, error) {
+	args := m.Called(ctx, organizationID, role)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockAuthService is a mock implementation of AuthService
+type MockAuthService struct {
+	mock.Mock
+}
+
+func (m *MockAuthService) Login(ctx context.Context, email, password string, client ClientInfo) (*LoginResult, error) {
+	args := m.Called(ctx, email, password, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*LoginResult), args.Error(1)
+}
+
+func (m *MockAuthService) VerifyMFA(ctx context.Context, mfaToken, code string, client ClientInfo) (*TokenPair, error) {
+	args := m.Called(ctx, mfaToken, code, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TokenPair), args.Error(1)
+}
+
+func (m *MockAuthService) AuthenticateUser(ctx context.Context, user *entity.User, authMethods []string, client ClientInfo) (*LoginResult, error) {
+	args := m.Called(ctx, user, authMethods, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*LoginResult), args.Error(1)
+}
+
+func (m *MockAuthService) ValidateToken(ctx context.Context, token string) (*jwt.Token, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*jwt.Token), args.Error(1)
+}
+
+func (m *MockAuthService) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	args := m.Called(ctx, refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TokenPair), args.Error(1)
+}
+
+func (m *MockAuthService) Logout(ctx context.Context, claims jwt.MapClaims) error {
+	args := m.Called(ctx, claims)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) Impersonate(ctx context.Context, actor jwt.MapClaims, target *entity.User) (*TokenPair, error) {
+	args := m.Called(ctx, actor, target)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TokenPair), args.Error(1)
+}
+
+func (m *MockAuthService) IssueClientToken(ctx context.Context, client *entity.OAuthClient, scopes []string) (*TokenPair, error) {
+	args := m.Called(ctx, client, scopes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TokenPair), args.Error(1)
+}
+
+func (m *MockAuthService) Downscope(ctx context.Context, claims jwt.MapClaims, scopes []string) (*TokenPair, error) {
+	args := m.Called(ctx, claims, scopes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TokenPair), args.Error(1)
+}
+
+func (m *MockAuthService) JWKS() security.JWKS {
+	args := m.Called()
+	return args.Get(0).(security.JWKS)
+}
+
+func TestOrganizationService_Create(t *testing.T) {
+	ctx := context.Background()
+	orgRepo := new(MockOrganizationRepository)
+	userRepo := new(MockUserRepository)
+	service := NewOrganizationService(orgRepo, userRepo, new(MockAuthService), nil)
+
+	user, _ := entity.NewUser("owner@example.com", "password123", "Owner")
+	userRepo.On("FindByID", tenant.Unscoped(ctx), user.ID).Return(user, nil)
+	orgRepo.On("Create", tenant.Unscoped(ctx), mock.AnythingOfType("*entity.Organization"), mock.AnythingOfType("*entity.Membership")).Return(nil)
+	userRepo.On("Update", tenant.Unscoped(ctx), user).Return(nil)
+
+	organization, err := service.Create(ctx, user.ID, "Acme")
+
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", organization.Name)
+	owner := orgRepo.Calls[0].Arguments.Get(2).(*entity.Membership)
+	assert.Equal(t, user.ID, owner.UserID)
+	assert.Equal(t, entity.OrgRoleOwner, owner.Role)
+	require.NotNil(t, user.ActiveOrganizationID)
+	assert.Equal(t, organization.ID, *user.ActiveOrganizationID)
+}
+
+func TestOrganizationService_Switch(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		orgRepo := new(MockOrganizationRepository)
+		userRepo := new(MockUserRepository)
+		authService := new(MockAuthService)
+		service := NewOrganizationService(orgRepo, userRepo, authService, nil)
+
+		user, _ := entity.NewUser("member@example.com", "password123", "Member")
+		tokens := &TokenPair{AccessToken: "access", RefreshToken: "refresh"}
+		orgRepo.On("FindMembership", ctx, orgID, user.ID).Return(entity.NewMembership(orgID, user.ID, entity.OrgRoleMember), nil)
+		userRepo.On("FindByID", tenant.Unscoped(ctx), user.ID).Return(user, nil)
+		userRepo.On("Update", tenant.Unscoped(ctx), user).Return(nil)
+		authService.On("RefreshToken", ctx, "old-refresh").Return(tokens, nil)
+
+		result, err := service.Switch(ctx, user.ID, orgID, "old-refresh")
+
+		require.NoError(t, err)
+		assert.Equal(t, tokens, result)
+		assert.Equal(t, orgID, *user.ActiveOrganizationID)
+	})
+
+	t.Run("NotMember", func(t *testing.T) {
+		orgRepo := new(MockOrganizationRepository)
+		service := NewOrganizationService(orgRepo, new(MockUserRepository), new(MockAuthService), nil)
+		userID := uuid.New()
+		orgRepo.On("FindMembership", ctx, orgID, userID).Return(nil, errors.ErrNotMember)
+
+		_, err := service.Switch(ctx, userID, orgID, "old-refresh")
+
+		assert.Equal(t, errors.ErrOrganizationNotFound, err)
+	})
+}
+
+func TestOrganizationService_InviteMember(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	scoped := tenant.WithID(ctx, orgID)
+	actor, _ := entity.NewUser("owner@example.com", "password123", "Owner")
+
+	t.Run("Success", func(t *testing.T) {
+		e := newInvitationTestEnv(t)
+		service := NewOrganizationService(e.orgRepo, e.userRepo, new(MockAuthService), e.service)
+		e.orgRepo.On("FindMembership", ctx, orgID, actor.ID).Return(entity.NewMembership(orgID, actor.ID, entity.OrgRoleAdmin), nil)
+		e.userRepo.On("FindByID", scoped, actor.ID).Return(actor, nil)
+		e.invitationRepo.On("RevokePending", scoped, "new@example.com").Return(nil)
+		e.invitationRepo.On("Create", scoped, mock.MatchedBy(func(invitation *entity.Invitation) bool {
+			return invitation.Email == "new@example.com" && invitation.Role == entity.RoleUser &&
+				*invitation.OrganizationID == orgID && invitation.OrgRole == entity.OrgRoleAdmin
+		})).Return(nil).Once()
+
+		err := service.InviteMember(ctx, actor.ID, orgID, "new@example.com", entity.OrgRoleAdmin)
+
+		require.NoError(t, err)
+		assert.Len(t, readMail(t, e.mailDir), 1)
+		e.invitationRepo.AssertExpectations(t)
+		// Nobody is added before they accept, and the address is not looked up
+		e.orgRepo.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything)
+		e.userRepo.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			actorRole string
+			role      string
+		}{
+			{"MemberInvitesMember", entity.OrgRoleMember, entity.OrgRoleMember},
+			{"AdminInvitesOwner", entity.OrgRoleAdmin, entity.OrgRoleOwner},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				orgRepo := new(MockOrganizationRepository)
+				service := NewOrganizationService(orgRepo, new(MockUserRepository), new(MockAuthService), nil)
+				orgRepo.On("FindMembership", ctx, orgID, actor.ID).Return(entity.NewMembership(orgID, actor.ID, tt.actorRole), nil)
+
+				err := service.InviteMember(ctx, actor.ID, orgID, "new@example.com", tt.role)
+
+				assert.Equal(t, errors.ErrForbidden, err)
+			})
+		}
+	})
+
+	t.Run("InvalidRole", func(t *testing.T) {
+		service := NewOrganizationService(new(MockOrganizationRepository), new(MockUserRepository), new(MockAuthService), nil)
+
+		err := service.InviteMember(ctx, actor.ID, orgID, "new@example.com", "superuser")
+
+		assert.Equal(t, errors.ErrInvalidRole, err)
+	})
+}
+
+func TestOrganizationService_RemoveMember(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	actorID := uuid.New()
+
+	t.Run("RevokesTokensScopedToOrganization", func(t *testing.T) {
+		orgRepo := new(MockOrganizationRepository)
+		userRepo := new(MockUserRepository)
+		authService := new(MockAuthService)
+		service := NewOrganizationService(orgRepo, userRepo, authService, nil)
+
+		user, _ := entity.NewUser("member@example.com", "password123", "Member")
+		user.SetActiveOrganization(&orgID)
+		orgRepo.On("FindMembership", ctx, orgID, actorID).Return(entity.NewMembership(orgID, actorID, entity.OrgRoleAdmin), nil)
+		orgRepo.On("FindMembership", ctx, orgID, user.ID).Return(entity.NewMembership(orgID, user.ID, entity.OrgRoleMember), nil)
+		orgRepo.On("RemoveMember", ctx, orgID, user.ID).Return(nil)
+		userRepo.On("FindByID", tenant.Unscoped(ctx), user.ID).Return(user, nil)
+		userRepo.On("Update", tenant.Unscoped(ctx), user).Return(nil)
+		authService.On("LogoutAll", ctx, user.ID).Return(nil)
+
+		err := service.RemoveMember(ctx, actorID, orgID, user.ID)
+
+		require.NoError(t, err)
+		assert.Nil(t, user.ActiveOrganizationID)
+		authService.AssertExpectations(t)
+	})
+
+	t.Run("LastOwner", func(t *testing.T) {
+		orgRepo := new(MockOrganizationRepository)
+		service := NewOrganizationService(orgRepo, new(MockUserRepository), new(MockAuthService), nil)
+		orgRepo.On("FindMembership", ctx, orgID, actorID).Return(entity.NewMembership(orgID, actorID, entity.OrgRoleOwner), nil)
+		orgRepo.On("CountByRole", ctx, orgID, entity.OrgRoleOwner).Return(int64(1), nil)
+
+		err := service.RemoveMember(ctx, actorID, orgID, actorID)
+
+		assert.Equal(t, errors.ErrLastOwner, err)
+		orgRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("MemberRemovesOther", func(t *testing.T) {
+		orgRepo := new(MockOrganizationRepository)
+		service := NewOrganizationService(orgRepo, new(MockUserRepository), new(MockAuthService), nil)
+		orgRepo.On("FindMembership", ctx, orgID, actorID).Return(entity.NewMembership(orgID, actorID, entity.OrgRoleMember), nil)
+
+		err := service.RemoveMember(ctx, actorID, orgID, uuid.New())
+
+		assert.Equal(t, errors.ErrForbidden, err)
+	})
+}
+
+func TestOrganizationService_CheckMembership(t *testing.T) {
+	ctx := context.Background()
+	orgID, memberID, formerID := uuid.New(), uuid.New(), uuid.New()
+	orgRepo := new(MockOrganizationRepository)
+	service := NewOrganizationService(orgRepo, new(MockUserRepository), new(MockAuthService), nil)
+	orgRepo.On("FindMembership", ctx, orgID, memberID).Return(entity.NewMembership(orgID, memberID, entity.OrgRoleMember), nil)
+	orgRepo.On("FindMembership", ctx, orgID, formerID).Return(nil, errors.ErrNotMember)
+
+	assert.NoError(t, service.CheckMembership(ctx, orgID, memberID))
+	assert.Equal(t, errors.ErrNotMember, service.CheckMembership(ctx, orgID, formerID))
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/rs/zerolog/log"
+)
+
+// BreachedPasswordLookup finds breached passwords by the first five hex
+// characters of their SHA-1 hash, so the password itself never leaves the
+// service
+type BreachedPasswordLookup interface {
+	// Range returns the upper case suffixes of the breached hashes that start
+	// with prefix
+	Range(ctx context.Context, prefix string) ([]string, error)
+}
+
+// PasswordPolicy decides which passwords users may choose
+type PasswordPolicy interface {
+	// Check returns an *errors.PasswordPolicyError listing every rule the
+	// password breaks. For users that do not exist yet only Email and Name
+	// need to be set; reuse is not checked without an ID.
+	Check(ctx context.Context, user *entity.User, password string) error
+	// Remember records the user's current password before it is replaced so
+	// it cannot be chosen again while it is one of the most recent ones
+	Remember(ctx context.Context, user *entity.User) error
+}
+
+type passwordPolicy struct {
+	config      *config.Config
+	historyRepo repository.PasswordHistoryRepository
+	breached    BreachedPasswordLookup
+}
+
+func NewPasswordPolicy(
+	cfg *config.Config,
+	historyRepo repository.PasswordHistoryRepository,
+	breached BreachedPasswordLookup,
+) PasswordPolicy {
+	return &passwordPolicy{
+		config:      cfg,
+		historyRepo: historyRepo,
+		breached:    breached,
+	}
+}
+
+// minPersonalInfoLength keeps short names like "Al" from ruling out most passwords
+const minPersonalInfoLength = 3
+
+func (p *passwordPolicy) Check(ctx context.Context, user *entity.User, password string) error {
+	cfg := p.config.Password
+	var reasons []string
+
+	length := utf8.RuneCountInString(password)
+	if length < cfg.MinLength {
+		reasons = append(reasons, fmt.Sprintf("must be at least %d characters", cfg.MinLength))
+	}
+	if cfg.MaxLength > 0 && length > cfg.MaxLength {
+		reasons = append(reasons, fmt.Sprintf("must be at most %d characters", cfg.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if cfg.RequireUpper && !upper {
+		reasons = append(reasons, "must contain an uppercase letter")
+	}
+	if cfg.RequireLower && !lower {
+		reasons = append(reasons, "must contain a lowercase letter")
+	}
+	if cfg.RequireDigit && !digit {
+		reasons = append(reasons, "must contain a digit")
+	}
+	if cfg.RequireSymbol && !symbol {
+		reasons = append(reasons, "must contain a symbol")
+	}
+
+	if cfg.DisallowPersonalInfo {
+		reasons = append(reasons, personalInfoReasons(user, password)...)
+	}
+
+	breached, err := p.isBreached(ctx, password)
+	if err != nil {
+		return err
+	}
+	if breached {
+		reasons = append(reasons, "has appeared in a data breach")
+	}
+
+	reused, err := p.isReused(ctx, user, password)
+	if err != nil {
+		return err
+	}
+	if reused {
+		reasons = append(reasons, fmt.Sprintf("must not match one of your last %d passwords", cfg.HistorySize))
+	}
+
+	if len(reasons) > 0 {
+		return &errors.PasswordPolicyError{Reasons: reasons}
+	}
+	return nil
+}
+
+func personalInfoReasons(user *entity.User, password string) []string {
+	var reasons []string
+	lowered := strings.ToLower(password)
+
+	local, _, _ := strings.Cut(strings.ToLower(user.Email), "@")
+	if len(local) >= minPersonalInfoLength && strings.Contains(lowered, local) {
+		reasons = append(reasons, "must not contain your email address")
+	}
+
+	for _, part := range strings.Fields(strings.ToLower(user.Name)) {
+		if utf8.RuneCountInString(part) >= minPersonalInfoLength && strings.Contains(lowered, part) {
+			reasons = append(reasons, "must not contain your name")
+			break
+		}
+	}
+
+	return reasons
+}
+
+func (p *passwordPolicy) isBreached(ctx context.Context, password string) (bool, error) {
+	if p.breached == nil {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := p.breached.Range(ctx, hash[:5])
+	if err != nil {
+		// An unavailable list must not stop users from changing passwords
+		log.Warn().Err(err).Msg("Failed to look up breached passwords")
+		return false, nil
+	}
+
+	for _, suffix := range suffixes {
+		if suffix == hash[5:] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *passwordPolicy) isReused(ctx context.Context, user *entity.User, password string) (bool, error) {
+	size := p.config.Password.HistorySize
+	if size <= 0 || user.ID == uuid.Nil {
+		return false, nil
+	}
+
+	if user.ComparePassword(password) == nil {
+		return true, nil
+	}
+	if size == 1 {
+		return false, nil
+	}
+
+	history, err := p.historyRepo.ListRecent(ctx, user.ID, size-1)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range history {
+		if entry.Matches(password) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *passwordPolicy) Remember(ctx context.Context, user *entity.User) error {
+	// The current password is always checked; older ones come from the history
+	keep := p.config.Password.HistorySize - 1
+	if keep < 1 {
+		return nil
+	}
+
+	if err := p.historyRepo.Create(ctx, entity.NewPasswordHistory(user)); err != nil {
+		return err
+	}
+	return p.historyRepo.Prune(ctx, user.ID, keep)
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPasswordHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordHistoryRepository) Create(ctx context.Context, entry *entity.PasswordHistory) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockPasswordHistoryRepository) ListRecent(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.PasswordHistory, error) {
+	args := m.Called(ctx, userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entity.PasswordHistory), args.Error(1)
+}
+
+func (m *MockPasswordHistoryRepository) Prune(ctx context.Context, userID uuid.UUID, keep int) error {
+	args := m.Called(ctx, userID, keep)
+	return args.Error(0)
+}
+
+// breachedRanges serves hash suffixes by prefix like the local breach list
+type breachedRanges map[string][]string
+
+func (b breachedRanges) Range(ctx context.Context, prefix string) ([]string, error) {
+	return b[prefix], nil
+}
+
+// newAllowingPasswordPolicy returns a policy that accepts any password
+func newAllowingPasswordPolicy() PasswordPolicy {
+	return NewPasswordPolicy(newTestConfig(), nil, nil)
+}
+
+func newPolicyTestConfig() *config.Config {
+	cfg := newTestConfig()
+	cfg.Password = config.PasswordConfig{
+		MinLength:            8,
+		MaxLength:            16,
+		RequireUpper:         true,
+		RequireLower:         true,
+		RequireDigit:         true,
+		RequireSymbol:        true,
+		DisallowPersonalInfo: true,
+		HistorySize:          3,
+	}
+	return cfg
+}
+
+func policyReasons(t *testing.T, err error) []string {
+	t.Helper()
+	var policyErr *errors.PasswordPolicyError
+	require.ErrorAs(t, err, &policyErr)
+	assert.ErrorIs(t, err, errors.ErrWeakPassword)
+	return policyErr.Reasons
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	ctx := context.Background()
+	newUser := &entity.User{Email: "jane.doe@example.com", Name: "Jane Doe"}
+
+	t.Run("Accepted", func(t *testing.T) {
+		policy := NewPasswordPolicy(newPolicyTestConfig(), nil, nil)
+
+		assert.NoError(t, policy.Check(ctx, newUser, "Tr0ub4dor&3"))
+	})
+
+	t.Run("CharacterRules", func(t *testing.T) {
+		policy := NewPasswordPolicy(newPolicyTestConfig(), nil, nil)
+
+		reasons := policyReasons(t, policy.Check(ctx, newUser, "short"))
+
+		assert.Equal(t, []string{
+			"must be at least 8 characters",
+			"must contain an uppercase letter",
+			"must contain a digit",
+			"must contain a symbol",
+		}, reasons)
+	})
+
+	t.Run("MaxLength", func(t *testing.T) {
+		policy := NewPasswordPolicy(newPolicyTestConfig(), nil, nil)
+
+		reasons := policyReasons(t, policy.Check(ctx, newUser, "Tr0ub4dor&3-and-more"))
+
+		assert.Equal(t, []string{"must be at most 16 characters"}, reasons)
+	})
+
+	t.Run("PersonalInfo", func(t *testing.T) {
+		policy := NewPasswordPolicy(newPolicyTestConfig(), nil, nil)
+
+		reasons := policyReasons(t, policy.Check(ctx, newUser, "Jane.Doe#2024"))
+
+		assert.Equal(t, []string{
+			"must not contain your email address",
+			"must not contain your name",
+		}, reasons)
+	})
+
+	t.Run("Breached", func(t *testing.T) {
+		// SHA-1 of "P@ssw0rd" is 21BD12DC183F740EE76F27B78EB39C8AD972A757
+		breached := breachedRanges{"21BD1": {"0000000000000000000000000000000000A", "2DC183F740EE76F27B78EB39C8AD972A757"}}
+		policy := NewPasswordPolicy(newPolicyTestConfig(), nil, breached)
+
+		reasons := policyReasons(t, policy.Check(ctx, newUser, "P@ssw0rd"))
+
+		assert.Equal(t, []string{"has appeared in a data breach"}, reasons)
+		assert.NoError(t, policy.Check(ctx, newUser, "P@ssw0rd!"))
+	})
+
+	t.Run("Reused", func(t *testing.T) {
+		historyRepo := new(MockPasswordHistoryRepository)
+		policy := NewPasswordPolicy(newPolicyTestConfig(), historyRepo, nil)
+
+		user, _ := entity.NewUser("jane.doe@example.com", "Curr3nt!pass", "Jane Doe")
+		previous, _ := entity.NewUser(user.Email, "Pr3vious!pass", user.Name)
+		previous.ID = user.ID
+		historyRepo.On("ListRecent", ctx, user.ID, 2).Return([]*entity.PasswordHistory{entity.NewPasswordHistory(previous)}, nil)
+
+		assert.Equal(t, []string{"must not match one of your last 3 passwords"}, policyReasons(t, policy.Check(ctx, user, "Curr3nt!pass")))
+		assert.Equal(t, []string{"must not match one of your last 3 passwords"}, policyReasons(t, policy.Check(ctx, user, "Pr3vious!pass")))
+		assert.NoError(t, policy.Check(ctx, user, "Br4nd!new"))
+	})
+}
+
+func TestPasswordPolicy_Remember(t *testing.T) {
+	ctx := context.Background()
+	user, _ := entity.NewUser("jane.doe@example.com", "Curr3nt!pass", "Jane Doe")
+
+	t.Run("StoresAndPrunes", func(t *testing.T) {
+		historyRepo := new(MockPasswordHistoryRepository)
+		policy := NewPasswordPolicy(newPolicyTestConfig(), historyRepo, nil)
+		historyRepo.On("Create", ctx, mock.AnythingOfType("*entity.PasswordHistory")).Return(nil)
+		historyRepo.On("Prune", ctx, user.ID, 2).Return(nil)
+
+		err := policy.Remember(ctx, user)
+
+		assert.NoError(t, err)
+		stored := historyRepo.Calls[0].Arguments.Get(1).(*entity.PasswordHistory)
+		assert.Equal(t, user.ID, stored.UserID)
+		assert.Equal(t, user.Password, stored.PasswordHash)
+		historyRepo.AssertExpectations(t)
+	})
+
+	t.Run("HistoryDisabled", func(t *testing.T) {
+		cfg := newPolicyTestConfig()
+		cfg.Password.HistorySize = 1
+		historyRepo := new(MockPasswordHistoryRepository)
+		policy := NewPasswordPolicy(cfg, historyRepo, nil)
+
+		assert.NoError(t, policy.Remember(ctx, user))
+		historyRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
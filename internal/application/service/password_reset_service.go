@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/mailer"
+	"github.com/rs/zerolog/log"
+)
+
+type PasswordResetService interface {
+	// RequestReset emails a reset link if the address belongs to an active
+	// user. It reports success either way so callers cannot probe for
+	// accounts. The lookup and the email are left to the mail queue, so the
+	// response time does not tell them apart either.
+	RequestReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+type passwordResetService struct {
+	config      *config.Config
+	userRepo    repository.UserRepository
+	tokenRepo   repository.OneTimeTokenRepository
+	mailer      mailer.Mailer
+	mailQueue   *MailQueue
+	authService AuthService
+	policy      PasswordPolicy
+}
+
+func NewPasswordResetService(
+	cfg *config.Config,
+	userRepo repository.UserRepository,
+	tokenRepo repository.OneTimeTokenRepository,
+	m mailer.Mailer,
+	mailQueue *MailQueue,
+	authService AuthService,
+	policy PasswordPolicy,
+) PasswordResetService {
+	return &passwordResetService{
+		config:      cfg,
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		mailer:      m,
+		mailQueue:   mailQueue,
+		authService: authService,
+		policy:      policy,
+	}
+}
+
+func (s *passwordResetService) RequestReset(ctx context.Context, email string) error {
+	// The work outlives the request, which is done once it is handed off
+	ctx = context.WithoutCancel(ctx)
+	queued := s.mailQueue.Enqueue(func() {
+		if err := s.sendResetLink(ctx, email); err != nil {
+			log.Error().Err(err).Msg("Failed to send password reset link")
+		}
+	})
+	if !queued {
+		log.Warn().Msg("Mail queue is full; password reset link dropped")
+	}
+	return nil
+}
+
+// sendResetLink issues a reset token for the user with the email address and
+// mails the link. Unknown and inactive users are skipped.
+func (s *passwordResetService) sendResetLink(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if err == errors.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if !user.Active {
+		return nil
+	}
+
+	token, err := issueOneTimeToken(ctx, s.tokenRepo, user.ID, entity.TokenPurposePasswordReset, s.config.Auth.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.config.App.PublicURL + "/reset-password?token=" + url.QueryEscape(token)
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can only be used once.\n\n%s\n\nIf you did not request a password reset you can ignore this email.\n",
+			user.Name, s.config.Auth.PasswordResetTTL, link,
+		),
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+func (s *passwordResetService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	record, err := s.tokenRepo.FindByHash(ctx, entity.TokenPurposePasswordReset, hashOpaqueToken(token))
+	if err != nil {
+		return err
+	}
+
+	if record.IsUsed() {
+		return errors.ErrInvalidToken
+	}
+
+	if record.IsExpired() {
+		return errors.ErrTokenExpired
+	}
+
+	user, err := s.userRepo.FindByID(ctx, record.UserID)
+	if err != nil {
+		if err == errors.ErrUserNotFound {
+			return errors.ErrInvalidToken
+		}
+		return err
+	}
+
+	// A rejected password leaves the link usable for another attempt
+	if err := s.policy.Check(ctx, user, newPassword); err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.MarkUsed(ctx, record.ID); err != nil {
+		return err
+	}
+
+	if err := s.policy.Remember(ctx, user); err != nil {
+		return err
+	}
+
+	if err := user.UpdatePassword(newPassword); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	// Whoever knew the old password must not keep a session
+	return s.authService.LogoutAll(ctx, user.ID)
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockOneTimeTokenRepository is a mock implementation of repository.OneTimeTokenRepository
+type MockOneTimeTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockOneTimeTokenRepository) Create(ctx context.Context, token *entity.OneTimeToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockOneTimeTokenRepository) FindByHash(ctx context.Context, purpose, tokenHash string) (*entity.OneTimeToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.OneTimeToken), args.Error(1)
+}
+
+func (m *MockOneTimeTokenRepository) FindLatest(ctx context.Context, userID uuid.UUID, purpose string) (*entity.OneTimeToken, error) {
+	args := m.Called(ctx, userID, purpose)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.OneTimeToken), args.Error(1)
+}
+
+func (m *MockOneTimeTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockOneTimeTokenRepository) InvalidateForUser(ctx context.Context, userID uuid.UUID, purpose string) error {
+	args := m.Called(ctx, userID, purpose)
+	return args.Error(0)
+}
+
+// readMail returns the bodies of all messages written by a file mailer
+func readMail(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+
+	var messages []string
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		require.NoError(t, err)
+		messages = append(messages, string(b))
+	}
+	return messages
+}
+
+var resetLinkPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+func TestPasswordResetService_RequestReset(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+	cfg.App.PublicURL = "https://app.example.com"
+	cfg.Auth.PasswordResetTTL = time.Hour
+
+	t.Run("KnownEmail", func(t *testing.T) {
+		mailDir := t.TempDir()
+		m, _ := mailer.NewFileMailer("no-reply@example.com", mailDir)
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		queue := NewMailQueue(cfg)
+		service := NewPasswordResetService(cfg, mockUserRepo, mockTokenRepo, m, queue, nil, newAllowingPasswordPolicy())
+
+		user, _ := entity.NewUser("reset@example.com", "password123", "Reset User")
+		// The request only hands the work off, so its context is not passed on
+		mockUserRepo.On("FindByEmail", mock.Anything, user.Email).Return(user, nil)
+		mockTokenRepo.On("InvalidateForUser", mock.Anything, user.ID, entity.TokenPurposePasswordReset).Return(nil)
+		mockTokenRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.OneTimeToken")).Return(nil)
+
+		err := service.RequestReset(ctx, user.Email)
+
+		assert.NoError(t, err)
+		require.NoError(t, queue.Shutdown(ctx))
+		messages := readMail(t, mailDir)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], "https://app.example.com/reset-password?token=")
+
+		mockTokenRepo.AssertExpectations(t)
+		token := resetLinkPattern.FindStringSubmatch(messages[0])[1]
+		stored := mockTokenRepo.Calls[1].Arguments.Get(1).(*entity.OneTimeToken)
+		assert.Equal(t, hashOpaqueToken(token), stored.TokenHash)
+		assert.Equal(t, user.ID, stored.UserID)
+	})
+
+	t.Run("UnknownEmail", func(t *testing.T) {
+		mailDir := t.TempDir()
+		m, _ := mailer.NewFileMailer("no-reply@example.com", mailDir)
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		queue := NewMailQueue(cfg)
+		service := NewPasswordResetService(cfg, mockUserRepo, mockTokenRepo, m, queue, nil, newAllowingPasswordPolicy())
+		mockUserRepo.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, errors.ErrUserNotFound)
+
+		err := service.RequestReset(ctx, "nobody@example.com")
+
+		assert.NoError(t, err)
+		require.NoError(t, queue.Shutdown(ctx))
+		mockUserRepo.AssertExpectations(t)
+		assert.Empty(t, readMail(t, mailDir))
+		mockTokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestPasswordResetService_ResetPassword(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+
+	newService := func() (PasswordResetService, *MockUserRepository, *MockOneTimeTokenRepository, *MockRefreshTokenRepository, *MockTokenRevocationRepository) {
+		mockUserRepo := new(MockUserRepository)
+		mockTokenRepo := new(MockOneTimeTokenRepository)
+		mockRefreshRepo := new(MockRefreshTokenRepository)
+		mockRevocationRepo := new(MockTokenRevocationRepository)
+		auth := NewAuthService(cfg, newTestKeyRing(), mockUserRepo, mockRefreshRepo, mockRevocationRepo, newTestSessionRepository(), newDisabledMFAService(), newAllowingLockoutService())
+		service := NewPasswordResetService(cfg, mockUserRepo, mockTokenRepo, mailer.NewLogMailer("no-reply@example.com"), NewMailQueue(cfg), auth, newAllowingPasswordPolicy())
+		return service, mockUserRepo, mockTokenRepo, mockRefreshRepo, mockRevocationRepo
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		service, mockUserRepo, mockTokenRepo, mockRefreshRepo, mockRevocationRepo := newService()
+		user, _ := entity.NewUser("reset@example.com", "password123", "Reset User")
+		record := entity.NewOneTimeToken(user.ID, entity.TokenPurposePasswordReset, hashOpaqueToken("raw-token"), time.Hour)
+
+		mockTokenRepo.On("FindByHash", ctx, entity.TokenPurposePasswordReset, record.TokenHash).Return(record, nil)
+		mockTokenRepo.On("MarkUsed", ctx, record.ID).Return(nil)
+		mockUserRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+		mockUserRepo.On("Update", ctx, user).Return(nil)
+		mockRefreshRepo.On("RevokeAllForUser", ctx, user.ID).Return(nil)
+		mockRevocationRepo.On("RevokeUserTokens", ctx, user.ID, mock.AnythingOfType("time.Time")).Return(nil)
+
+		err := service.ResetPassword(ctx, "raw-token", "new-password")
+
+		assert.NoError(t, err)
+		assert.NoError(t, user.ComparePassword("new-password"))
+		mockUserRepo.AssertExpectations(t)
+		mockRefreshRepo.AssertExpectations(t)
+		mockRevocationRepo.AssertExpectations(t)
+	})
+
+	t.Run("AlreadyUsed", func(t *testing.T) {
+		service, mockUserRepo, mockTokenRepo, _, _ := newService()
+		user, _ := entity.NewUser("reset@example.com", "password123", "Reset User")
+		record := entity.NewOneTimeToken(user.ID, entity.TokenPurposePasswordReset, hashOpaqueToken("used-token"), time.Hour)
+
+		mockTokenRepo.On("FindByHash", ctx, entity.TokenPurposePasswordReset, record.TokenHash).Return(record, nil)
+		mockUserRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+		mockTokenRepo.On("MarkUsed", ctx, record.ID).Return(errors.ErrInvalidToken)
+
+		err := service.ResetPassword(ctx, "used-token", "new-password")
+
+		assert.Equal(t, errors.ErrInvalidToken, err)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		service, _, mockTokenRepo, _, _ := newService()
+		record := entity.NewOneTimeToken(uuid.New(), entity.TokenPurposePasswordReset, hashOpaqueToken("old-token"), -time.Minute)
+
+		mockTokenRepo.On("FindByHash", ctx, entity.TokenPurposePasswordReset, record.TokenHash).Return(record, nil)
+
+		err := service.ResetPassword(ctx, "old-token", "new-password")
+
+		assert.Equal(t, errors.ErrTokenExpired, err)
+		mockTokenRepo.AssertNotCalled(t, "MarkUsed", mock.Anything, mock.Anything)
+	})
+}
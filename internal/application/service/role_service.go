@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/cache"
+)
+
+// rolePermissionsTTL bounds how long a permission change may take to apply
+// when several instances share the database
+const rolePermissionsTTL = time.Minute
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+type RoleService interface {
+	List(ctx context.Context) ([]*entity.Role, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Role, error)
+	Create(ctx context.Context, name, description string, permissions []string) (*entity.Role, error)
+	Update(ctx context.Context, id uuid.UUID, description string, permissions []string) (*entity.Role, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	ListPermissions(ctx context.Context) ([]*entity.Permission, error)
+	// HasPermission reports whether any of the named roles grants the
+	// permission. Unknown roles grant nothing.
+	HasPermission(ctx context.Context, roles []string, permission string) (bool, error)
+}
+
+type roleService struct {
+	cache    cache.Cache
+	roleRepo repository.RoleRepository
+	userRepo repository.UserRepository
+}
+
+// NewRoleService creates the role service and makes sure the built-in roles
+// and every known permission exist. The admin role is granted all
+// permissions, including ones added since the last start.
+func NewRoleService(c cache.Cache, roleRepo repository.RoleRepository, userRepo repository.UserRepository) (RoleService, error) {
+	s := &roleService{
+		cache:    c,
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+	}
+
+	if err := s.seed(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *roleService) seed(ctx context.Context) error {
+	if err := s.roleRepo.SavePermissions(ctx, entity.Permissions); err != nil {
+		return err
+	}
+
+	admin, err := s.roleRepo.FindByName(ctx, entity.RoleAdmin)
+	switch err {
+	case nil:
+		admin.SetPermissions(admin.Description, entity.Permissions)
+		if err := s.roleRepo.Update(ctx, admin); err != nil {
+			return err
+		}
+	case errors.ErrRoleNotFound:
+		admin = entity.NewRole(entity.RoleAdmin, "Full access to every resource", entity.Permissions)
+		admin.System = true
+		if err := s.roleRepo.Create(ctx, admin); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	if _, err := s.roleRepo.FindByName(ctx, entity.RoleUser); err != errors.ErrRoleNotFound {
+		return err
+	}
+	user := entity.NewRole(entity.RoleUser, "Default role of new accounts; manages only their own account", nil)
+	user.System = true
+	return s.roleRepo.Create(ctx, user)
+}
+
+func (s *roleService) List(ctx context.Context) ([]*entity.Role, error) {
+	return s.roleRepo.List(ctx)
+}
+
+func (s *roleService) GetByID(ctx context.Context, id uuid.UUID) (*entity.Role, error) {
+	return s.roleRepo.FindByID(ctx, id)
+}
+
+func (s *roleService) Create(ctx context.Context, name, description string, permissions []string) (*entity.Role, error) {
+	if !roleNamePattern.MatchString(name) {
+		return nil, errors.ErrInvalidRole
+	}
+
+	if _, err := s.roleRepo.FindByName(ctx, name); err != errors.ErrRoleNotFound {
+		if err == nil {
+			return nil, errors.ErrRoleAlreadyExists
+		}
+		return nil, err
+	}
+
+	granted, err := s.resolvePermissions(ctx, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := entity.NewRole(name, description, granted)
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+
+	// A role may have been looked up under this name before it existed
+	s.cache.Delete(rolePermissionsKey(name))
+	return role, nil
+}
+
+func (s *roleService) Update(ctx context.Context, id uuid.UUID, description string, permissions []string) (*entity.Role, error) {
+	role, err := s.roleRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Admins must not be able to lock themselves out
+	if role.Name == entity.RoleAdmin {
+		return nil, errors.ErrRoleProtected
+	}
+
+	granted, err := s.resolvePermissions(ctx, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role.SetPermissions(description, granted)
+	if err := s.roleRepo.Update(ctx, role); err != nil {
+		return nil, err
+	}
+
+	s.cache.Delete(rolePermissionsKey(role.Name))
+	return role, nil
+}
+
+func (s *roleService) Delete(ctx context.Context, id uuid.UUID) error {
+	role, err := s.roleRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if role.System {
+		return errors.ErrRoleProtected
+	}
+
+	// Roles are global, so assignments in every organization count
+	assigned, err := s.userRepo.CountByRole(tenant.Unscoped(ctx), role.Name)
+	if err != nil {
+		return err
+	}
+	if assigned > 0 {
+		return errors.ErrRoleInUse
+	}
+
+	if err := s.roleRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.cache.Delete(rolePermissionsKey(role.Name))
+	return nil
+}
+
+func (s *roleService) ListPermissions(ctx context.Context) ([]*entity.Permission, error) {
+	return s.roleRepo.ListPermissions(ctx)
+}
+
+func (s *roleService) HasPermission(ctx context.Context, roles []string, permission string) (bool, error) {
+	for _, role := range roles {
+		granted, err := s.permissions(ctx, role)
+		if err != nil {
+			return false, err
+		}
+		if granted[permission] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// permissions returns the set of permissions the named role grants
+func (s *roleService) permissions(ctx context.Context, role string) (map[string]bool, error) {
+	key := rolePermissionsKey(role)
+	if granted, ok := s.cache.Get(key); ok {
+		return granted.(map[string]bool), nil
+	}
+
+	granted := make(map[string]bool)
+	stored, err := s.roleRepo.FindByName(ctx, role)
+	switch err {
+	case nil:
+		for _, p := range stored.Permissions {
+			granted[p.Name] = true
+		}
+	case errors.ErrRoleNotFound:
+	default:
+		return nil, err
+	}
+
+	s.cache.Set(key, granted, rolePermissionsTTL)
+	return granted, nil
+}
+
+// resolvePermissions looks up permissions by name and rejects unknown ones
+func (s *roleService) resolvePermissions(ctx context.Context, names []string) ([]*entity.Permission, error) {
+	known, err := s.roleRepo.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*entity.Permission, len(known))
+	for _, p := range known {
+		byName[p.Name] = p
+	}
+
+	permissions := make([]*entity.Permission, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		p, ok := byName[name]
+		if !ok {
+			return nil, errors.ErrInvalidPermission
+		}
+		if !seen[name] {
+			seen[name] = true
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions, nil
+}
+
+func rolePermissionsKey(role string) string {
+	return "role_permissions:" + role
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockRoleRepository is a mock implementation of repository.RoleRepository
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) Create(ctx context.Context, role *entity.Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) Update(ctx context.Context, role *entity.Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.Role, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) FindByName(ctx context.Context, name string) (*entity.Role, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) List(ctx context.Context) ([]*entity.Role, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*entity.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) SavePermissions(ctx context.Context, permissions []*entity.Permission) error {
+	args := m.Called(ctx, permissions)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) ListPermissions(ctx context.Context) ([]*entity.Permission, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*entity.Permission), args.Error(1)
+}
+
+// newSeededRoleService returns a role service whose built-in roles already exist
+func newSeededRoleService(t *testing.T, roleRepo *MockRoleRepository, userRepo *MockUserRepository) RoleService {
+	ctx := context.Background()
+	admin := entity.NewRole(entity.RoleAdmin, "", nil)
+	admin.System = true
+	user := entity.NewRole(entity.RoleUser, "", nil)
+	user.System = true
+
+	roleRepo.On("SavePermissions", ctx, entity.Permissions).Return(nil).Once()
+	roleRepo.On("FindByName", ctx, entity.RoleAdmin).Return(admin, nil).Once()
+	roleRepo.On("Update", ctx, admin).Return(nil).Once()
+	roleRepo.On("FindByName", ctx, entity.RoleUser).Return(user, nil).Once()
+	roleRepo.On("ListPermissions", ctx).Return(entity.Permissions, nil).Maybe()
+
+	service, err := NewRoleService(cache.NewMemoryCache(newTestConfig()), roleRepo, userRepo)
+	require.NoError(t, err)
+	assert.Len(t, admin.Permissions, len(entity.Permissions))
+	return service
+}
+
+func TestNewRoleService_SeedsBuiltInRoles(t *testing.T) {
+	ctx := context.Background()
+	roleRepo := new(MockRoleRepository)
+
+	roleRepo.On("SavePermissions", ctx, entity.Permissions).Return(nil)
+	roleRepo.On("FindByName", ctx, mock.Anything).Return(nil, errors.ErrRoleNotFound)
+	roleRepo.On("Create", ctx, mock.AnythingOfType("*entity.Role")).Return(nil)
+
+	_, err := NewRoleService(cache.NewMemoryCache(newTestConfig()), roleRepo, new(MockUserRepository))
+
+	require.NoError(t, err)
+	admin := roleRepo.Calls[2].Arguments.Get(1).(*entity.Role)
+	assert.Equal(t, entity.RoleAdmin, admin.Name)
+	assert.True(t, admin.System)
+	assert.True(t, admin.HasPermission(entity.PermissionRolesManage))
+
+	user := roleRepo.Calls[4].Arguments.Get(1).(*entity.Role)
+	assert.Equal(t, entity.RoleUser, user.Name)
+	assert.Empty(t, user.Permissions)
+}
+
+func TestRoleService_Create(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		service := newSeededRoleService(t, roleRepo, new(MockUserRepository))
+		roleRepo.On("FindByName", ctx, "support").Return(nil, errors.ErrRoleNotFound)
+		roleRepo.On("Create", ctx, mock.AnythingOfType("*entity.Role")).Return(nil)
+
+		role, err := service.Create(ctx, "support", "Support staff", []string{entity.PermissionUsersRead, entity.PermissionUsersRead})
+
+		require.NoError(t, err)
+		assert.Len(t, role.Permissions, 1)
+		assert.True(t, role.HasPermission(entity.PermissionUsersRead))
+	})
+
+	t.Run("UnknownPermission", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		service := newSeededRoleService(t, roleRepo, new(MockUserRepository))
+		roleRepo.On("FindByName", ctx, "support").Return(nil, errors.ErrRoleNotFound)
+
+		_, err := service.Create(ctx, "support", "", []string{"users:everything"})
+
+		assert.Equal(t, errors.ErrInvalidPermission, err)
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		service := newSeededRoleService(t, new(MockRoleRepository), new(MockUserRepository))
+
+		_, err := service.Create(ctx, "Support Staff", "", nil)
+
+		assert.Equal(t, errors.ErrInvalidRole, err)
+	})
+}
+
+func TestRoleService_Protected(t *testing.T) {
+	ctx := context.Background()
+	roleRepo := new(MockRoleRepository)
+	service := newSeededRoleService(t, roleRepo, new(MockUserRepository))
+
+	admin := entity.NewRole(entity.RoleAdmin, "", entity.Permissions)
+	admin.System = true
+	roleRepo.On("FindByID", ctx, admin.ID).Return(admin, nil)
+
+	_, err := service.Update(ctx, admin.ID, "", nil)
+	assert.Equal(t, errors.ErrRoleProtected, err)
+
+	err = service.Delete(ctx, admin.ID)
+	assert.Equal(t, errors.ErrRoleProtected, err)
+}
+
+func TestRoleService_Delete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("InUse", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		userRepo := new(MockUserRepository)
+		service := newSeededRoleService(t, roleRepo, userRepo)
+
+		role := entity.NewRole("support", "", nil)
+		roleRepo.On("FindByID", ctx, role.ID).Return(role, nil)
+		userRepo.On("CountByRole", tenant.Unscoped(ctx), "support").Return(int64(2), nil)
+
+		err := service.Delete(ctx, role.ID)
+
+		assert.Equal(t, errors.ErrRoleInUse, err)
+		roleRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		userRepo := new(MockUserRepository)
+		service := newSeededRoleService(t, roleRepo, userRepo)
+
+		role := entity.NewRole("support", "", nil)
+		roleRepo.On("FindByID", ctx, role.ID).Return(role, nil)
+		userRepo.On("CountByRole", tenant.Unscoped(ctx), "support").Return(int64(0), nil)
+		roleRepo.On("Delete", ctx, role.ID).Return(nil)
+
+		assert.NoError(t, service.Delete(ctx, role.ID))
+	})
+}
+
+func TestRoleService_HasPermission(t *testing.T) {
+	ctx := context.Background()
+	roleRepo := new(MockRoleRepository)
+	service := newSeededRoleService(t, roleRepo, new(MockUserRepository))
+
+	support := entity.NewRole("support", "", []*entity.Permission{{Name: entity.PermissionUsersRead}})
+	roleRepo.On("FindByName", ctx, "support").Return(support, nil).Once()
+	roleRepo.On("FindByName", ctx, "ghost").Return(nil, errors.ErrRoleNotFound).Once()
+
+	ok, err := service.HasPermission(ctx, []string{"support"}, entity.PermissionUsersRead)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// The second lookup is served from the cache
+	ok, err = service.HasPermission(ctx, []string{"support"}, entity.PermissionUsersDelete)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = service.HasPermission(ctx, []string{"ghost"}, entity.PermissionUsersRead)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The permissions of all the roles add up
+	billing := entity.NewRole("billing", "", []*entity.Permission{{Name: entity.PermissionUsersWrite}})
+	roleRepo.On("FindByName", ctx, "billing").Return(billing, nil).Once()
+
+	ok, err = service.HasPermission(ctx, []string{"support", "billing"}, entity.PermissionUsersWrite)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = service.HasPermission(ctx, []string{"ghost", "billing"}, entity.PermissionUsersRead)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = service.HasPermission(ctx, nil, entity.PermissionUsersRead)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	t.Run("UpdateInvalidatesCache", func(t *testing.T) {
+		roleRepo.On("FindByID", ctx, support.ID).Return(support, nil)
+		roleRepo.On("Update", ctx, support).Return(nil)
+		roleRepo.On("FindByName", ctx, "support").Return(support, nil).Once()
+
+		_, err := service.Update(ctx, support.ID, "", []string{entity.PermissionUsersDelete})
+		require.NoError(t, err)
+
+		ok, err := service.HasPermission(ctx, []string{"support"}, entity.PermissionUsersDelete)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
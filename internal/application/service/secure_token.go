@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+)
+
+// oneTimeTokenSize is the number of random bytes in emailed one-time tokens
+const oneTimeTokenSize = 32
+
+// generateOpaqueToken returns a URL-safe random token carrying size bytes of entropy
+func generateOpaqueToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashOpaqueToken returns the digest that is persisted in place of an opaque token
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueOneTimeToken invalidates the user's outstanding tokens for the purpose,
+// so only the most recent link works, and stores a new one. The raw token is
+// returned for delivery and never persisted.
+func issueOneTimeToken(ctx context.Context, repo repository.OneTimeTokenRepository, userID uuid.UUID, purpose string, ttl time.Duration) (string, error) {
+	if err := repo.InvalidateForUser(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := generateOpaqueToken(oneTimeTokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	if err := repo.Create(ctx, entity.NewOneTimeToken(userID, purpose, hashOpaqueToken(token), ttl)); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+)
+
+type SessionService interface {
+	// List returns the sessions of the user that are neither revoked nor
+	// expired, most recently active first
+	List(ctx context.Context, userID uuid.UUID) ([]*entity.Session, error)
+	// Revoke ends a session of the user. Its refresh tokens stop working and
+	// its access tokens are rejected on their next use.
+	Revoke(ctx context.Context, userID, sessionID uuid.UUID) error
+}
+
+type sessionService struct {
+	sessionRepo      repository.SessionRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+}
+
+func NewSessionService(sessionRepo repository.SessionRepository, refreshTokenRepo repository.RefreshTokenRepository) SessionService {
+	return &sessionService{
+		sessionRepo:      sessionRepo,
+		refreshTokenRepo: refreshTokenRepo,
+	}
+}
+
+func (s *sessionService) List(ctx context.Context, userID uuid.UUID) ([]*entity.Session, error) {
+	return s.sessionRepo.ListActiveByUser(ctx, userID)
+}
+
+func (s *sessionService) Revoke(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := s.sessionRepo.Revoke(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	// The session id is the refresh token family
+	return s.refreshTokenRepo.RevokeFamily(ctx, sessionID)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionService_List(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	sessions := []*entity.Session{
+		entity.NewSession(uuid.New(), userID, "laptop", "203.0.113.1", time.Hour),
+		entity.NewSession(uuid.New(), userID, "phone", "203.0.113.2", time.Hour),
+	}
+
+	sessionRepo := new(MockSessionRepository)
+	sessionRepo.On("ListActiveByUser", ctx, userID).Return(sessions, nil)
+	service := NewSessionService(sessionRepo, new(MockRefreshTokenRepository))
+
+	listed, err := service.List(ctx, userID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, sessions, listed)
+}
+
+func TestSessionService_Revoke(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		sessionID := uuid.New()
+		sessionRepo := new(MockSessionRepository)
+		refreshRepo := new(MockRefreshTokenRepository)
+		sessionRepo.On("Revoke", ctx, userID, sessionID).Return(nil)
+		refreshRepo.On("RevokeFamily", ctx, sessionID).Return(nil)
+		service := NewSessionService(sessionRepo, refreshRepo)
+
+		assert.NoError(t, service.Revoke(ctx, userID, sessionID))
+		sessionRepo.AssertExpectations(t)
+		refreshRepo.AssertExpectations(t)
+	})
+
+	t.Run("OtherUsersSession", func(t *testing.T) {
+		sessionID := uuid.New()
+		sessionRepo := new(MockSessionRepository)
+		refreshRepo := new(MockRefreshTokenRepository)
+		sessionRepo.On("Revoke", ctx, userID, sessionID).Return(errors.ErrSessionNotFound)
+		service := NewSessionService(sessionRepo, refreshRepo)
+
+		assert.Equal(t, errors.ErrSessionNotFound, service.Revoke(ctx, userID, sessionID))
+		refreshRepo.AssertNotCalled(t, "RevokeFamily", ctx, sessionID)
+	})
+}
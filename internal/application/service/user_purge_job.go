@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/rs/zerolog/log"
+)
+
+// UserPurgeJob permanently removes deleted users once their retention period
+// has passed
+type UserPurgeJob struct {
+	config      *config.Config
+	userService UserService
+}
+
+func NewUserPurgeJob(cfg *config.Config, userService UserService) *UserPurgeJob {
+	return &UserPurgeJob{
+		config:      cfg,
+		userService: userService,
+	}
+}
+
+// Start purges right away and then every Retention.PurgeInterval until ctx
+// is done. It does nothing when the interval is zero.
+func (j *UserPurgeJob) Start(ctx context.Context) {
+	interval := j.config.Retention.PurgeInterval
+	if interval <= 0 {
+		return
+	}
+
+	go j.run(ctx, interval)
+}
+
+func (j *UserPurgeJob) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		j.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *UserPurgeJob) purge(ctx context.Context) {
+	purged, err := j.userService.PurgeDeleted(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to purge deleted users")
+		return
+	}
+	if purged > 0 {
+		log.Info().Int64("count", purged).Msg("Purged deleted users")
+	}
+}
@@ -2,41 +2,101 @@ package service
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/rs/zerolog/log"
 )
 
 type UserService interface {
+	// Create registers a user through public signup and emails a
+	// verification link. It fails with ErrSignupDisabled in invite-only mode.
 	Create(ctx context.Context, email, password, name string) (*entity.User, error)
 	Update(ctx context.Context, id uuid.UUID, name string) (*entity.User, error)
+	// Delete soft-deletes the user, who can be restored until the retention
+	// period ends. The user's sessions, tokens and API keys are revoked and
+	// stay revoked after a restore.
 	Delete(ctx context.Context, id uuid.UUID) error
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error)
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
-	List(ctx context.Context, page, limit int) ([]*entity.User, int64, error)
+	// List returns a page of users matching the query with the total count
+	// of matches
+	List(ctx context.Context, query repository.UserQuery) ([]*entity.User, int64, error)
 	ChangePassword(ctx context.Context, id uuid.UUID, oldPassword, newPassword string) error
-	UpdateRole(ctx context.Context, id uuid.UUID, role string) error
+	// UpdateRoles replaces the roles of the user; at least one is required
+	UpdateRoles(ctx context.Context, id uuid.UUID, roles []string) error
+	ListDeleted(ctx context.Context, page, limit int) ([]*entity.User, int64, error)
+	Restore(ctx context.Context, id uuid.UUID) (*entity.User, error)
+	// PurgeDeleted permanently removes the users deleted longer than the
+	// retention period ago and returns how many were removed
+	PurgeDeleted(ctx context.Context) (int64, error)
 }
 
 type userService struct {
-	userRepo repository.UserRepository
+	config              *config.Config
+	userRepo            repository.UserRepository
+	roleRepo            repository.RoleRepository
+	verificationService EmailVerificationService
+	passwordPolicy      PasswordPolicy
+	authService         AuthService
+	apiKeyRepo          repository.APIKeyRepository
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(
+	cfg *config.Config,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	verificationService EmailVerificationService,
+	passwordPolicy PasswordPolicy,
+	authService AuthService,
+	apiKeyRepo repository.APIKeyRepository,
+) UserService {
 	return &userService{
-		userRepo: userRepo,
+		config:              cfg,
+		userRepo:            userRepo,
+		roleRepo:            roleRepo,
+		verificationService: verificationService,
+		passwordPolicy:      passwordPolicy,
+		authService:         authService,
+		apiKeyRepo:          apiKeyRepo,
 	}
 }
 
 func (s *userService) Create(ctx context.Context, email, password, name string) (*entity.User, error) {
-	// Check if user already exists
-	_, err := s.userRepo.FindByEmail(ctx, email)
+	if s.config.Auth.InviteOnly {
+		return nil, errors.ErrSignupDisabled
+	}
+
+	user, err := s.create(ctx, email, password, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The account exists either way; a failed send can be retried via resend
+	if err := s.verificationService.SendVerification(ctx, user); err != nil {
+		log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to issue email verification token")
+	}
+
+	return user, nil
+}
+
+func (s *userService) create(ctx context.Context, email, password, name string) (*entity.User, error) {
+	// Check if user already exists; addresses are unique across tenants
+	_, err := s.userRepo.FindByEmail(tenant.Unscoped(ctx), email)
 	if err == nil {
 		return nil, errors.ErrUserAlreadyExists
 	}
 
+	if err := s.passwordPolicy.Check(ctx, &entity.User{Email: email, Name: name}, password); err != nil {
+		return nil, err
+	}
+
 	// Create new user
 	user, err := entity.NewUser(email, password, name)
 	if err != nil {
@@ -67,7 +127,14 @@ func (s *userService) Update(ctx context.Context, id uuid.UUID, name string) (*e
 }
 
 func (s *userService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.userRepo.Delete(ctx, id)
+	if err := s.userRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.authService.LogoutAll(ctx, id); err != nil {
+		return err
+	}
+	return s.apiKeyRepo.RevokeAllForUser(ctx, id)
 }
 
 func (s *userService) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
@@ -78,14 +145,16 @@ func (s *userService) GetByEmail(ctx context.Context, email string) (*entity.Use
 	return s.userRepo.FindByEmail(ctx, email)
 }
 
-func (s *userService) List(ctx context.Context, page, limit int) ([]*entity.User, int64, error) {
-	if page < 1 {
-		page = 1
+func (s *userService) List(ctx context.Context, query repository.UserQuery) ([]*entity.User, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
 	}
-	if limit < 1 {
-		limit = 10
+	if query.Limit < 1 {
+		query.Limit = 10
 	}
-	return s.userRepo.List(ctx, page, limit)
+	query.Search = strings.TrimSpace(query.Search)
+	query.EmailDomain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(query.EmailDomain), "@"))
+	return s.userRepo.List(ctx, query)
 }
 
 func (s *userService) ChangePassword(ctx context.Context, id uuid.UUID, oldPassword, newPassword string) error {
@@ -98,6 +167,14 @@ func (s *userService) ChangePassword(ctx context.Context, id uuid.UUID, oldPassw
 		return errors.ErrInvalidPassword
 	}
 
+	if err := s.passwordPolicy.Check(ctx, user, newPassword); err != nil {
+		return err
+	}
+
+	if err := s.passwordPolicy.Remember(ctx, user); err != nil {
+		return err
+	}
+
 	if err := user.UpdatePassword(newPassword); err != nil {
 		return err
 	}
@@ -105,19 +182,55 @@ func (s *userService) ChangePassword(ctx context.Context, id uuid.UUID, oldPassw
 	return s.userRepo.Update(ctx, user)
 }
 
-func (s *userService) UpdateRole(ctx context.Context, id uuid.UUID, role string) error {
-	user, err := s.userRepo.FindByID(ctx, id)
-	if err != nil {
+func (s *userService) UpdateRoles(ctx context.Context, id uuid.UUID, roles []string) error {
+	if _, err := s.userRepo.FindByID(ctx, id); err != nil {
 		return err
 	}
 
-	// Validate role
-	switch role {
-	case "admin", "user":
-		user.SetRole(role)
-	default:
+	if len(roles) == 0 {
 		return errors.ErrInvalidRole
 	}
 
-	return s.userRepo.Update(ctx, user)
+	// Only roles managed through the role service can be assigned
+	assigned := make([]string, 0, len(roles))
+	seen := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		if seen[role] {
+			continue
+		}
+		seen[role] = true
+
+		if _, err := s.roleRepo.FindByName(ctx, role); err != nil {
+			if err == errors.ErrRoleNotFound {
+				return errors.ErrInvalidRole
+			}
+			return err
+		}
+		assigned = append(assigned, role)
+	}
+
+	return s.userRepo.SetRoles(ctx, id, assigned)
+}
+
+func (s *userService) ListDeleted(ctx context.Context, page, limit int) ([]*entity.User, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	return s.userRepo.ListDeleted(ctx, page, limit)
+}
+
+func (s *userService) Restore(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	if err := s.userRepo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.userRepo.FindByID(ctx, id)
+}
+
+func (s *userService) PurgeDeleted(ctx context.Context) (int64, error) {
+	// Retention applies to every organization
+	cutoff := time.Now().Add(-s.config.Retention.DeletedUsers)
+	return s.userRepo.Purge(tenant.Unscoped(ctx), cutoff)
 }
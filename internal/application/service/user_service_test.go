@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -47,14 +50,75 @@ func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*en
 	return args.Get(0).(*entity.User), args.Error(1)
 }
 
-func (m *MockUserRepository) List(ctx context.Context, page, limit int) ([]*entity.User, int64, error) {
+func (m *MockUserRepository) List(ctx context.Context, query repository.UserQuery) ([]*entity.User, int64, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]*entity.User), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRepository) SetRoles(ctx context.Context, id uuid.UUID, roles []string) error {
+	args := m.Called(ctx, id, roles)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	args := m.Called(ctx, role)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepository) IncrementFailedLogins(ctx context.Context, id uuid.UUID) (int, error) {
+	args := m.Called(ctx, id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ExtendLock(ctx context.Context, id uuid.UUID, until time.Time) error {
+	args := m.Called(ctx, id, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetFailedLogins(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ListDeleted(ctx context.Context, page, limit int) ([]*entity.User, int64, error) {
 	args := m.Called(ctx, page, limit)
 	return args.Get(0).([]*entity.User), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockUserRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	args := m.Called(ctx, deletedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockEmailVerificationService is a mock implementation of EmailVerificationService
+type MockEmailVerificationService struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationService) SendVerification(ctx context.Context, user *entity.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationService) Resend(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationService) Verify(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
 func TestUserService_Create(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	mockVerification := new(MockEmailVerificationService)
+	service := NewUserService(newTestConfig(), mockRepo, new(MockRoleRepository), mockVerification, newAllowingPasswordPolicy(), new(MockAuthService), new(MockAPIKeyRepository))
 	ctx := context.Background()
 
 	t.Run("Success", func(t *testing.T) {
@@ -62,8 +126,9 @@ func TestUserService_Create(t *testing.T) {
 		password := "password123"
 		name := "Test User"
 
-		mockRepo.On("FindByEmail", ctx, email).Return(nil, errors.ErrUserNotFound)
+		mockRepo.On("FindByEmail", tenant.Unscoped(ctx), email).Return(nil, errors.ErrUserNotFound)
 		mockRepo.On("Create", ctx, mock.AnythingOfType("*entity.User")).Return(nil)
+		mockVerification.On("SendVerification", ctx, mock.AnythingOfType("*entity.User")).Return(nil)
 
 		user, err := service.Create(ctx, email, password, name)
 
@@ -71,7 +136,9 @@ func TestUserService_Create(t *testing.T) {
 		assert.NotNil(t, user)
 		assert.Equal(t, email, user.Email)
 		assert.Equal(t, name, user.Name)
+		assert.False(t, user.EmailVerified)
 		mockRepo.AssertExpectations(t)
+		mockVerification.AssertExpectations(t)
 	})
 
 	t.Run("UserAlreadyExists", func(t *testing.T) {
@@ -80,7 +147,7 @@ func TestUserService_Create(t *testing.T) {
 		name := "Existing User"
 
 		existingUser, _ := entity.NewUser(email, password, name)
-		mockRepo.On("FindByEmail", ctx, email).Return(existingUser, nil)
+		mockRepo.On("FindByEmail", tenant.Unscoped(ctx), email).Return(existingUser, nil)
 
 		user, err := service.Create(ctx, email, password, name)
 
@@ -89,11 +156,22 @@ func TestUserService_Create(t *testing.T) {
 		assert.Nil(t, user)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("InviteOnly", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Auth.InviteOnly = true
+		service := NewUserService(cfg, new(MockUserRepository), new(MockRoleRepository), new(MockEmailVerificationService), newAllowingPasswordPolicy(), new(MockAuthService), new(MockAPIKeyRepository))
+
+		user, err := service.Create(ctx, "new@example.com", "password123", "New User")
+
+		assert.Equal(t, errors.ErrSignupDisabled, err)
+		assert.Nil(t, user)
+	})
 }
 
 func TestUserService_GetByID(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(newTestConfig(), mockRepo, new(MockRoleRepository), new(MockEmailVerificationService), newAllowingPasswordPolicy(), new(MockAuthService), new(MockAPIKeyRepository))
 	ctx := context.Background()
 
 	t.Run("Success", func(t *testing.T) {
@@ -123,3 +201,156 @@ func TestUserService_GetByID(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestUserService_List(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockUserRepository)
+	service := NewUserService(newTestConfig(), mockRepo, new(MockRoleRepository), new(MockEmailVerificationService), newAllowingPasswordPolicy(), new(MockAuthService), new(MockAPIKeyRepository))
+
+	users := []*entity.User{{Email: "jane@example.com"}}
+	mockRepo.On("List", ctx, repository.UserQuery{
+		Page:        1,
+		Limit:       10,
+		Search:      "jane doe",
+		EmailDomain: "example.com",
+		Sort:        []repository.SortOrder{{Field: "name", Desc: true}},
+	}).Return(users, int64(1), nil)
+
+	listed, total, err := service.List(ctx, repository.UserQuery{
+		Search:      "  jane doe ",
+		EmailDomain: "@Example.COM",
+		Sort:        []repository.SortOrder{{Field: "name", Desc: true}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, users, listed)
+	assert.Equal(t, int64(1), total)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_UpdateRoles(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRoles := new(MockRoleRepository)
+		service := NewUserService(newTestConfig(), mockRepo, mockRoles, new(MockEmailVerificationService), newAllowingPasswordPolicy(), new(MockAuthService), new(MockAPIKeyRepository))
+
+		user, _ := entity.NewUser("test@example.com", "password123", "Test User")
+		mockRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+		mockRoles.On("FindByName", ctx, "support").Return(entity.NewRole("support", "", nil), nil)
+		mockRoles.On("FindByName", ctx, "billing").Return(entity.NewRole("billing", "", nil), nil)
+		mockRepo.On("SetRoles", ctx, user.ID, []string{"support", "billing"}).Return(nil)
+
+		err := service.UpdateRoles(ctx, user.ID, []string{"support", "billing", "support"})
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("NoRoles", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(newTestConfig(), mockRepo, new(MockRoleRepository), new(MockEmailVerificationService), newAllowingPasswordPolicy(), new(MockAuthService), new(MockAPIKeyRepository))
+
+		user, _ := entity.NewUser("test@example.com", "password123", "Test User")
+		mockRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+
+		err := service.UpdateRoles(ctx, user.ID, nil)
+
+		assert.Equal(t, errors.ErrInvalidRole, err)
+		mockRepo.AssertNotCalled(t, "SetRoles", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("UnknownRole", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRoles := new(MockRoleRepository)
+		service := NewUserService(newTestConfig(), mockRepo, mockRoles, new(MockEmailVerificationService), newAllowingPasswordPolicy(), new(MockAuthService), new(MockAPIKeyRepository))
+
+		user, _ := entity.NewUser("test@example.com", "password123", "Test User")
+		mockRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+		mockRoles.On("FindByName", ctx, "superuser").Return(nil, errors.ErrRoleNotFound)
+
+		err := service.UpdateRoles(ctx, user.ID, []string{"superuser"})
+
+		assert.Equal(t, errors.ErrInvalidRole, err)
+		mockRepo.AssertNotCalled(t, "SetRoles", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserService_Delete(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+
+	t.Run("RevokesCredentials", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		authService := new(MockAuthService)
+		apiKeyRepo := new(MockAPIKeyRepository)
+		service := NewUserService(newTestConfig(), mockRepo, new(MockRoleRepository), new(MockEmailVerificationService), newAllowingPasswordPolicy(), authService, apiKeyRepo)
+		mockRepo.On("Delete", ctx, id).Return(nil)
+		authService.On("LogoutAll", ctx, id).Return(nil)
+		apiKeyRepo.On("RevokeAllForUser", ctx, id).Return(nil)
+
+		assert.NoError(t, service.Delete(ctx, id))
+		authService.AssertExpectations(t)
+		apiKeyRepo.AssertExpectations(t)
+	})
+
+	t.Run("UserNotFound", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		authService := new(MockAuthService)
+		service := NewUserService(newTestConfig(), mockRepo, new(MockRoleRepository), new(MockEmailVerificationService), newAllowingPasswordPolicy(), authService, new(MockAPIKeyRepository))
+		mockRepo.On("Delete", ctx, id).Return(errors.ErrUserNotFound)
+
+		assert.Equal(t, errors.ErrUserNotFound, service.Delete(ctx, id))
+		authService.AssertNotCalled(t, "LogoutAll", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserService_Restore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(newTestConfig(), mockRepo, new(MockRoleRepository), new(MockEmailVerificationService), newAllowingPasswordPolicy(), new(MockAuthService), new(MockAPIKeyRepository))
+
+		user, _ := entity.NewUser("test@example.com", "password123", "Test User")
+		mockRepo.On("Restore", ctx, user.ID).Return(nil)
+		mockRepo.On("FindByID", ctx, user.ID).Return(user, nil)
+
+		restored, err := service.Restore(ctx, user.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, user, restored)
+	})
+
+	t.Run("NotDeleted", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(newTestConfig(), mockRepo, new(MockRoleRepository), new(MockEmailVerificationService), newAllowingPasswordPolicy(), new(MockAuthService), new(MockAPIKeyRepository))
+
+		id := uuid.New()
+		mockRepo.On("Restore", ctx, id).Return(errors.ErrUserNotFound)
+
+		restored, err := service.Restore(ctx, id)
+
+		assert.Equal(t, errors.ErrUserNotFound, err)
+		assert.Nil(t, restored)
+		mockRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserService_PurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+	cfg.Retention.DeletedUsers = 30 * 24 * time.Hour
+	mockRepo := new(MockUserRepository)
+	service := NewUserService(cfg, mockRepo, new(MockRoleRepository), new(MockEmailVerificationService), newAllowingPasswordPolicy(), new(MockAuthService), new(MockAPIKeyRepository))
+
+	mockRepo.On("Purge", tenant.Unscoped(ctx), mock.AnythingOfType("time.Time")).Return(int64(2), nil)
+
+	purged, err := service.PurgeDeleted(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+	cutoff := mockRepo.Calls[0].Arguments.Get(1).(time.Time)
+	assert.WithinDuration(t, time.Now().Add(-cfg.Retention.DeletedUsers), cutoff, time.Minute)
+}
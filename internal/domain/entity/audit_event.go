@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditImpersonationStart = "impersonation.start"
+	AuditImpersonationStop  = "impersonation.stop"
+)
+
+// AuditEvent records a security relevant action taken by a user
+type AuditEvent struct {
+	ID      uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	Action  string    `json:"action" gorm:"not null;index"`
+	ActorID uuid.UUID `json:"actor_id" gorm:"type:uuid;not null;index"`
+	// SubjectID is the user the action was taken on, if any
+	SubjectID *uuid.UUID `json:"subject_id,omitempty" gorm:"type:uuid;index"`
+	IP        string     `json:"ip"`
+	UserAgent string     `json:"user_agent"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+}
+
+func NewAuditEvent(action string, actorID uuid.UUID, subjectID *uuid.UUID, ip, userAgent string) *AuditEvent {
+	return &AuditEvent{
+		ID:        uuid.New(),
+		Action:    action,
+		ActorID:   actorID,
+		SubjectID: subjectID,
+		IP:        ip,
+		UserAgent: userAgent,
+		CreatedAt: time.Now(),
+	}
+}
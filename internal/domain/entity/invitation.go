@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invitation lets the holder of an emailed link join with a role chosen by
+// the inviting admin. Only the hash of the link token is stored.
+type Invitation struct {
+	ID    uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	Email string    `json:"email" gorm:"not null;index"`
+	Role  string    `json:"role" gorm:"not null"`
+	// OrganizationID is the organization the invitee joins, if any, with
+	// OrgRole as their role in it
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty" gorm:"type:uuid;index"`
+	OrgRole        string     `json:"org_role,omitempty" gorm:"not null;default:member"`
+	InvitedBy      uuid.UUID  `json:"invited_by" gorm:"type:uuid;not null"`
+	TokenHash      string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func NewInvitation(email, role string, organizationID *uuid.UUID, orgRole string, invitedBy uuid.UUID, tokenHash string, ttl time.Duration) *Invitation {
+	now := time.Now()
+	return &Invitation{
+		ID:             uuid.New(),
+		Email:          email,
+		Role:           role,
+		OrganizationID: organizationID,
+		OrgRole:        orgRole,
+		InvitedBy:      invitedBy,
+		TokenHash:      tokenHash,
+		ExpiresAt:      now.Add(ttl),
+		CreatedAt:      now,
+	}
+}
+
+func (i *Invitation) IsExpired() bool {
+	return time.Now().After(i.ExpiresAt)
+}
+
+func (i *Invitation) IsAccepted() bool {
+	return i.AcceptedAt != nil
+}
+
+func (i *Invitation) IsRevoked() bool {
+	return i.RevokedAt != nil
+}
+
+// IsPending reports whether the invitation can still be accepted
+func (i *Invitation) IsPending() bool {
+	return !i.IsAccepted() && !i.IsRevoked() && !i.IsExpired()
+}
@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TOTPFactor is a user's authenticator app enrolment. Secret has to be kept in
+// a recoverable form to compute codes and is never serialised.
+type TOTPFactor struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;uniqueIndex"`
+	Secret       string     `json:"-" gorm:"not null"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	LastUsedStep int64      `json:"-" gorm:"not null;default:0"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func NewTOTPFactor(userID uuid.UUID, secret string) *TOTPFactor {
+	return &TOTPFactor{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// IsConfirmed reports whether enrolment finished and the factor is enforced at login
+func (f *TOTPFactor) IsConfirmed() bool {
+	return f.ConfirmedAt != nil
+}
+
+func (f *TOTPFactor) Confirm(step int64) {
+	now := time.Now()
+	f.ConfirmedAt = &now
+	f.LastUsedStep = step
+	f.UpdatedAt = now
+}
+
+// RecoveryCode is a hashed single-use code that stands in for the authenticator app
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func NewRecoveryCode(userID uuid.UUID, codeHash string) *RecoveryCode {
+	return &RecoveryCode{
+		ID:        uuid.New(),
+		UserID:    userID,
+		CodeHash:  codeHash,
+		CreatedAt: time.Now(),
+	}
+}
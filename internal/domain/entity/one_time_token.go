@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purposes of one-time tokens. A token is only ever accepted for the purpose
+// it was issued for.
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeMagicLink         = "magic_link"
+)
+
+// OneTimeToken is a hashed, expiring, single-use secret that is delivered to
+// the user out of band, e.g. in a password reset email
+type OneTimeToken struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Purpose   string     `json:"purpose" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func NewOneTimeToken(userID uuid.UUID, purpose, tokenHash string, ttl time.Duration) *OneTimeToken {
+	now := time.Now()
+	return &OneTimeToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+}
+
+func (t *OneTimeToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *OneTimeToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Roles of a user within an organization. They govern the organization
+// itself; API permissions come from the user's global role.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// Organization is a tenant. Users see only the users of their active
+// organization.
+type Organization struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	Name      string    `json:"name" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewOrganization(name string) *Organization {
+	return &Organization{
+		ID:        uuid.New(),
+		Name:      name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// Membership links a user to an organization with a per-organization role
+type Membership struct {
+	ID             uuid.UUID     `json:"id" gorm:"type:uuid;primary_key"`
+	OrganizationID uuid.UUID     `json:"organization_id" gorm:"type:uuid;not null;uniqueIndex:idx_membership_org_user"`
+	UserID         uuid.UUID     `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_membership_org_user;index"`
+	Role           string        `json:"role" gorm:"not null"`
+	Organization   *Organization `json:"organization,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	User           *User         `json:"user,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+func NewMembership(organizationID, userID uuid.UUID, role string) *Membership {
+	return &Membership{
+		ID:             uuid.New(),
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Role:           role,
+		CreatedAt:      time.Now(),
+	}
+}
+
+// CanManage reports whether the member may add and remove other members
+func (m *Membership) CanManage() bool {
+	return m.Role == OrgRoleOwner || m.Role == OrgRoleAdmin
+}
+
+// IsValidOrgRole reports whether role is one of the organization roles
+func IsValidOrgRole(role string) bool {
+	switch role {
+	case OrgRoleOwner, OrgRoleAdmin, OrgRoleMember:
+		return true
+	}
+	return false
+}
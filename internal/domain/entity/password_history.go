@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordHistory is a former password hash of a user, kept to refuse the
+// reuse of recent passwords
+type PasswordHistory struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	UserID       uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	PasswordHash string    `json:"-" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NewPasswordHistory records the current password hash of the user
+func NewPasswordHistory(user *User) *PasswordHistory {
+	return &PasswordHistory{
+		ID:           uuid.New(),
+		UserID:       user.ID,
+		PasswordHash: user.Password,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// Matches reports whether the password is the one this hash was made from
+func (h *PasswordHistory) Matches(password string) bool {
+	return passwordHasher.Verify(h.PasswordHash, password) == nil
+}
@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevokedToken denies a single access token, identified by its jti claim,
+// until the token would have expired anyway
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primary_key"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewRevokedToken(jti string, userID uuid.UUID, expiresAt time.Time) *RevokedToken {
+	return &RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+}
+
+// UserTokenRevocation denies every access token of a user issued at or
+// before RevokedBefore
+type UserTokenRevocation struct {
+	UserID        uuid.UUID `json:"user_id" gorm:"type:uuid;primary_key"`
+	RevokedBefore time.Time `json:"revoked_before" gorm:"not null"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,122 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Built-in roles, created on startup. New users get RoleUser; a user may
+// hold several roles and has the permissions of all of them.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Permissions checked by the API. New permissions are added here and granted
+// to the admin role automatically on the next startup.
+const (
+	PermissionUsersRead        = "users:read"
+	PermissionUsersWrite       = "users:write"
+	PermissionUsersDelete      = "users:delete"
+	PermissionUsersRestore     = "users:restore"
+	PermissionUsersRole        = "users:role"
+	PermissionUsersLogout      = "users:logout"
+	PermissionUsersUnlock      = "users:unlock"
+	PermissionUsersInvite      = "users:invite"
+	PermissionUsersImpersonate = "users:impersonate"
+	PermissionKeysManage       = "keys:manage"
+	PermissionClientsManage    = "clients:manage"
+	PermissionRolesManage      = "roles:manage"
+	PermissionPolicyRead       = "policy:read"
+	PermissionAuditRead        = "audit:read"
+)
+
+// Permissions lists every permission with its description
+var Permissions = []*Permission{
+	{Name: PermissionUsersRead, Description: "View any user"},
+	{Name: PermissionUsersWrite, Description: "Update any user's profile and password"},
+	{Name: PermissionUsersDelete, Description: "Delete any user"},
+	{Name: PermissionUsersRestore, Description: "View and restore deleted users"},
+	{Name: PermissionUsersRole, Description: "Assign roles to users"},
+	{Name: PermissionUsersLogout, Description: "Revoke the sessions of any user"},
+	{Name: PermissionUsersUnlock, Description: "View and clear account lockouts"},
+	{Name: PermissionUsersInvite, Description: "Invite, list and revoke invitations"},
+	{Name: PermissionUsersImpersonate, Description: "Act as another user for support purposes"},
+	{Name: PermissionKeysManage, Description: "Create, promote and retire signing keys"},
+	{Name: PermissionClientsManage, Description: "Register and remove OAuth clients"},
+	{Name: PermissionRolesManage, Description: "Manage roles and their permissions"},
+	{Name: PermissionPolicyRead, Description: "Explain authorization policy decisions"},
+	{Name: PermissionAuditRead, Description: "View the audit log"},
+}
+
+// IsValidPermission reports whether the name is a known permission
+func IsValidPermission(name string) bool {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type Permission struct {
+	Name        string `json:"name" gorm:"primary_key"`
+	Description string `json:"description"`
+}
+
+// Role is a named set of permissions. System roles are created on startup
+// and cannot be deleted.
+type Role struct {
+	ID          uuid.UUID     `json:"id" gorm:"type:uuid;primary_key"`
+	Name        string        `json:"name" gorm:"uniqueIndex;not null"`
+	Description string        `json:"description"`
+	System      bool          `json:"system" gorm:"not null;default:false"`
+	Permissions []*Permission `json:"permissions" gorm:"many2many:role_permissions;joinForeignKey:RoleID;joinReferences:PermissionName"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+func NewRole(name, description string, permissions []*Permission) *Role {
+	return &Role{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: description,
+		Permissions: permissions,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+}
+
+func (r *Role) SetPermissions(description string, permissions []*Permission) {
+	r.Description = description
+	r.Permissions = permissions
+	r.UpdatedAt = time.Now()
+}
+
+// HasPermission reports whether the role grants the permission
+func (r *Role) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p.Name == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// UserRole assigns a role to a user
+type UserRole struct {
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;primary_key"`
+	RoleID    uuid.UUID `json:"role_id" gorm:"type:uuid;primary_key;index"`
+	User      *User     `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Role      *Role     `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewUserRole(userID, roleID uuid.UUID) *UserRole {
+	return &UserRole{
+		UserID:    userID,
+		RoleID:    roleID,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,11 @@
+package entity
+
+// ScopeAccount covers the self-service routes that manage the caller's own
+// account, such as second factors, API keys and organizations. Every other
+// scope is a permission name.
+const ScopeAccount = "account"
+
+// IsValidScope reports whether a token may be restricted to the scope
+func IsValidScope(scope string) bool {
+	return scope == ScopeAccount || IsValidPermission(scope)
+}
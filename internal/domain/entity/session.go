@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a login on one device. Its ID is the family of the refresh
+// tokens issued from the login and the sid claim of their access tokens.
+type Session struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// ExpiresAt follows the most recent refresh token of the session
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func NewSession(id, userID uuid.UUID, userAgent, ip string, ttl time.Duration) *Session {
+	now := time.Now()
+	return &Session{
+		ID:         id,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+}
+
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+func (s *Session) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
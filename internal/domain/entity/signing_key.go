@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	// SigningKeyPending keys are published for verification but do not sign yet
+	SigningKeyPending = "pending"
+	// SigningKeyActive is the single key that signs new tokens
+	SigningKeyActive = "active"
+	// SigningKeyRetired keys only verify tokens until ExpiresAt
+	SigningKeyRetired = "retired"
+)
+
+// SigningKey is a persisted JWT signing key. Material holds the private key
+// (PKCS#8 PEM) or the HMAC secret, encrypted unless Encrypted is false as in
+// rows stored by earlier versions, and is never serialised.
+type SigningKey struct {
+	ID        string     `json:"kid" gorm:"primary_key"`
+	Algorithm string     `json:"alg" gorm:"not null"`
+	Material  []byte     `json:"-" gorm:"not null"`
+	Encrypted bool       `json:"-" gorm:"not null;default:false"`
+	Status    string     `json:"status" gorm:"not null;index"`
+	RetiredAt *time.Time `json:"retired_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// NewSigningKey records a key whose material is already encrypted
+func NewSigningKey(id, algorithm string, material []byte) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Algorithm: algorithm,
+		Material:  material,
+		Encrypted: true,
+		Status:    SigningKeyPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// IsExpired reports whether the key may no longer verify tokens
+func (k *SigningKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
+func (k *SigningKey) Activate() {
+	k.Status = SigningKeyActive
+	k.ExpiresAt = nil
+	k.UpdatedAt = time.Now()
+}
+
+// Retire stops the key from signing; it keeps verifying for the grace period
+func (k *SigningKey) Retire(grace time.Duration) {
+	now := time.Now()
+	expiresAt := now.Add(grace)
+
+	k.Status = SigningKeyRetired
+	k.RetiredAt = &now
+	k.ExpiresAt = &expiresAt
+	k.UpdatedAt = now
+}
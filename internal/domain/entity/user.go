@@ -4,22 +4,56 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/mrfansi/go-api-boilerplate/pkg/password"
+	"gorm.io/gorm"
 )
 
+// PasswordHasher encodes and verifies user password hashes
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(encoded, password string) error
+	// NeedsRehash reports whether the hash is outdated and should be
+	// replaced after the next successful comparison
+	NeedsRehash(encoded string) bool
+}
+
+var passwordHasher PasswordHasher = mustDefaultHasher()
+
+func mustDefaultHasher() PasswordHasher {
+	h, err := password.NewHasher(password.DefaultConfig())
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// SetPasswordHasher replaces the hasher of new passwords. It is meant to be
+// called once at startup, before any user is created.
+func SetPasswordHasher(h PasswordHasher) {
+	passwordHasher = h
+}
+
 type User struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
-	Email     string    `json:"email" gorm:"unique;not null"`
-	Password  string    `json:"-" gorm:"not null"`
-	Name      string    `json:"name" gorm:"not null"`
-	Role      string    `json:"role" gorm:"not null;default:'user'"`
-	Active    bool      `json:"active" gorm:"not null;default:true"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                   uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	Email                string     `json:"email" gorm:"unique;not null"`
+	Password             string     `json:"-" gorm:"not null"`
+	Name                 string     `json:"name" gorm:"not null"`
+	Roles                []string   `json:"roles" gorm:"-"`
+	Active               bool       `json:"active" gorm:"not null;default:true"`
+	EmailVerified        bool       `json:"email_verified" gorm:"not null;default:false"`
+	VerifiedAt           *time.Time `json:"verified_at,omitempty"`
+	FailedLogins         int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil          *time.Time `json:"-"`
+	ActiveOrganizationID *uuid.UUID `json:"active_organization_id,omitempty" gorm:"type:uuid"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+	// DeletedAt marks a soft-deleted user. Deleted users are hidden from
+	// queries and keep their email address until they are purged.
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 func NewUser(email, password, name string) (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := passwordHasher.Hash(password)
 	if err != nil {
 		return nil, err
 	}
@@ -27,9 +61,9 @@ func NewUser(email, password, name string) (*User, error) {
 	return &User{
 		ID:        uuid.New(),
 		Email:     email,
-		Password:  string(hashedPassword),
+		Password:  hashedPassword,
 		Name:      name,
-		Role:      "user",
+		Roles:     []string{RoleUser},
 		Active:    true,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -37,16 +71,22 @@ func NewUser(email, password, name string) (*User, error) {
 }
 
 func (u *User) ComparePassword(password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
+	return passwordHasher.Verify(u.Password, password)
+}
+
+// PasswordNeedsRehash reports whether the stored hash was made with another
+// algorithm or weaker parameters than the current ones
+func (u *User) PasswordNeedsRehash() bool {
+	return passwordHasher.NeedsRehash(u.Password)
 }
 
 func (u *User) UpdatePassword(password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := passwordHasher.Hash(password)
 	if err != nil {
 		return err
 	}
 
-	u.Password = string(hashedPassword)
+	u.Password = hashedPassword
 	u.UpdatedAt = time.Now()
 	return nil
 }
@@ -56,12 +96,48 @@ func (u *User) Update(name string) {
 	u.UpdatedAt = time.Now()
 }
 
-func (u *User) SetRole(role string) {
-	u.Role = role
+func (u *User) SetRoles(roles []string) {
+	u.Roles = roles
+	u.UpdatedAt = time.Now()
+}
+
+// SetActiveOrganization selects the organization that scopes the user's
+// tokens; nil leaves the user without a tenant
+func (u *User) SetActiveOrganization(id *uuid.UUID) {
+	u.ActiveOrganizationID = id
 	u.UpdatedAt = time.Now()
 }
 
 func (u *User) SetActive(active bool) {
 	u.Active = active
 	u.UpdatedAt = time.Now()
+}
+
+// IsLocked reports whether logins are currently refused for the user
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
+// RecordFailedLogin sets the failed login count stored for the user and
+// refuses further attempts until the given time. A zero time, or one before
+// the current lock ends, leaves the lock as it is.
+func (u *User) RecordFailedLogin(attempts int, lockedUntil time.Time) {
+	u.FailedLogins = attempts
+	if !lockedUntil.IsZero() && (u.LockedUntil == nil || u.LockedUntil.Before(lockedUntil)) {
+		u.LockedUntil = &lockedUntil
+	}
+	u.UpdatedAt = time.Now()
+}
+
+func (u *User) ResetFailedLogins() {
+	u.FailedLogins = 0
+	u.LockedUntil = nil
+	u.UpdatedAt = time.Now()
+}
+
+func (u *User) MarkEmailVerified() {
+	now := time.Now()
+	u.EmailVerified = true
+	u.VerifiedAt = &now
+	u.UpdatedAt = now
 }
\ No newline at end of file
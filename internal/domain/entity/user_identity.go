@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links a user to an account at an external identity provider.
+// The pair of issuer and subject is the provider's stable identifier; the
+// email is informational and may change.
+type UserIdentity struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Issuer      string     `json:"issuer" gorm:"not null;uniqueIndex:idx_identity_subject"`
+	Subject     string     `json:"subject" gorm:"not null;uniqueIndex:idx_identity_subject"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func NewUserIdentity(userID uuid.UUID, issuer, subject, email string) *UserIdentity {
+	return &UserIdentity{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Issuer:    issuer,
+		Subject:   subject,
+		Email:     email,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (i *UserIdentity) RecordLogin(email string) {
+	now := time.Now()
+	i.Email = email
+	i.LastLoginAt = &now
+	i.UpdatedAt = now
+}
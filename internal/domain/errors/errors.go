@@ -15,6 +15,10 @@ var (
 	ErrInvalidToken      = errors.New("invalid token")
 	ErrTokenExpired      = errors.New("token expired")
 	ErrInvalidCredential = errors.New("invalid credentials")
+	ErrTokenRevoked      = errors.New("token revoked")
+	ErrTokenReused       = errors.New("refresh token reuse detected")
+	ErrTooManyRequests   = errors.New("too many requests")
+	ErrSessionNotFound   = errors.New("session not found")
 
 	// User specific errors
 	ErrUserNotFound      = errors.New("user not found")
@@ -22,8 +26,69 @@ var (
 	ErrInvalidPassword   = errors.New("invalid password")
 	ErrInvalidEmail      = errors.New("invalid email")
 	ErrInvalidRole       = errors.New("invalid role")
+	ErrEmailNotVerified  = errors.New("email address is not verified")
+	ErrAccountLocked     = errors.New("account is temporarily locked")
+	ErrWeakPassword      = errors.New("password does not meet the password policy")
+
+	// Role specific errors
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleAlreadyExists = errors.New("role already exists")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrRoleProtected     = errors.New("built-in role cannot be changed")
+	ErrInvalidPermission = errors.New("invalid permission")
+
+	// Organization specific errors
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrNotMember            = errors.New("user is not a member of the organization")
+	ErrAlreadyMember        = errors.New("user is already a member of the organization")
+	ErrLastOwner            = errors.New("organization must keep at least one owner")
+
+	// Invitation specific errors
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrSignupDisabled     = errors.New("registration is by invitation only")
+
+	// Impersonation specific errors
+	ErrImpersonating    = errors.New("not allowed while impersonating a user")
+	ErrNotImpersonating = errors.New("token is not an impersonation token")
+
+	// OAuth and scope specific errors
+	ErrClientNotFound       = errors.New("client not found")
+	ErrInvalidClient        = errors.New("invalid client credentials")
+	ErrInvalidScope         = errors.New("requested scope is not allowed")
+	ErrInsufficientScope    = errors.New("insufficient scope")
+	ErrUnsupportedGrantType = errors.New("unsupported grant type")
+
+	// External login errors
+	ErrExternalLoginDisabled = errors.New("external login is not enabled")
+	ErrExternalLoginFailed   = errors.New("external login failed")
+	ErrMagicLinkDisabled     = errors.New("magic link login is not enabled")
+
+	// Multi-factor authentication errors
+	ErrMFARequired       = errors.New("multi-factor authentication required")
+	ErrMFANotEnrolled    = errors.New("multi-factor authentication is not enrolled")
+	ErrMFAAlreadyEnabled = errors.New("multi-factor authentication is already enabled")
+	ErrInvalidMFACode    = errors.New("invalid verification code")
+
+	// Signing key specific errors
+	ErrSigningKeyNotFound = errors.New("signing key not found")
+	ErrSigningKeyActive   = errors.New("the active signing key cannot be retired")
+	ErrSigningKeyExpired  = errors.New("signing key has expired")
 )
 
+// PasswordPolicyError lists every password policy rule a password breaks.
+// It matches ErrWeakPassword.
+type PasswordPolicyError struct {
+	Reasons []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return ErrWeakPassword.Error()
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
+
 // ErrorResponse represents the structure of error responses
 type ErrorResponse struct {
 	Code    int    `json:"code"`
@@ -37,3 +102,20 @@ func NewErrorResponse(code int, message string) *ErrorResponse {
 		Message: message,
 	}
 }
+
+// ValidationErrorResponse is an error response with the reasons each request
+// field was rejected
+type ValidationErrorResponse struct {
+	Code    int                 `json:"code"`
+	Message string              `json:"message"`
+	Fields  map[string][]string `json:"fields"`
+}
+
+// NewValidationErrorResponse creates a new validation error response
+func NewValidationErrorResponse(code int, message string, fields map[string][]string) *ValidationErrorResponse {
+	return &ValidationErrorResponse{
+		Code:    code,
+		Message: message,
+		Fields:  fields,
+	}
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *entity.APIKey) error
+	// FindByHash returns errors.ErrInvalidToken if no key has the hash
+	FindByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.APIKey, error)
+	// Revoke revokes a key of the user and returns errors.ErrNotFound if the
+	// user has no such active key
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+	// RevokeAllForUser revokes every active key of the user
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
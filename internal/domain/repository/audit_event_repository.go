@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type AuditEventRepository interface {
+	Create(ctx context.Context, event *entity.AuditEvent) error
+	// List returns events newest first with the total count
+	List(ctx context.Context, page, limit int) ([]*entity.AuditEvent, int64, error)
+}
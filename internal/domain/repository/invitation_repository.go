@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *entity.Invitation) error
+	FindByHash(ctx context.Context, tokenHash string) (*entity.Invitation, error)
+	// List returns invitations newest first
+	List(ctx context.Context) ([]*entity.Invitation, error)
+	// Accept accepts a pending invitation in one transaction with what it
+	// brings about: the user is created with its roles if newUser, or
+	// updated otherwise, and the membership, if not nil, is added. It fails
+	// with ErrInvalidToken if the invitation was accepted or revoked
+	// meanwhile, and nothing is saved when it fails.
+	Accept(ctx context.Context, id uuid.UUID, user *entity.User, newUser bool, membership *entity.Membership) error
+	Revoke(ctx context.Context, id uuid.UUID) error
+	// RevokePending revokes the unaccepted invitations sent to the address
+	RevokePending(ctx context.Context, email string) error
+}
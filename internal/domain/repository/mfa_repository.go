@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type MFARepository interface {
+	FindTOTPByUserID(ctx context.Context, userID uuid.UUID) (*entity.TOTPFactor, error)
+	SaveTOTP(ctx context.Context, factor *entity.TOTPFactor) error
+	// UseTOTPStep records step as the last accepted code and returns
+	// errors.ErrInvalidMFACode if it is not newer than the previous one
+	UseTOTPStep(ctx context.Context, factorID uuid.UUID, step int64) error
+	// DeleteByUserID removes the TOTP factor and all recovery codes of the user
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []*entity.RecoveryCode) error
+	// UseRecoveryCode consumes an unused code and returns
+	// errors.ErrInvalidMFACode if there is none
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error
+}
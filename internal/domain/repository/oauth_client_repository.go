@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type OAuthClientRepository interface {
+	Create(ctx context.Context, client *entity.OAuthClient) error
+	// FindByID returns errors.ErrClientNotFound if there is no such client
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.OAuthClient, error)
+	List(ctx context.Context) ([]*entity.OAuthClient, error)
+	// Delete returns errors.ErrClientNotFound if there is no such client
+	Delete(ctx context.Context, id uuid.UUID) error
+}
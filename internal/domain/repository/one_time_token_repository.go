@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type OneTimeTokenRepository interface {
+	Create(ctx context.Context, token *entity.OneTimeToken) error
+	FindByHash(ctx context.Context, purpose, tokenHash string) (*entity.OneTimeToken, error)
+	// FindLatest returns the most recently issued token of the user for the
+	// purpose, used or not
+	FindLatest(ctx context.Context, userID uuid.UUID, purpose string) (*entity.OneTimeToken, error)
+	// MarkUsed atomically consumes the token and returns errors.ErrInvalidToken
+	// if it had already been used
+	MarkUsed(ctx context.Context, id uuid.UUID) error
+	// InvalidateForUser consumes every outstanding token of the user for the purpose
+	InvalidateForUser(ctx context.Context, userID uuid.UUID, purpose string) error
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type OrganizationRepository interface {
+	// Create stores the organization together with its first membership
+	Create(ctx context.Context, organization *entity.Organization, owner *entity.Membership) error
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.Organization, error)
+	AddMember(ctx context.Context, membership *entity.Membership) error
+	RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error
+	FindMembership(ctx context.Context, organizationID, userID uuid.UUID) (*entity.Membership, error)
+	// ListByUser returns the user's memberships with their organizations
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*entity.Membership, error)
+	// ListMembers returns the organization's memberships with their users
+	ListMembers(ctx context.Context, organizationID uuid.UUID) ([]*entity.Membership, error)
+	CountByRole(ctx context.Context, organizationID uuid.UUID, role string) (int64, error)
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type PasswordHistoryRepository interface {
+	Create(ctx context.Context, entry *entity.PasswordHistory) error
+	// ListRecent returns up to limit entries of the user, newest first
+	ListRecent(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.PasswordHistory, error)
+	// Prune deletes all but the newest keep entries of the user
+	Prune(ctx context.Context, userID uuid.UUID, keep int) error
+}
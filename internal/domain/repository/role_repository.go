@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type RoleRepository interface {
+	Create(ctx context.Context, role *entity.Role) error
+	// Update saves the role and replaces its permissions
+	Update(ctx context.Context, role *entity.Role) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.Role, error)
+	FindByName(ctx context.Context, name string) (*entity.Role, error)
+	List(ctx context.Context) ([]*entity.Role, error)
+	// SavePermissions creates or updates the given permissions
+	SavePermissions(ctx context.Context, permissions []*entity.Permission) error
+	ListPermissions(ctx context.Context) ([]*entity.Permission, error)
+}
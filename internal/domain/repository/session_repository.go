@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *entity.Session) error
+	// FindByID returns errors.ErrSessionNotFound if there is no such session
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.Session, error)
+	// ListActiveByUser returns the sessions that are neither revoked nor
+	// expired, most recently seen first
+	ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]*entity.Session, error)
+	// Touch records activity; a zero expiresAt leaves the expiry unchanged
+	Touch(ctx context.Context, id uuid.UUID, seenAt, expiresAt time.Time) error
+	// Revoke revokes an active session of the user and returns
+	// errors.ErrSessionNotFound if the user has no such session
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type SigningKeyRepository interface {
+	Create(ctx context.Context, key *entity.SigningKey) error
+	FindByID(ctx context.Context, id string) (*entity.SigningKey, error)
+	List(ctx context.Context) ([]*entity.SigningKey, error)
+	// SaveAll persists the given keys in a single transaction
+	SaveAll(ctx context.Context, keys ...*entity.SigningKey) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type TokenRevocationRepository interface {
+	RevokeToken(ctx context.Context, token *entity.RevokedToken) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID, before time.Time) error
+	// UserTokensRevokedBefore returns the zero time if the user has no revocation
+	UserTokensRevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error)
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+)
+
+type UserIdentityRepository interface {
+	Create(ctx context.Context, identity *entity.UserIdentity) error
+	Update(ctx context.Context, identity *entity.UserIdentity) error
+	// FindBySubject returns errors.ErrNotFound if the identity is not linked to any user
+	FindBySubject(ctx context.Context, issuer, subject string) (*entity.UserIdentity, error)
+}
@@ -0,0 +1,39 @@
+package repository
+
+import "time"
+
+// UserSortFields are the fields users can be sorted by. Each names a column
+// of the users table.
+var UserSortFields = map[string]bool{
+	"name":       true,
+	"email":      true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// SortOrder orders results by one field
+type SortOrder struct {
+	Field string
+	Desc  bool
+}
+
+// UserQuery selects a page of users. Filters left at their zero value match
+// every user.
+type UserQuery struct {
+	Page  int
+	Limit int
+	// Search matches users whose name or email address contain every word
+	Search string
+	// Role matches users that hold the role, among others
+	Role   string
+	Active *bool
+	// CreatedAfter and CreatedBefore bound the creation time, inclusive
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// EmailDomain matches the part of the email address after the @
+	EmailDomain string
+	// Sort lists the orders to apply, most significant first, using the
+	// fields in UserSortFields. Without it search results are ordered by
+	// relevance and other lists by creation time.
+	Sort []SortOrder
+}
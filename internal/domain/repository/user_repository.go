@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
@@ -10,8 +11,31 @@ import (
 type UserRepository interface {
 	Create(ctx context.Context, user *entity.User) error
 	Update(ctx context.Context, user *entity.User) error
+	// Delete soft-deletes the user; the row is kept until it is purged
 	Delete(ctx context.Context, id uuid.UUID) error
 	FindByID(ctx context.Context, id uuid.UUID) (*entity.User, error)
 	FindByEmail(ctx context.Context, email string) (*entity.User, error)
-	List(ctx context.Context, page, limit int) ([]*entity.User, int64, error)
+	// List returns the page of users the query selects with the total count
+	// of matches. It returns errors.ErrInvalidInput for unknown sort fields.
+	List(ctx context.Context, query UserQuery) ([]*entity.User, int64, error)
+	// SetRoles replaces the roles of the user. It returns
+	// errors.ErrInvalidRole if a role does not exist.
+	SetRoles(ctx context.Context, id uuid.UUID, roles []string) error
+	// CountByRole counts the users that hold the role
+	CountByRole(ctx context.Context, role string) (int64, error)
+	// IncrementFailedLogins atomically counts a failed login and returns the
+	// new count, so that parallel attempts are all counted
+	IncrementFailedLogins(ctx context.Context, id uuid.UUID) (int, error)
+	// ExtendLock refuses logins until the given time unless the user is
+	// already locked for longer
+	ExtendLock(ctx context.Context, id uuid.UUID, until time.Time) error
+	// ResetFailedLogins clears the failed login count and any lock
+	ResetFailedLogins(ctx context.Context, id uuid.UUID) error
+	// ListDeleted returns soft-deleted users, most recently deleted first
+	ListDeleted(ctx context.Context, page, limit int) ([]*entity.User, int64, error)
+	// Restore undeletes a user. It returns errors.ErrUserNotFound unless the
+	// user is soft-deleted.
+	Restore(ctx context.Context, id uuid.UUID) error
+	// Purge permanently removes the users deleted before the cutoff
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
@@ -0,0 +1,39 @@
+// Package tenant carries the active organization through a request so that
+// repositories can restrict queries to it
+package tenant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type contextKey struct{}
+
+type scope struct {
+	id       uuid.UUID
+	unscoped bool
+}
+
+// WithID scopes data access in ctx to the organization
+func WithID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{id: id})
+}
+
+// Unscoped lifts tenant scoping for lookups that are global by nature, such
+// as checking that an email address is unused. Callers are responsible for
+// not exposing the results across tenants.
+func Unscoped(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{unscoped: true})
+}
+
+// FromContext returns the organization that scopes ctx. It reports false
+// when ctx is not scoped, as for unauthenticated requests, users without an
+// organization and internal lookups.
+func FromContext(ctx context.Context) (uuid.UUID, bool) {
+	s, ok := ctx.Value(contextKey{}).(scope)
+	if !ok || s.unscoped {
+		return uuid.Nil, false
+	}
+	return s.id, true
+}
@@ -0,0 +1,94 @@
+// Package breach looks up passwords in a local copy of a breached password
+// list
+package breach
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+)
+
+// PrefixLength is the number of hex characters of the SHA-1 hash that
+// select a range, as in the Pwned Passwords range API
+const PrefixLength = 5
+
+// List holds breached password hashes grouped by hash prefix. It reads the
+// Pwned Passwords download format: one upper or lower case hex SHA-1 hash per
+// line, optionally followed by ":<count>". The whole list is kept in memory,
+// so it suits curated lists of common and leaked passwords rather than the
+// complete corpus.
+type List struct {
+	ranges map[string][]string
+}
+
+// NewList loads the file named by PasswordConfig.BreachedListPath. Without a
+// path the list is empty and every lookup misses.
+func NewList(cfg *config.Config) (*List, error) {
+	if cfg.Password.BreachedListPath == "" {
+		return &List{ranges: map[string][]string{}}, nil
+	}
+
+	f, err := os.Open(cfg.Password.BreachedListPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+// Load reads a list in the Pwned Passwords format
+func Load(r io.Reader) (*List, error) {
+	ranges := make(map[string][]string)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(entry, ":")
+		hash = strings.ToUpper(hash)
+		if !isSHA1Hex(hash) {
+			return nil, fmt.Errorf("breached password list line %d: not a SHA-1 hash", line)
+		}
+
+		prefix := hash[:PrefixLength]
+		ranges[prefix] = append(ranges[prefix], hash[PrefixLength:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, suffixes := range ranges {
+		sort.Strings(suffixes)
+	}
+
+	return &List{ranges: ranges}, nil
+}
+
+// Range returns the upper case hash suffixes that follow the prefix. Only the
+// prefix is needed, so a remote implementation of the same lookup learns
+// nothing that identifies the password.
+func (l *List) Range(ctx context.Context, prefix string) ([]string, error) {
+	return l.ranges[strings.ToUpper(prefix)], nil
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+	return true
+}
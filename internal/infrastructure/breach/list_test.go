@@ -0,0 +1,57 @@
+package breach
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	list, err := Load(strings.NewReader(
+		"# SHA-1 of P@ssw0rd and password\n" +
+			"21BD12DC183F740EE76F27B78EB39C8AD972A757:52579\n" +
+			"\n" +
+			"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8\n",
+	))
+	require.NoError(t, err)
+
+	suffixes, err := list.Range(ctx, "21BD1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2DC183F740EE76F27B78EB39C8AD972A757"}, suffixes)
+
+	suffixes, _ = list.Range(ctx, "5baa6")
+	assert.Equal(t, []string{"1E4C9B93F3F0682250B6CF8331B7EE68FD8"}, suffixes)
+
+	suffixes, _ = list.Range(ctx, "00000")
+	assert.Empty(t, suffixes)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := Load(strings.NewReader("21BD12DC183F740EE76F27B78EB39C8AD972A757\nnot-a-hash\n"))
+	assert.EqualError(t, err, "breached password list line 2: not a SHA-1 hash")
+}
+
+func TestNewList(t *testing.T) {
+	cfg := &config.Config{}
+	list, err := NewList(cfg)
+	require.NoError(t, err)
+	suffixes, _ := list.Range(context.Background(), "21BD1")
+	assert.Empty(t, suffixes)
+
+	cfg.Password.BreachedListPath = filepath.Join(t.TempDir(), "breached.txt")
+	_, err = NewList(cfg)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(cfg.Password.BreachedListPath, []byte("21BD12DC183F740EE76F27B78EB39C8AD972A757\n"), 0o600))
+	list, err = NewList(cfg)
+	require.NoError(t, err)
+	suffixes, _ = list.Range(context.Background(), "21BD1")
+	assert.Len(t, suffixes, 1)
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+)
+
+// Cache is a key/value store with per-entry expiration
+type Cache interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, ttl time.Duration)
+	Delete(key string)
+	// Increment atomically adds one to an integer counter and returns the new
+	// value. A missing or expired counter starts at one and expires after ttl.
+	Increment(key string, ttl time.Duration) int
+}
+
+type item struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+type memoryCache struct {
+	mu         sync.RWMutex
+	items      map[string]item
+	defaultTTL time.Duration
+}
+
+// NewMemoryCache creates an in-process cache and starts a janitor that evicts
+// expired entries every CacheConfig.CleanupInterval
+func NewMemoryCache(cfg *config.Config) Cache {
+	c := &memoryCache{
+		items:      make(map[string]item),
+		defaultTTL: cfg.Cache.DefaultExpiration,
+	}
+
+	if cfg.Cache.CleanupInterval > 0 {
+		go c.janitor(cfg.Cache.CleanupInterval)
+	}
+
+	return c
+}
+
+func (c *memoryCache) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	it, ok := c.items[key]
+	if !ok || time.Now().After(it.expiresAt) {
+		return nil, false
+	}
+	return it.value, true
+}
+
+// Set stores the value for ttl, or for the default expiration when ttl is zero
+func (c *memoryCache) Set(key string, value interface{}, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = c.defaultTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items[key] = item{
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+	}
+}
+
+func (c *memoryCache) Increment(key string, ttl time.Duration) int {
+	if ttl <= 0 {
+		ttl = c.defaultTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, ok := c.items[key]
+	n, isInt := it.value.(int)
+	if !ok || !isInt || time.Now().After(it.expiresAt) {
+		c.items[key] = item{value: 1, expiresAt: time.Now().Add(ttl)}
+		return 1
+	}
+
+	// The window is fixed by the first increment
+	it.value = n + 1
+	c.items[key] = it
+	return n + 1
+}
+
+func (c *memoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+}
+
+func (c *memoryCache) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.deleteExpired()
+	}
+}
+
+func (c *memoryCache) deleteExpired() {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, it := range c.items {
+		if now.After(it.expiresAt) {
+			delete(c.items, key)
+		}
+	}
+}
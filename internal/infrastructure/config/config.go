@@ -9,13 +9,27 @@ import (
 
 type Config struct {
 	Environment string `env:"ENV" envDefault:"development"`
+	App         AppConfig
 	Server      ServerConfig
 	Database    DatabaseConfig
 	JWT         JWTConfig
+	Auth        AuthConfig
+	Password    PasswordConfig
+	MFA         MFAConfig
+	Mail        MailConfig
+	Lockout     LockoutConfig
+	Retention   RetentionConfig
+	OIDC        OIDCConfig
+	Policy      PolicyConfig
 	Cache       CacheConfig
 	Cors        CorsConfig
 }
 
+type AppConfig struct {
+	// PublicURL is the base URL of the client application used in emailed links
+	PublicURL string `env:"APP_PUBLIC_URL" envDefault:"http://localhost:3000"`
+}
+
 type ServerConfig struct {
 	Port         int           `env:"SERVER_PORT" envDefault:"8080"`
 	ReadTimeout  time.Duration `env:"SERVER_READ_TIMEOUT" envDefault:"10s"`
@@ -32,6 +46,132 @@ type JWTConfig struct {
 	ExpirationHours  time.Duration `env:"JWT_EXPIRATION_HOURS" envDefault:"24h"`
 	RefreshDuration  time.Duration `env:"JWT_REFRESH_DURATION" envDefault:"168h"`
 	SigningAlgorithm string        `env:"JWT_SIGNING_ALGORITHM" envDefault:"HS256"`
+	PrivateKeyPath   string        `env:"JWT_PRIVATE_KEY_PATH"`
+	KeyID            string        `env:"JWT_KEY_ID"`
+	// KeyEncryptionKey encrypts the signing keys stored in the database. It
+	// cannot be changed without losing them.
+	KeyEncryptionKey string `env:"JWT_KEY_ENCRYPTION_KEY"`
+	// KeyGracePeriod is how long a rotated-out key keeps verifying tokens; it
+	// should be at least the access token lifetime
+	KeyGracePeriod time.Duration `env:"JWT_KEY_GRACE_PERIOD" envDefault:"24h"`
+	// KeyReloadInterval is how often the signing keys are reloaded from the
+	// database to pick up rotations made through other instances; a new key
+	// should be promoted no sooner. Zero disables reloading.
+	KeyReloadInterval time.Duration `env:"JWT_KEY_RELOAD_INTERVAL" envDefault:"1m"`
+	// ImpersonationDuration is the lifetime of tokens issued to act as
+	// another user; they cannot be refreshed
+	ImpersonationDuration time.Duration `env:"JWT_IMPERSONATION_DURATION" envDefault:"15m"`
+	// ClientTokenDuration is the lifetime of tokens issued to OAuth clients
+	ClientTokenDuration time.Duration `env:"JWT_CLIENT_TOKEN_DURATION" envDefault:"1h"`
+}
+
+type AuthConfig struct {
+	PasswordResetTTL     time.Duration `env:"AUTH_PASSWORD_RESET_TTL" envDefault:"1h"`
+	EmailVerificationTTL time.Duration `env:"AUTH_EMAIL_VERIFICATION_TTL" envDefault:"24h"`
+	// VerificationResendInterval is the minimum time between two verification emails
+	VerificationResendInterval time.Duration `env:"AUTH_VERIFICATION_RESEND_INTERVAL" envDefault:"1m"`
+	// RequireVerifiedEmail makes login refuse accounts that have not verified their email
+	RequireVerifiedEmail bool `env:"AUTH_REQUIRE_VERIFIED_EMAIL" envDefault:"false"`
+	// InviteOnly disables public signup; accounts are created by accepting an invitation
+	InviteOnly    bool          `env:"AUTH_INVITE_ONLY" envDefault:"false"`
+	InvitationTTL time.Duration `env:"AUTH_INVITATION_TTL" envDefault:"168h"`
+	// MagicLinkEnabled allows passwordless login with a link sent by email
+	MagicLinkEnabled bool          `env:"AUTH_MAGIC_LINK_ENABLED" envDefault:"true"`
+	MagicLinkTTL     time.Duration `env:"AUTH_MAGIC_LINK_TTL" envDefault:"15m"`
+	// MagicLinkResendInterval is the minimum time between two login links to the same account
+	MagicLinkResendInterval time.Duration `env:"AUTH_MAGIC_LINK_RESEND_INTERVAL" envDefault:"1m"`
+}
+
+// PasswordConfig holds the password policy and selects how new password
+// hashes are made. Hashes of either algorithm are accepted; outdated ones are
+// replaced on the next login.
+type PasswordConfig struct {
+	MinLength int `env:"PASSWORD_MIN_LENGTH" envDefault:"8"`
+	// MaxLength bounds the work of hashing; zero means no limit
+	MaxLength     int  `env:"PASSWORD_MAX_LENGTH" envDefault:"128"`
+	RequireUpper  bool `env:"PASSWORD_REQUIRE_UPPER" envDefault:"false"`
+	RequireLower  bool `env:"PASSWORD_REQUIRE_LOWER" envDefault:"false"`
+	RequireDigit  bool `env:"PASSWORD_REQUIRE_DIGIT" envDefault:"false"`
+	RequireSymbol bool `env:"PASSWORD_REQUIRE_SYMBOL" envDefault:"false"`
+	// DisallowPersonalInfo refuses passwords containing the user's email or name
+	DisallowPersonalInfo bool `env:"PASSWORD_DISALLOW_PERSONAL_INFO" envDefault:"true"`
+	// HistorySize is the number of most recent passwords, including the
+	// current one, that cannot be reused; zero disables the check
+	HistorySize int `env:"PASSWORD_HISTORY_SIZE" envDefault:"5"`
+	// BreachedListPath is a file of SHA-1 hashes of breached passwords in the
+	// Pwned Passwords format; the check is skipped when empty
+	BreachedListPath string `env:"PASSWORD_BREACHED_LIST_PATH"`
+
+	// Hasher is "argon2id" or "bcrypt"
+	Hasher string `env:"PASSWORD_HASHER" envDefault:"argon2id"`
+	// Argon2Memory is in KiB
+	Argon2Memory      uint32 `env:"PASSWORD_ARGON2_MEMORY" envDefault:"65536"`
+	Argon2Iterations  uint32 `env:"PASSWORD_ARGON2_ITERATIONS" envDefault:"3"`
+	Argon2Parallelism uint8  `env:"PASSWORD_ARGON2_PARALLELISM" envDefault:"4"`
+	BcryptCost        int    `env:"PASSWORD_BCRYPT_COST" envDefault:"10"`
+}
+
+type MFAConfig struct {
+	// Issuer is the account label shown in authenticator apps
+	Issuer            string        `env:"MFA_ISSUER" envDefault:"Go API Boilerplate"`
+	ChallengeDuration time.Duration `env:"MFA_CHALLENGE_DURATION" envDefault:"5m"`
+	RequireForAdmins  bool          `env:"MFA_REQUIRE_FOR_ADMINS" envDefault:"true"`
+}
+
+type MailConfig struct {
+	// Driver is "log" to write messages to the application log or "file" to
+	// write them as .eml files into Dir
+	Driver string `env:"MAIL_DRIVER" envDefault:"log"`
+	From   string `env:"MAIL_FROM" envDefault:"no-reply@example.com"`
+	Dir    string `env:"MAIL_DIR" envDefault:"./data/mail"`
+	// Workers send the emails requested anonymously, such as reset links,
+	// after the request is answered; up to QueueSize wait for a worker and
+	// further ones are dropped
+	Workers   int `env:"MAIL_WORKERS" envDefault:"2"`
+	QueueSize int `env:"MAIL_QUEUE_SIZE" envDefault:"100"`
+}
+
+type LockoutConfig struct {
+	// MaxAttempts is the number of consecutive failed logins after which an
+	// account is locked for Duration
+	MaxAttempts int           `env:"LOCKOUT_MAX_ATTEMPTS" envDefault:"5"`
+	Duration    time.Duration `env:"LOCKOUT_DURATION" envDefault:"15m"`
+	// DelayBase is the wait imposed after the first failure; it doubles with
+	// every further failure until the account locks
+	DelayBase time.Duration `env:"LOCKOUT_DELAY_BASE" envDefault:"1s"`
+	// IPMaxAttempts failed logins from one IP within IPWindow block that IP
+	IPMaxAttempts int           `env:"LOCKOUT_IP_MAX_ATTEMPTS" envDefault:"20"`
+	IPWindow      time.Duration `env:"LOCKOUT_IP_WINDOW" envDefault:"15m"`
+}
+
+// RetentionConfig controls how long deleted users can be restored
+type RetentionConfig struct {
+	// DeletedUsers is how long a deleted user is kept before it is purged
+	DeletedUsers time.Duration `env:"RETENTION_DELETED_USERS" envDefault:"720h"`
+	// PurgeInterval is how often the purge job runs; zero disables it
+	PurgeInterval time.Duration `env:"RETENTION_PURGE_INTERVAL" envDefault:"1h"`
+}
+
+// OIDCConfig registers the application as a client of an OpenID Connect
+// provider for single sign-on
+type OIDCConfig struct {
+	Enabled      bool     `env:"OIDC_ENABLED" envDefault:"false"`
+	Issuer       string   `env:"OIDC_ISSUER"`
+	ClientID     string   `env:"OIDC_CLIENT_ID"`
+	ClientSecret string   `env:"OIDC_CLIENT_SECRET"`
+	RedirectURL  string   `env:"OIDC_REDIRECT_URL" envDefault:"http://localhost:8080/api/v1/auth/oidc/callback"`
+	Scopes       []string `env:"OIDC_SCOPES" envDefault:"openid,email,profile"`
+	// StateTTL bounds how long a user may take at the provider's login page
+	StateTTL time.Duration `env:"OIDC_STATE_TTL" envDefault:"10m"`
+	// AutoCreateUsers creates a local user on first login when no account
+	// matches the identity
+	AutoCreateUsers bool `env:"OIDC_AUTO_CREATE_USERS" envDefault:"true"`
+}
+
+// PolicyConfig points to the attribute-based authorization rules. Without a
+// file, role permissions alone decide access.
+type PolicyConfig struct {
+	Path string `env:"POLICY_PATH"`
 }
 
 type CacheConfig struct {
@@ -41,8 +181,8 @@ type CacheConfig struct {
 
 type CorsConfig struct {
 	AllowedOrigins   []string `env:"CORS_ALLOWED_ORIGINS" envDefault:"*"`
-	AllowedMethods   []string `env:"CORS_ALLOWED_METHODS" envDefault:"GET,POST,PUT,DELETE,OPTIONS"`
-	AllowedHeaders   []string `env:"CORS_ALLOWED_HEADERS" envDefault:"Accept,Authorization,Content-Type,X-CSRF-Token"`
+	AllowedMethods   []string `env:"CORS_ALLOWED_METHODS" envDefault:"GET,POST,PUT,PATCH,DELETE,OPTIONS"`
+	AllowedHeaders   []string `env:"CORS_ALLOWED_HEADERS" envDefault:"Accept,Authorization,Content-Type,X-CSRF-Token,X-API-Key"`
 	ExposedHeaders   []string `env:"CORS_EXPOSED_HEADERS" envDefault:"Link"`
 	AllowCredentials bool     `env:"CORS_ALLOW_CREDENTIALS" envDefault:"true"`
 	MaxAge           int      `env:"CORS_MAX_AGE" envDefault:"300"`
@@ -55,6 +195,9 @@ func Load() (*Config, error) {
 	}
 
 	config := &Config{
+		App: AppConfig{
+			PublicURL: "http://localhost:3000",
+		},
 		Server: ServerConfig{
 			Port:         8080,
 			ReadTimeout:  10 * time.Second,
@@ -65,10 +208,66 @@ func Load() (*Config, error) {
 			Path: "./data.db",
 		},
 		JWT: JWTConfig{
-			Secret:           "your-secret-key",
-			ExpirationHours:  24 * time.Hour,
-			RefreshDuration:  168 * time.Hour,
-			SigningAlgorithm: "HS256",
+			Secret:                "your-secret-key",
+			ExpirationHours:       24 * time.Hour,
+			RefreshDuration:       168 * time.Hour,
+			SigningAlgorithm:      "HS256",
+			KeyGracePeriod:        24 * time.Hour,
+			KeyReloadInterval:     time.Minute,
+			ImpersonationDuration: 15 * time.Minute,
+			ClientTokenDuration:   time.Hour,
+		},
+		Auth: AuthConfig{
+			PasswordResetTTL:           time.Hour,
+			EmailVerificationTTL:       24 * time.Hour,
+			VerificationResendInterval: time.Minute,
+			RequireVerifiedEmail:       false,
+			InviteOnly:                 false,
+			InvitationTTL:              7 * 24 * time.Hour,
+			MagicLinkEnabled:           true,
+			MagicLinkTTL:               15 * time.Minute,
+			MagicLinkResendInterval:    time.Minute,
+		},
+		Password: PasswordConfig{
+			MinLength:            8,
+			MaxLength:            128,
+			DisallowPersonalInfo: true,
+			HistorySize:          5,
+			Hasher:               "argon2id",
+			Argon2Memory:         64 * 1024,
+			Argon2Iterations:     3,
+			Argon2Parallelism:    4,
+			BcryptCost:           10,
+		},
+		MFA: MFAConfig{
+			Issuer:            "Go API Boilerplate",
+			ChallengeDuration: 5 * time.Minute,
+			RequireForAdmins:  true,
+		},
+		Mail: MailConfig{
+			Driver:    "log",
+			From:      "no-reply@example.com",
+			Dir:       "./data/mail",
+			Workers:   2,
+			QueueSize: 100,
+		},
+		Lockout: LockoutConfig{
+			MaxAttempts:   5,
+			Duration:      15 * time.Minute,
+			DelayBase:     time.Second,
+			IPMaxAttempts: 20,
+			IPWindow:      15 * time.Minute,
+		},
+		Retention: RetentionConfig{
+			DeletedUsers:  30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		OIDC: OIDCConfig{
+			Enabled:         false,
+			RedirectURL:     "http://localhost:8080/api/v1/auth/oidc/callback",
+			Scopes:          []string{"openid", "email", "profile"},
+			StateTTL:        10 * time.Minute,
+			AutoCreateUsers: true,
 		},
 		Cache: CacheConfig{
 			DefaultExpiration: 5 * time.Minute,
@@ -76,8 +275,8 @@ func Load() (*Config, error) {
 		},
 		Cors: CorsConfig{
 			AllowedOrigins:   []string{"*"},
-			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key"},
 			ExposedHeaders:   []string{"Link"},
 			AllowCredentials: true,
 			MaxAge:           300,
@@ -1,13 +1,23 @@
 package container
 
 import (
+	"context"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/application/policy"
 	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
 	domainRepository "github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/breach"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/cache"
 	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
 	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/database"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/mailer"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/oidc"
 	infraRepository "github.com/mrfansi/go-api-boilerplate/internal/infrastructure/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/security"
 	"github.com/mrfansi/go-api-boilerplate/internal/interfaces/http/handler"
 	"github.com/mrfansi/go-api-boilerplate/internal/interfaces/http/middleware"
+	"github.com/mrfansi/go-api-boilerplate/pkg/password"
 	"go.uber.org/dig"
 	"gorm.io/gorm"
 )
@@ -30,25 +40,202 @@ func (c *Container) Configure(cfg *config.Config) error {
 		return err
 	}
 
+	// Configure password hashing; users hash their passwords themselves
+	hasherConfig := password.DefaultConfig()
+	hasherConfig.Algorithm = cfg.Password.Hasher
+	hasherConfig.Argon2id.Memory = cfg.Password.Argon2Memory
+	hasherConfig.Argon2id.Iterations = cfg.Password.Argon2Iterations
+	hasherConfig.Argon2id.Parallelism = cfg.Password.Argon2Parallelism
+	hasherConfig.BcryptCost = cfg.Password.BcryptCost
+	hasher, err := password.NewHasher(hasherConfig)
+	if err != nil {
+		return err
+	}
+	entity.SetPasswordHasher(hasher)
+
 	// Provide database
 	if err := c.container.Provide(database.NewSQLiteDB); err != nil {
 		return err
 	}
 
+	// Provide cache
+	if err := c.container.Provide(cache.NewMemoryCache); err != nil {
+		return err
+	}
+
+	// Provide mailer
+	if err := c.container.Provide(mailer.NewMailer); err != nil {
+		return err
+	}
+
+	// Provide OpenID Connect provider
+	if err := c.container.Provide(oidc.NewProvider); err != nil {
+		return err
+	}
+
+	// Provide breached password list
+	if err := c.container.Provide(func(cfg *config.Config) (service.BreachedPasswordLookup, error) {
+		return breach.NewList(cfg)
+	}); err != nil {
+		return err
+	}
+
 	// Provide repositories
 	if err := c.container.Provide(func(db *gorm.DB) domainRepository.UserRepository {
 		return infraRepository.NewUserRepository(db)
 	}); err != nil {
 		return err
 	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.RefreshTokenRepository {
+		return infraRepository.NewRefreshTokenRepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.SigningKeyRepository {
+		return infraRepository.NewSigningKeyRepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.MFARepository {
+		return infraRepository.NewMFARepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.UserIdentityRepository {
+		return infraRepository.NewUserIdentityRepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.OneTimeTokenRepository {
+		return infraRepository.NewOneTimeTokenRepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.RoleRepository {
+		return infraRepository.NewRoleRepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.APIKeyRepository {
+		return infraRepository.NewAPIKeyRepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.OrganizationRepository {
+		return infraRepository.NewOrganizationRepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.InvitationRepository {
+		return infraRepository.NewInvitationRepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.AuditEventRepository {
+		return infraRepository.NewAuditEventRepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.OAuthClientRepository {
+		return infraRepository.NewOAuthClientRepository(db)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB, c cache.Cache) domainRepository.TokenRevocationRepository {
+		return infraRepository.NewCachedTokenRevocationRepository(infraRepository.NewTokenRevocationRepository(db), c)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB, c cache.Cache) domainRepository.SessionRepository {
+		return infraRepository.NewCachedSessionRepository(infraRepository.NewSessionRepository(db), c)
+	}); err != nil {
+		return err
+	}
+	if err := c.container.Provide(func(db *gorm.DB) domainRepository.PasswordHistoryRepository {
+		return infraRepository.NewPasswordHistoryRepository(db)
+	}); err != nil {
+		return err
+	}
+
+	// Provide signing keys
+	if err := c.container.Provide(security.NewKeyEncrypter); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.LoadKeyRing); err != nil {
+		return err
+	}
+
+	// Provide authorization policy
+	if err := c.container.Provide(policy.NewEngine); err != nil {
+		return err
+	}
 
 	// Provide services
+	if err := c.container.Provide(service.NewMFAService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewLockoutService); err != nil {
+		return err
+	}
 	if err := c.container.Provide(service.NewAuthService); err != nil {
 		return err
 	}
+	if err := c.container.Provide(service.NewPasswordPolicy); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewEmailVerificationService); err != nil {
+		return err
+	}
 	if err := c.container.Provide(service.NewUserService); err != nil {
 		return err
 	}
+	if err := c.container.Provide(service.NewKeyService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewMailQueue); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewPasswordResetService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewOIDCService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewAPIKeyService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewRoleService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewOrganizationService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewInvitationService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewAuditService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewImpersonationService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewOAuthClientService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewSessionService); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewMagicLinkService); err != nil {
+		return err
+	}
+
+	// Provide background jobs
+	if err := c.container.Provide(service.NewUserPurgeJob); err != nil {
+		return err
+	}
+	if err := c.container.Provide(service.NewKeyReloadJob); err != nil {
+		return err
+	}
 
 	// Provide handlers
 	if err := c.container.Provide(handler.NewAuthHandler); err != nil {
@@ -57,11 +244,62 @@ func (c *Container) Configure(cfg *config.Config) error {
 	if err := c.container.Provide(handler.NewUserHandler); err != nil {
 		return err
 	}
+	if err := c.container.Provide(handler.NewKeyHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewMFAHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewPasswordHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewVerificationHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewLockoutHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewOIDCHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewAPIKeyHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewRoleHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewPolicyHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewOrganizationHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewInvitationHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewImpersonationHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewAuditHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewOAuthHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewSessionHandler); err != nil {
+		return err
+	}
+	if err := c.container.Provide(handler.NewMagicLinkHandler); err != nil {
+		return err
+	}
 
 	// Provide middleware
 	if err := c.container.Provide(middleware.NewAuthMiddleware); err != nil {
 		return err
 	}
+	if err := c.container.Provide(middleware.NewPolicyMiddleware); err != nil {
+		return err
+	}
 	if err := c.container.Provide(middleware.NewLoggerMiddleware); err != nil {
 		return err
 	}
@@ -69,7 +307,18 @@ func (c *Container) Configure(cfg *config.Config) error {
 		return err
 	}
 
-	return nil
+	// Start background jobs
+	return c.container.Invoke(func(purgeJob *service.UserPurgeJob, keyReloadJob *service.KeyReloadJob) {
+		purgeJob.Start(context.Background())
+		keyReloadJob.Start(context.Background())
+	})
+}
+
+// Shutdown waits, until ctx ends, for the emails still queued to be sent
+func (c *Container) Shutdown(ctx context.Context) error {
+	return c.container.Invoke(func(mailQueue *service.MailQueue) error {
+		return mailQueue.Shutdown(ctx)
+	})
 }
 
 func (c *Container) Resolve(constructor interface{}) error {
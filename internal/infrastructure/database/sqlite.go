@@ -14,6 +14,8 @@ import (
 func NewSQLiteDB(cfg *config.Config) (*gorm.DB, error) {
 	db, err := gorm.Open(sqlite.Open(cfg.Database.Path), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// Report constraint violations as gorm.ErrDuplicatedKey and friends
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -22,11 +24,26 @@ func NewSQLiteDB(cfg *config.Config) (*gorm.DB, error) {
 	// Enable foreign key constraints
 	db.Exec("PRAGMA foreign_keys = ON")
 
+	// Scope tenant data to the organization of the request
+	if err := registerTenantScope(db); err != nil {
+		return nil, fmt.Errorf("failed to register tenant scope: %w", err)
+	}
+
 	// Auto migrate the schema
 	if err := autoMigrate(db); err != nil {
 		return nil, fmt.Errorf("failed to auto migrate schema: %w", err)
 	}
 
+	// Give users with a single role column their role in user_roles
+	if err := migrateUserRoles(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate user roles: %w", err)
+	}
+
+	// Index users for full-text search
+	if err := migrateUserSearch(db); err != nil {
+		return nil, fmt.Errorf("failed to create user search index: %w", err)
+	}
+
 	return db, nil
 }
 
@@ -34,6 +51,25 @@ func NewSQLiteDB(cfg *config.Config) (*gorm.DB, error) {
 func autoMigrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&entity.User{},
+		&entity.RefreshToken{},
+		&entity.RevokedToken{},
+		&entity.UserTokenRevocation{},
+		&entity.SigningKey{},
+		&entity.TOTPFactor{},
+		&entity.RecoveryCode{},
+		&entity.OneTimeToken{},
+		&entity.UserIdentity{},
+		&entity.APIKey{},
+		&entity.Permission{},
+		&entity.Role{},
+		&entity.UserRole{},
+		&entity.Organization{},
+		&entity.Membership{},
+		&entity.Invitation{},
+		&entity.AuditEvent{},
+		&entity.OAuthClient{},
+		&entity.Session{},
+		&entity.PasswordHistory{},
 		// Add other entities here as they are created
 	)
 }
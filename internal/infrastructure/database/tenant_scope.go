@@ -0,0 +1,47 @@
+package database
+
+import (
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantScopedTables lists the tables whose rows are only visible within the
+// organization in the query context, with the condition that restricts them
+var tenantScopedTables = map[string]string{
+	"users":       "users.id IN (SELECT user_id FROM memberships WHERE organization_id = ?)",
+	"invitations": "invitations.organization_id = ?",
+}
+
+// registerTenantScope restricts queries, updates and deletes on tenant-scoped
+// tables to the organization carried by the statement context. Statements
+// without a tenant in their context are left untouched.
+func registerTenantScope(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("tenant:query", tenantScope); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("tenant:update", tenantScope); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register("tenant:delete", tenantScope)
+}
+
+func tenantScope(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+
+	condition, ok := tenantScopedTables[db.Statement.Schema.Table]
+	if !ok {
+		return
+	}
+
+	organizationID, ok := tenant.FromContext(db.Statement.Context)
+	if !ok {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Expr{SQL: condition, Vars: []interface{}{organizationID}},
+	}})
+}
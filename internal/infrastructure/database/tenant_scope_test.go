@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantScope(t *testing.T) {
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "test.db")}}
+	db, err := NewSQLiteDB(cfg)
+	require.NoError(t, err)
+
+	// Two organizations with one member each, and a user without any
+	acme, globex := entity.NewOrganization("Acme"), entity.NewOrganization("Globex")
+	alice, _ := entity.NewUser("alice@acme.test", "password123", "Alice")
+	bob, _ := entity.NewUser("bob@globex.test", "password123", "Bob")
+	carol, _ := entity.NewUser("carol@example.test", "password123", "Carol")
+	require.NoError(t, db.Create([]*entity.Organization{acme, globex}).Error)
+	require.NoError(t, db.Create([]*entity.User{alice, bob, carol}).Error)
+	require.NoError(t, db.Omit("Organization", "User").Create([]*entity.Membership{
+		entity.NewMembership(acme.ID, alice.ID, entity.OrgRoleOwner),
+		entity.NewMembership(globex.ID, bob.ID, entity.OrgRoleOwner),
+	}).Error)
+
+	acmeCtx := tenant.WithID(context.Background(), acme.ID)
+
+	t.Run("Query", func(t *testing.T) {
+		var users []*entity.User
+		require.NoError(t, db.WithContext(acmeCtx).Find(&users).Error)
+		require.Len(t, users, 1)
+		assert.Equal(t, alice.ID, users[0].ID)
+
+		var count int64
+		require.NoError(t, db.WithContext(acmeCtx).Model(&entity.User{}).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+
+		err := db.WithContext(acmeCtx).First(&entity.User{}, bob.ID).Error
+		assert.Error(t, err)
+	})
+
+	t.Run("Unscoped", func(t *testing.T) {
+		var count int64
+		require.NoError(t, db.WithContext(tenant.Unscoped(acmeCtx)).Model(&entity.User{}).Count(&count).Error)
+		assert.Equal(t, int64(3), count)
+
+		require.NoError(t, db.Model(&entity.User{}).Count(&count).Error)
+		assert.Equal(t, int64(3), count)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		result := db.WithContext(acmeCtx).Model(&entity.User{}).Where("id = ?", bob.ID).Update("name", "Mallory")
+		require.NoError(t, result.Error)
+		assert.Zero(t, result.RowsAffected)
+
+		var stored entity.User
+		require.NoError(t, db.First(&stored, bob.ID).Error)
+		assert.Equal(t, "Bob", stored.Name)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		result := db.WithContext(acmeCtx).Delete(&entity.User{}, carol.ID)
+		require.NoError(t, result.Error)
+		assert.Zero(t, result.RowsAffected)
+	})
+}
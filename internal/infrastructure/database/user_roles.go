@@ -0,0 +1,43 @@
+package database
+
+import (
+	"time"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+// migrateUserRoles moves the role column users had when they could hold a
+// single role into user_roles, then drops it. Roles named in the column
+// that were never created are created without permissions.
+func migrateUserRoles(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&entity.User{}, "role") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var names []string
+		if err := tx.Table("users").Distinct("role").Where("role NOT IN (SELECT name FROM roles)").Pluck("role", &names).Error; err != nil {
+			return err
+		}
+		for _, name := range names {
+			role := entity.NewRole(name, "", nil)
+			role.System = name == entity.RoleAdmin || name == entity.RoleUser
+			if err := tx.Create(role).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Exec(
+			"INSERT INTO user_roles (user_id, role_id, created_at) SELECT users.id, roles.id, ? FROM users JOIN roles ON roles.name = users.role",
+			time.Now(),
+		).Error
+		if err != nil {
+			return err
+		}
+
+		// Unlike the migrator, which copies the table, this keeps the
+		// triggers of the search index
+		return tx.Exec("ALTER TABLE users DROP COLUMN role").Error
+	})
+}
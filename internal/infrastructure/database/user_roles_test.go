@@ -0,0 +1,64 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateUserRoles(t *testing.T) {
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "test.db")}}
+	db, err := NewSQLiteDB(cfg)
+	require.NoError(t, err)
+
+	// The users table as it was while users had a single role
+	require.NoError(t, db.Exec("ALTER TABLE users ADD COLUMN role text NOT NULL DEFAULT 'user'").Error)
+	userRole := entity.NewRole(entity.RoleUser, "", nil)
+	userRole.System = true
+	require.NoError(t, db.Create(userRole).Error)
+
+	roles := map[string]string{
+		"jane@example.com": entity.RoleUser,
+		"john@example.com": entity.RoleAdmin,
+		"sam@example.com":  "support",
+	}
+	for email, role := range roles {
+		user, _ := entity.NewUser(email, "password123", "Migrated User")
+		require.NoError(t, db.Create(user).Error)
+		require.NoError(t, db.Exec("UPDATE users SET role = ? WHERE id = ?", role, user.ID).Error)
+	}
+
+	require.NoError(t, migrateUserRoles(db))
+
+	assert.False(t, db.Migrator().HasColumn(&entity.User{}, "role"))
+	for email, role := range roles {
+		var names []string
+		require.NoError(t, db.Table("user_roles").
+			Joins("JOIN roles ON roles.id = user_roles.role_id").
+			Joins("JOIN users ON users.id = user_roles.user_id").
+			Where("users.email = ?", email).
+			Pluck("roles.name", &names).Error)
+		assert.Equal(t, []string{role}, names, email)
+	}
+
+	// Roles missing from the roles table were created, the built-in ones as
+	// system roles
+	var admin, support entity.Role
+	require.NoError(t, db.Where("name = ?", entity.RoleAdmin).First(&admin).Error)
+	require.NoError(t, db.Where("name = ?", "support").First(&support).Error)
+	assert.True(t, admin.System)
+	assert.False(t, support.System)
+
+	t.Run("Reopen", func(t *testing.T) {
+		_, err := NewSQLiteDB(cfg)
+		assert.NoError(t, err)
+
+		var count int64
+		require.NoError(t, db.Model(&entity.UserRole{}).Count(&count).Error)
+		assert.Equal(t, int64(len(roles)), count)
+	})
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// UserSearchTable is the FTS5 index over user names and email addresses. It
+// only exists when the SQLite driver was built with the sqlite_fts5 tag.
+const UserSearchTable = "users_fts"
+
+// userSearchStatements create the index and the triggers that keep it in
+// step with the users table
+var userSearchStatements = []string{
+	`CREATE VIRTUAL TABLE users_fts USING fts5(
+		user_id UNINDEXED,
+		name,
+		email,
+		tokenize = 'unicode61 remove_diacritics 2'
+	)`,
+	`CREATE TRIGGER users_fts_insert AFTER INSERT ON users BEGIN
+		INSERT INTO users_fts (user_id, name, email) VALUES (new.id, new.name, new.email);
+	END`,
+	`CREATE TRIGGER users_fts_update AFTER UPDATE OF name, email ON users BEGIN
+		UPDATE users_fts SET name = new.name, email = new.email WHERE user_id = old.id;
+	END`,
+	`CREATE TRIGGER users_fts_delete AFTER DELETE ON users BEGIN
+		DELETE FROM users_fts WHERE user_id = old.id;
+	END`,
+	`INSERT INTO users_fts (user_id, name, email) SELECT id, name, email FROM users`,
+}
+
+// migrateUserSearch creates the full-text index of users once. Without FTS5
+// support it logs a warning and user search falls back to pattern matching.
+func migrateUserSearch(db *gorm.DB) error {
+	if db.Migrator().HasTable(UserSearchTable) {
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range userSearchStatements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && strings.Contains(err.Error(), "no such module: fts5") {
+		log.Warn().Msg("SQLite was built without FTS5; build with -tags sqlite_fts5 to index user search")
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserSearchIndex(t *testing.T) {
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "test.db")}}
+	db, err := NewSQLiteDB(cfg)
+	require.NoError(t, err)
+	if !db.Migrator().HasTable(UserSearchTable) {
+		t.Skip("SQLite was built without the sqlite_fts5 tag")
+	}
+
+	search := func(t *testing.T, match string) []string {
+		t.Helper()
+		var ids []string
+		require.NoError(t, db.Table(UserSearchTable).Where(UserSearchTable+" MATCH ?", match).Pluck("user_id", &ids).Error)
+		return ids
+	}
+
+	jose, _ := entity.NewUser("jose@acme.test", "password123", "José García")
+	require.NoError(t, db.Create(jose).Error)
+
+	t.Run("Insert", func(t *testing.T) {
+		assert.Equal(t, []string{jose.ID.String()}, search(t, `"jose"`))
+		assert.Equal(t, []string{jose.ID.String()}, search(t, `"garc"*`))
+		assert.Equal(t, []string{jose.ID.String()}, search(t, `"acme.test"`))
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		require.NoError(t, db.Model(jose).Update("name", "Joe Bloggs").Error)
+
+		assert.Empty(t, search(t, `"garcia"`))
+		assert.Equal(t, []string{jose.ID.String()}, search(t, `"bloggs"`))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, db.Unscoped().Delete(jose).Error)
+
+		assert.Empty(t, search(t, `"bloggs"`))
+	})
+
+	t.Run("Reopen", func(t *testing.T) {
+		_, err := NewSQLiteDB(cfg)
+		assert.NoError(t, err)
+	})
+}
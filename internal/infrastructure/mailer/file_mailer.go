@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// fileMailer writes every message as an .eml file into a directory, so local
+// setups and tests can pick up links without an SMTP server
+type fileMailer struct {
+	from string
+	dir  string
+	seq  atomic.Uint64
+}
+
+func NewFileMailer(from, dir string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	return &fileMailer{
+		from: from,
+		dir:  dir,
+	}, nil
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg *Message) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	// The sequence number keeps names unique and sortable within one process
+	name := fmt.Sprintf("%d-%06d.eml", time.Now().UnixNano(), m.seq.Add(1))
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(b.String()), 0o600)
+}
@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+// logMailer writes messages to the application log. It is meant for local
+// development only, since message bodies usually contain secrets.
+type logMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) Mailer {
+	return &logMailer{
+		from: from,
+	}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg *Message) error {
+	log.Info().
+		Str("from", m.from).
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Str("body", msg.Body).
+		Msg("Email sent")
+	return nil
+}
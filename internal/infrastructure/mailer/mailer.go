@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional email. Implementations must be safe for
+// concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewMailer returns the mailer selected by MailConfig.Driver
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "", "log":
+		return NewLogMailer(cfg.Mail.From), nil
+	case "file":
+		return NewFileMailer(cfg.Mail.From, cfg.Mail.Dir)
+	default:
+		return nil, fmt.Errorf("unsupported mail driver %q", cfg.Mail.Driver)
+	}
+}
@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Application metrics, registered with the default registry served at /metrics
+var (
+	LoginFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_login_failures_total",
+		Help: "Failed login attempts by reason.",
+	}, []string{"reason"})
+
+	AccountLockouts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "auth_account_lockouts_total",
+		Help: "Accounts locked after too many failed logins.",
+	})
+)
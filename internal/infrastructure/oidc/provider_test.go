@@ -0,0 +1,139 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/oidc"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://localhost:8080/api/v1/auth/oidc/callback"
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewProvider("client", "secret")
+	defer idp.Close()
+	idp.SetUser(oidctest.User{Subject: "alice", Email: "alice@example.com", EmailVerified: true, Name: "Alice"})
+
+	provider := oidc.New(idp.Config(redirectURL), http.DefaultClient)
+
+	verifier, err := oidc.NewCodeVerifier()
+	require.NoError(t, err)
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", oidc.CodeChallengeS256(verifier))
+	require.NoError(t, err)
+
+	u, _ := url.Parse(authURL)
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+	assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+
+	code, state, err := idp.Authorize(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "state-1", state)
+
+	t.Run("WrongVerifier", func(t *testing.T) {
+		other, _ := oidc.NewCodeVerifier()
+		_, err := provider.Exchange(ctx, code, other)
+		assert.Error(t, err)
+	})
+
+	// The failed attempt consumed the code
+	code, _, err = idp.Authorize(authURL)
+	require.NoError(t, err)
+
+	token, err := provider.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+
+	t.Run("NonceMismatch", func(t *testing.T) {
+		_, err := provider.VerifyIDToken(ctx, token.IDToken, "other-nonce")
+		assert.Error(t, err)
+	})
+
+	idToken, err := provider.VerifyIDToken(ctx, token.IDToken, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, idp.Issuer(), idToken.Issuer)
+	assert.Equal(t, "alice", idToken.Subject)
+	assert.Equal(t, "alice@example.com", idToken.Email)
+	assert.True(t, idToken.EmailVerified)
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewProvider("client", "secret")
+	defer idp.Close()
+
+	provider := oidc.New(idp.Config(redirectURL), http.DefaultClient)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   idp.Issuer(),
+			"sub":   "bob",
+			"aud":   "client",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": "n",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+	}{
+		{"WrongIssuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{"WrongAudience", func(c jwt.MapClaims) { c["aud"] = "other-client" }},
+		{"Expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"MissingSubject", func(c jwt.MapClaims) { delete(c, "sub") }},
+		{"ForeignAuthorizedParty", func(c jwt.MapClaims) {
+			c["aud"] = []string{"client", "other-client"}
+			c["azp"] = "other-client"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.mutate(claims)
+			raw, err := idp.SignIDToken(claims)
+			require.NoError(t, err)
+
+			_, err = provider.VerifyIDToken(ctx, raw, "n")
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("Unsigned", func(t *testing.T) {
+		raw, err := jwt.NewWithClaims(jwt.SigningMethodNone, valid()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, raw, "n")
+		assert.Error(t, err)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		raw, err := idp.SignIDToken(valid())
+		require.NoError(t, err)
+
+		idToken, err := provider.VerifyIDToken(ctx, raw, "n")
+		require.NoError(t, err)
+		assert.Equal(t, "bob", idToken.Subject)
+	})
+}
+
+func TestProvider_IssuerMismatch(t *testing.T) {
+	idp := oidctest.NewProvider("client", "secret")
+	defer idp.Close()
+
+	cfg := idp.Config(redirectURL)
+	cfg.Issuer = idp.Issuer() + "/"
+	provider := oidc.New(cfg, http.DefaultClient)
+
+	_, err := provider.AuthCodeURL(context.Background(), "s", "n", "c")
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type auditEventRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditEventRepository(db *gorm.DB) *auditEventRepository {
+	return &auditEventRepository{
+		db: db,
+	}
+}
+
+func (r *auditEventRepository) Create(ctx context.Context, event *entity.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *auditEventRepository) List(ctx context.Context, page, limit int) ([]*entity.AuditEvent, int64, error) {
+	var events []*entity.AuditEvent
+	var total int64
+
+	if err := r.db.WithContext(ctx).Model(&entity.AuditEvent{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Offset(offset).Limit(limit).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
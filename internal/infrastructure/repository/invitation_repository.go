@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	domainErrors "github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/database"
+	"gorm.io/gorm"
+)
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) *invitationRepository {
+	return &invitationRepository{
+		db: db,
+	}
+}
+
+func (r *invitationRepository) Create(ctx context.Context, invitation *entity.Invitation) error {
+	return r.db.WithContext(ctx).Create(invitation).Error
+}
+
+func (r *invitationRepository) FindByHash(ctx context.Context, tokenHash string) (*entity.Invitation, error) {
+	var invitation entity.Invitation
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domainErrors.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) List(ctx context.Context) ([]*entity.Invitation, error) {
+	var invitations []*entity.Invitation
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *invitationRepository) Accept(ctx context.Context, id uuid.UUID, user *entity.User, newUser bool, membership *entity.Membership) error {
+	return database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		if newUser {
+			if err := createUser(tx, user); err != nil {
+				return err
+			}
+		} else if err := updateUser(tx, user); err != nil {
+			return err
+		}
+
+		if membership != nil {
+			if err := addMember(tx, membership); err != nil {
+				return err
+			}
+		}
+
+		// Claimed last, so the invitation stays usable if anything above fails
+		result := tx.Model(&entity.Invitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+			Update("accepted_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domainErrors.ErrInvalidToken
+		}
+		return nil
+	})
+}
+
+func (r *invitationRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Model(&entity.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainErrors.ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (r *invitationRepository) RevokePending(ctx context.Context, email string) error {
+	return r.db.WithContext(ctx).
+		Model(&entity.Invitation{}).
+		Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", email).
+		Update("revoked_at", time.Now()).Error
+}
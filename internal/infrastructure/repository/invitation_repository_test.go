@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	domainErrors "github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationRepository_Accept(t *testing.T) {
+	ctx := tenant.Unscoped(context.Background())
+	db := newTestDB(t)
+	repo := NewInvitationRepository(db)
+	userRepo := NewUserRepository(db)
+	orgRepo := NewOrganizationRepository(db)
+	newTestRoles(t, db, entity.RoleUser, "support")
+
+	owner, _ := entity.NewUser("owner@example.com", "password123", "Owner")
+	require.NoError(t, userRepo.Create(ctx, owner))
+	org := entity.NewOrganization("Acme")
+	require.NoError(t, orgRepo.Create(ctx, org, entity.NewMembership(org.ID, owner.ID, entity.OrgRoleOwner)))
+
+	newInvitation := func(t *testing.T, email, role string) *entity.Invitation {
+		invitation := entity.NewInvitation(email, role, &org.ID, entity.OrgRoleMember, owner.ID, uuid.NewString(), time.Hour)
+		require.NoError(t, repo.Create(ctx, invitation))
+		return invitation
+	}
+	newInvitee := func(email, role string) *entity.User {
+		user, _ := entity.NewUser(email, "password123", "Invitee")
+		user.SetRoles([]string{role})
+		user.MarkEmailVerified()
+		return user
+	}
+	pending := func(t *testing.T, invitation *entity.Invitation) bool {
+		stored, err := repo.FindByHash(ctx, invitation.TokenHash)
+		require.NoError(t, err)
+		return stored.IsPending()
+	}
+
+	t.Run("NewUser", func(t *testing.T) {
+		invitation := newInvitation(t, "new@example.com", "support")
+		user := newInvitee(invitation.Email, "support")
+
+		err := repo.Accept(ctx, invitation.ID, user, true, entity.NewMembership(org.ID, user.ID, entity.OrgRoleMember))
+
+		require.NoError(t, err)
+		assert.False(t, pending(t, invitation))
+		stored, err := userRepo.FindByEmail(ctx, invitation.Email)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"support"}, stored.Roles)
+		_, err = orgRepo.FindMembership(ctx, org.ID, user.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ExistingUser", func(t *testing.T) {
+		invitation := newInvitation(t, "existing@example.com", "support")
+		user := newInvitee(invitation.Email, entity.RoleUser)
+		user.EmailVerified = false
+		require.NoError(t, userRepo.Create(ctx, user))
+		user.MarkEmailVerified()
+
+		require.NoError(t, repo.Accept(ctx, invitation.ID, user, false, nil))
+
+		stored, err := userRepo.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.EmailVerified)
+		assert.Equal(t, []string{entity.RoleUser}, stored.Roles)
+	})
+
+	t.Run("RollsBack", func(t *testing.T) {
+		invitation := newInvitation(t, "rollback@example.com", "support")
+		user := newInvitee(invitation.Email, "support")
+
+		// The owner is a member already, so adding the membership fails
+		// after the user was created
+		err := repo.Accept(ctx, invitation.ID, user, true, entity.NewMembership(org.ID, owner.ID, entity.OrgRoleMember))
+
+		assert.Equal(t, domainErrors.ErrAlreadyMember, err)
+		assert.True(t, pending(t, invitation))
+		_, err = userRepo.FindByEmail(ctx, invitation.Email)
+		assert.Equal(t, domainErrors.ErrUserNotFound, err)
+	})
+
+	t.Run("ClaimedMeanwhile", func(t *testing.T) {
+		invitation := newInvitation(t, "twice@example.com", entity.RoleUser)
+		require.NoError(t, repo.Accept(ctx, invitation.ID, newInvitee(invitation.Email, entity.RoleUser), true, nil))
+
+		second := newInvitee("other@example.com", entity.RoleUser)
+		err := repo.Accept(ctx, invitation.ID, second, true, nil)
+
+		assert.Equal(t, domainErrors.ErrInvalidToken, err)
+		_, err = userRepo.FindByEmail(ctx, second.Email)
+		assert.Equal(t, domainErrors.ErrUserNotFound, err)
+	})
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	domainErrors "github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/database"
+	"gorm.io/gorm"
+)
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *mfaRepository {
+	return &mfaRepository{
+		db: db,
+	}
+}
+
+func (r *mfaRepository) FindTOTPByUserID(ctx context.Context, userID uuid.UUID) (*entity.TOTPFactor, error) {
+	var factor entity.TOTPFactor
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&factor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domainErrors.ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	return &factor, nil
+}
+
+func (r *mfaRepository) SaveTOTP(ctx context.Context, factor *entity.TOTPFactor) error {
+	return r.db.WithContext(ctx).Save(factor).Error
+}
+
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, factorID uuid.UUID, step int64) error {
+	result := r.db.WithContext(ctx).
+		Model(&entity.TOTPFactor{}).
+		Where("id = ? AND last_used_step < ?", factorID, step).
+		Updates(map[string]interface{}{"last_used_step": step, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainErrors.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (r *mfaRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	return database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&entity.TOTPFactor{}).Error
+	})
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []*entity.RecoveryCode) error {
+	return database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(codes).Error
+	})
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	result := r.db.WithContext(ctx).
+		Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainErrors.ErrInvalidMFACode
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	domainErrors "github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"gorm.io/gorm"
+)
+
+type oauthClientRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthClientRepository(db *gorm.DB) *oauthClientRepository {
+	return &oauthClientRepository{
+		db: db,
+	}
+}
+
+func (r *oauthClientRepository) Create(ctx context.Context, client *entity.OAuthClient) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+func (r *oauthClientRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.OAuthClient, error) {
+	var client entity.OAuthClient
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domainErrors.ErrClientNotFound
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *oauthClientRepository) List(ctx context.Context) ([]*entity.OAuthClient, error) {
+	var clients []*entity.OAuthClient
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+func (r *oauthClientRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entity.OAuthClient{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainErrors.ErrClientNotFound
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	domainErrors "github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/database"
+	"gorm.io/gorm"
+)
+
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) *organizationRepository {
+	return &organizationRepository{
+		db: db,
+	}
+}
+
+func (r *organizationRepository) Create(ctx context.Context, organization *entity.Organization, owner *entity.Membership) error {
+	return database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		if err := tx.Create(organization).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Organization", "User").Create(owner).Error
+	})
+}
+
+func (r *organizationRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.Organization, error) {
+	var organization entity.Organization
+	if err := r.db.WithContext(ctx).First(&organization, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domainErrors.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return &organization, nil
+}
+
+func (r *organizationRepository) AddMember(ctx context.Context, membership *entity.Membership) error {
+	return addMember(r.db.WithContext(ctx), membership)
+}
+
+func (r *organizationRepository) RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		Delete(&entity.Membership{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainErrors.ErrNotMember
+	}
+	return nil
+}
+
+func (r *organizationRepository) FindMembership(ctx context.Context, organizationID, userID uuid.UUID) (*entity.Membership, error) {
+	var membership entity.Membership
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		First(&membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domainErrors.ErrNotMember
+		}
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func (r *organizationRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*entity.Membership, error) {
+	var memberships []*entity.Membership
+	err := r.db.WithContext(ctx).
+		Preload("Organization").
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+func (r *organizationRepository) ListMembers(ctx context.Context, organizationID uuid.UUID) ([]*entity.Membership, error) {
+	var memberships []*entity.Membership
+	// Scoped to the organization listed, which need not be the one scoping
+	// the request, so only its members' accounts are loaded
+	err := r.db.WithContext(tenant.WithID(ctx, organizationID)).
+		Preload("User").
+		Where("organization_id = ?", organizationID).
+		Order("created_at").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*entity.User, 0, len(memberships))
+	for _, membership := range memberships {
+		if membership.User != nil {
+			users = append(users, membership.User)
+		}
+	}
+	if err := withRoles(r.db.WithContext(ctx), users...); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+func (r *organizationRepository) CountByRole(ctx context.Context, organizationID uuid.UUID, role string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.Membership{}).
+		Where("organization_id = ? AND role = ?", organizationID, role).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func addMember(db *gorm.DB, membership *entity.Membership) error {
+	if err := db.Omit("Organization", "User").Create(membership).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return domainErrors.ErrAlreadyMember
+		}
+		return err
+	}
+	return nil
+}
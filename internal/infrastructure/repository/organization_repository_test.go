@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrganizationRepository_ListMembers(t *testing.T) {
+	ctx := tenant.Unscoped(context.Background())
+	db := newTestDB(t)
+	repo := NewOrganizationRepository(db)
+	userRepo := NewUserRepository(db)
+	newTestRoles(t, db, entity.RoleUser, entity.RoleAdmin, "support")
+
+	newUser := func(email string, roles ...string) *entity.User {
+		user, _ := entity.NewUser(email, "password123", "Member")
+		user.SetRoles(roles)
+		require.NoError(t, userRepo.Create(ctx, user))
+		return user
+	}
+	owner := newUser("owner@example.com", entity.RoleAdmin, "support")
+	member := newUser("member@example.com", entity.RoleUser)
+
+	acme := entity.NewOrganization("Acme")
+	require.NoError(t, repo.Create(ctx, acme, entity.NewMembership(acme.ID, owner.ID, entity.OrgRoleOwner)))
+	require.NoError(t, repo.AddMember(ctx, entity.NewMembership(acme.ID, member.ID, entity.OrgRoleMember)))
+	globex := entity.NewOrganization("Globex")
+	require.NoError(t, repo.Create(ctx, globex, entity.NewMembership(globex.ID, owner.ID, entity.OrgRoleOwner)))
+
+	// Listed while another organization scopes the request
+	memberships, err := repo.ListMembers(tenant.WithID(context.Background(), globex.ID), acme.ID)
+
+	require.NoError(t, err)
+	require.Len(t, memberships, 2)
+	require.NotNil(t, memberships[0].User)
+	assert.Equal(t, owner.Email, memberships[0].User.Email)
+	assert.Equal(t, []string{entity.RoleAdmin, "support"}, memberships[0].User.Roles)
+	require.NotNil(t, memberships[1].User)
+	assert.Equal(t, member.Email, memberships[1].User.Email)
+	assert.Equal(t, []string{entity.RoleUser}, memberships[1].User.Roles)
+}
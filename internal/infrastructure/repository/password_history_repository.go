@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) *passwordHistoryRepository {
+	return &passwordHistoryRepository{
+		db: db,
+	}
+}
+
+func (r *passwordHistoryRepository) Create(ctx context.Context, entry *entity.PasswordHistory) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *passwordHistoryRepository) ListRecent(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.PasswordHistory, error) {
+	var entries []*entity.PasswordHistory
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *passwordHistoryRepository) Prune(ctx context.Context, userID uuid.UUID, keep int) error {
+	newest := r.db.
+		Model(&entity.PasswordHistory{}).
+		Select("id").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(keep)
+
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND id NOT IN (?)", userID, newest).
+		Delete(&entity.PasswordHistory{}).Error
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	domainErrors "github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) *roleRepository {
+	return &roleRepository{
+		db: db,
+	}
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *entity.Role) error {
+	// Permissions must already exist; only the associations are written
+	return r.db.WithContext(ctx).Omit("Permissions.*").Create(role).Error
+}
+
+func (r *roleRepository) Update(ctx context.Context, role *entity.Role) error {
+	return database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions").Save(role).Error; err != nil {
+			return err
+		}
+		return tx.Model(role).Omit("Permissions.*").Association("Permissions").Replace(role.Permissions)
+	})
+}
+
+func (r *roleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		role := &entity.Role{ID: id}
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+
+		result := tx.Delete(role)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domainErrors.ErrRoleNotFound
+		}
+		return nil
+	})
+}
+
+func (r *roleRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.Role, error) {
+	return r.find(ctx, "id = ?", id)
+}
+
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*entity.Role, error) {
+	return r.find(ctx, "name = ?", name)
+}
+
+func (r *roleRepository) List(ctx context.Context) ([]*entity.Role, error) {
+	var roles []*entity.Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) SavePermissions(ctx context.Context, permissions []*entity.Permission) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(permissions).Error
+}
+
+func (r *roleRepository) ListPermissions(ctx context.Context) ([]*entity.Permission, error) {
+	var permissions []*entity.Permission
+	if err := r.db.WithContext(ctx).Order("name").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *roleRepository) find(ctx context.Context, query string, arg interface{}) (*entity.Role, error) {
+	var role entity.Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Where(query, arg).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domainErrors.ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	domainErrors "github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/database"
+	"gorm.io/gorm"
+)
+
+type signingKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) *signingKeyRepository {
+	return &signingKeyRepository{
+		db: db,
+	}
+}
+
+func (r *signingKeyRepository) Create(ctx context.Context, key *entity.SigningKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *signingKeyRepository) FindByID(ctx context.Context, id string) (*entity.SigningKey, error) {
+	var key entity.SigningKey
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domainErrors.ErrSigningKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *signingKeyRepository) List(ctx context.Context) ([]*entity.SigningKey, error) {
+	var keys []*entity.SigningKey
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *signingKeyRepository) SaveAll(ctx context.Context, keys ...*entity.SigningKey) error {
+	return database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		for _, key := range keys {
+			if err := tx.Save(key).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
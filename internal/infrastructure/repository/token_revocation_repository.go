@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	domainRepository "github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/cache"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type tokenRevocationRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRevocationRepository(db *gorm.DB) *tokenRevocationRepository {
+	return &tokenRevocationRepository{
+		db: db,
+	}
+}
+
+func (r *tokenRevocationRepository) RevokeToken(ctx context.Context, token *entity.RevokedToken) error {
+	// Entries are only useful until the token expires, so prune old ones here
+	if err := r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&entity.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+func (r *tokenRevocationRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *tokenRevocationRepository) RevokeUserTokens(ctx context.Context, userID uuid.UUID, before time.Time) error {
+	revocation := &entity.UserTokenRevocation{
+		UserID:        userID,
+		RevokedBefore: before,
+		UpdatedAt:     time.Now(),
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(revocation).Error
+}
+
+func (r *tokenRevocationRepository) UserTokensRevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	var revocation entity.UserTokenRevocation
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&revocation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return revocation.RevokedBefore, nil
+}
+
+// cachedTokenRevocationRepository answers lookups from memory, since they are
+// made on every authenticated request, and writes through to the wrapped repository
+type cachedTokenRevocationRepository struct {
+	next  domainRepository.TokenRevocationRepository
+	cache cache.Cache
+}
+
+func NewCachedTokenRevocationRepository(next domainRepository.TokenRevocationRepository, c cache.Cache) *cachedTokenRevocationRepository {
+	return &cachedTokenRevocationRepository{
+		next:  next,
+		cache: c,
+	}
+}
+
+func (r *cachedTokenRevocationRepository) RevokeToken(ctx context.Context, token *entity.RevokedToken) error {
+	if err := r.next.RevokeToken(ctx, token); err != nil {
+		return err
+	}
+	r.cache.Set(revokedTokenKey(token.JTI), true, time.Until(token.ExpiresAt))
+	return nil
+}
+
+func (r *cachedTokenRevocationRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	if revoked, ok := r.cache.Get(revokedTokenKey(jti)); ok {
+		return revoked.(bool), nil
+	}
+
+	revoked, err := r.next.IsTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+	r.cache.Set(revokedTokenKey(jti), revoked, 0)
+	return revoked, nil
+}
+
+func (r *cachedTokenRevocationRepository) RevokeUserTokens(ctx context.Context, userID uuid.UUID, before time.Time) error {
+	if err := r.next.RevokeUserTokens(ctx, userID, before); err != nil {
+		return err
+	}
+	r.cache.Set(userRevocationKey(userID), before, 0)
+	return nil
+}
+
+func (r *cachedTokenRevocationRepository) UserTokensRevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	if before, ok := r.cache.Get(userRevocationKey(userID)); ok {
+		return before.(time.Time), nil
+	}
+
+	before, err := r.next.UserTokensRevokedBefore(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	r.cache.Set(userRevocationKey(userID), before, 0)
+	return before, nil
+}
+
+func revokedTokenKey(jti string) string {
+	return "revoked_token:" + jti
+}
+
+func userRevocationKey(userID uuid.UUID) string {
+	return "user_token_revocation:" + userID.String()
+}
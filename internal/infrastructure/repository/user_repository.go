@@ -3,43 +3,38 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
 	domainErrors "github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	domainRepository "github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/database"
 	"gorm.io/gorm"
 )
 
 type userRepository struct {
 	db *gorm.DB
+	// fullText reports whether the FTS5 user search index exists
+	fullText bool
 }
 
 func NewUserRepository(db *gorm.DB) *userRepository {
 	return &userRepository{
-		db: db,
+		db:       db,
+		fullText: db.Migrator().HasTable(database.UserSearchTable),
 	}
 }
 
 func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
-	result := r.db.WithContext(ctx).Create(user)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
-			return domainErrors.ErrUserAlreadyExists
-		}
-		return result.Error
-	}
-	return nil
+	return database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		return createUser(tx, user)
+	})
 }
 
 func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
-	result := r.db.WithContext(ctx).Save(user)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return domainErrors.ErrUserNotFound
-	}
-	return nil
+	return updateUser(r.db.WithContext(ctx), user)
 }
 
 func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
@@ -61,6 +56,9 @@ func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.Us
 		}
 		return nil, err
 	}
+	if err := withRoles(r.db.WithContext(ctx), &user); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
@@ -72,24 +70,350 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*entity
 		}
 		return nil, err
 	}
+	if err := withRoles(r.db.WithContext(ctx), &user); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
-func (r *userRepository) List(ctx context.Context, page, limit int) ([]*entity.User, int64, error) {
+func (r *userRepository) List(ctx context.Context, query domainRepository.UserQuery) ([]*entity.User, int64, error) {
+	var users []*entity.User
+	var total int64
+
+	order, err := r.order(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.Limit
+
+	// Get total count
+	if err := r.filtered(ctx, query).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get users with pagination
+	if err := r.filtered(ctx, query).Order(order).Offset(offset).Limit(query.Limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := withRoles(r.db.WithContext(ctx), users...); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (r *userRepository) SetRoles(ctx context.Context, id uuid.UUID, roles []string) error {
+	return database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		result := tx.Model(&entity.User{}).Where("id = ?", id).Update("updated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domainErrors.ErrUserNotFound
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&entity.UserRole{}).Error; err != nil {
+			return err
+		}
+		return assignRoles(tx, id, roles)
+	})
+}
+
+func (r *userRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.User{}).Where(holdsRole, role).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *userRepository) IncrementFailedLogins(ctx context.Context, id uuid.UUID) (int, error) {
+	var attempts []int
+	err := r.db.WithContext(ctx).Raw(
+		`UPDATE users SET failed_logins = failed_logins + 1, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL
+		RETURNING failed_logins`,
+		time.Now(), id,
+	).Scan(&attempts).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(attempts) == 0 {
+		return 0, domainErrors.ErrUserNotFound
+	}
+	return attempts[0], nil
+}
+
+func (r *userRepository) ExtendLock(ctx context.Context, id uuid.UUID, until time.Time) error {
+	// Parallel failures may finish in any order; a shorter delay must not cut
+	// a lock short
+	return r.db.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ?", id).
+		Where("locked_until IS NULL OR julianday(locked_until) < julianday(?)", until.UTC()).
+		UpdateColumns(map[string]interface{}{"locked_until": until, "updated_at": time.Now()}).
+		Error
+}
+
+func (r *userRepository) ResetFailedLogins(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"failed_logins": 0, "locked_until": nil, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainErrors.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) ListDeleted(ctx context.Context, page, limit int) ([]*entity.User, int64, error) {
 	var users []*entity.User
 	var total int64
 
 	offset := (page - 1) * limit
 
 	// Get total count
-	if err := r.db.WithContext(ctx).Model(&entity.User{}).Count(&total).Error; err != nil {
+	if err := r.deleted(ctx).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get users with pagination
-	if err := r.db.WithContext(ctx).Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+	if err := r.deleted(ctx).Order("deleted_at DESC").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := withRoles(r.db.WithContext(ctx), users...); err != nil {
 		return nil, 0, err
 	}
 
 	return users, total, nil
 }
+
+func (r *userRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Unscoped().
+		Model(&entity.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainErrors.ErrUserNotFound
+	}
+	return nil
+}
+
+// userOwnedTables hold credentials and personal data of a user that are
+// removed with the user. Memberships cascade in the database; audit events
+// are kept as the record of what happened.
+var userOwnedTables = []interface{}{
+	&entity.UserRole{},
+	&entity.Session{},
+	&entity.RefreshToken{},
+	&entity.APIKey{},
+	&entity.UserIdentity{},
+	&entity.TOTPFactor{},
+	&entity.RecoveryCode{},
+	&entity.PasswordHistory{},
+	&entity.OneTimeToken{},
+}
+
+func (r *userRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := database.Transaction(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		expired := tx.Unscoped().Model(&entity.User{}).
+			Select("id").
+			Where("deleted_at IS NOT NULL AND julianday(deleted_at) < julianday(?)", deletedBefore.UTC())
+
+		for _, table := range userOwnedTables {
+			if err := tx.Where("user_id IN (?)", expired).Delete(table).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Unscoped().
+			Where("deleted_at IS NOT NULL AND julianday(deleted_at) < julianday(?)", deletedBefore.UTC()).
+			Delete(&entity.User{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+// holdsRole is the condition that a user holds the role named by its argument
+const holdsRole = "users.id IN (SELECT user_roles.user_id FROM user_roles JOIN roles ON roles.id = user_roles.role_id WHERE roles.name = ?)"
+
+// assignRoles gives the user the named roles in addition to the ones it holds
+// createUser inserts the user and its roles; tx must be a transaction
+func createUser(tx *gorm.DB, user *entity.User) error {
+	if err := tx.Create(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return domainErrors.ErrUserAlreadyExists
+		}
+		return err
+	}
+	return assignRoles(tx, user.ID, user.Roles)
+}
+
+func updateUser(db *gorm.DB, user *entity.User) error {
+	// Selecting the columns explicitly stops Save from falling back to an
+	// upsert when the tenant scope hides the row. The lockout columns are
+	// only changed atomically, so a stale copy must not overwrite them.
+	result := db.Select("*").Omit("failed_logins", "locked_until").Save(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domainErrors.ErrUserNotFound
+	}
+	return nil
+}
+
+func assignRoles(tx *gorm.DB, userID uuid.UUID, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	names := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		names[role] = true
+	}
+
+	result := tx.Exec(
+		"INSERT INTO user_roles (user_id, role_id, created_at) SELECT ?, id, ? FROM roles WHERE name IN ?",
+		userID, time.Now(), roles,
+	)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(names)) {
+		return domainErrors.ErrInvalidRole
+	}
+	return nil
+}
+
+// withRoles fills in the names of the roles the users hold
+func withRoles(db *gorm.DB, users ...*entity.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*entity.User, len(users))
+	ids := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		user.Roles = []string{}
+		byID[user.ID] = user
+		ids = append(ids, user.ID)
+	}
+
+	var assignments []struct {
+		UserID uuid.UUID
+		Name   string
+	}
+	err := db.Table("user_roles").
+		Select("user_roles.user_id, roles.name").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id IN ?", ids).
+		Order("roles.name").
+		Scan(&assignments).Error
+	if err != nil {
+		return err
+	}
+
+	for _, assignment := range assignments {
+		user := byID[assignment.UserID]
+		user.Roles = append(user.Roles, assignment.Name)
+	}
+	return nil
+}
+
+// deleted selects soft-deleted users only
+func (r *userRepository) deleted(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Unscoped().Model(&entity.User{}).Where("deleted_at IS NOT NULL")
+}
+
+// filtered selects the users matching the query filters
+func (r *userRepository) filtered(ctx context.Context, query domainRepository.UserQuery) *gorm.DB {
+	db := r.db.WithContext(ctx).Model(&entity.User{})
+
+	if query.Search != "" {
+		db = r.search(db, query.Search)
+	}
+	if query.Role != "" {
+		db = db.Where(holdsRole, query.Role)
+	}
+	if query.Active != nil {
+		db = db.Where("users.active = ?", *query.Active)
+	}
+	// Timestamps are stored as text with the offset of the zone they were
+	// written in, so they are compared and sorted as instants rather than as
+	// strings
+	if !query.CreatedAfter.IsZero() {
+		db = db.Where("julianday(users.created_at) >= julianday(?)", query.CreatedAfter.UTC())
+	}
+	if !query.CreatedBefore.IsZero() {
+		db = db.Where("julianday(users.created_at) <= julianday(?)", query.CreatedBefore.UTC())
+	}
+	if query.EmailDomain != "" {
+		db = db.Where(`users.email LIKE ? ESCAPE '\'`, "%@"+escapeLike(query.EmailDomain))
+	}
+
+	return db
+}
+
+// search matches users whose name or email contain every word of the
+// search. With the full-text index the words match token prefixes, so "jo"
+// finds "John" but not "Major"; without it they match anywhere.
+func (r *userRepository) search(db *gorm.DB, search string) *gorm.DB {
+	words := strings.Fields(search)
+
+	if r.fullText {
+		terms := make([]string, len(words))
+		for i, word := range words {
+			terms[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"*`
+		}
+		return db.Joins("JOIN "+database.UserSearchTable+" ON "+database.UserSearchTable+".user_id = users.id").
+			Where(database.UserSearchTable+" MATCH ?", strings.Join(terms, " "))
+	}
+
+	for _, word := range words {
+		pattern := "%" + escapeLike(word) + "%"
+		db = db.Where(`(users.name LIKE ? ESCAPE '\' OR users.email LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+	return db
+}
+
+// order builds the ORDER BY clause of the query. The ID breaks ties so that
+// pages do not overlap.
+func (r *userRepository) order(query domainRepository.UserQuery) (string, error) {
+	var columns []string
+	for _, sort := range query.Sort {
+		if !domainRepository.UserSortFields[sort.Field] {
+			return "", domainErrors.ErrInvalidInput
+		}
+		column := "users." + sort.Field
+		if strings.HasSuffix(sort.Field, "_at") {
+			column = "julianday(" + column + ")"
+		}
+		if sort.Desc {
+			column += " DESC"
+		}
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		if query.Search != "" && r.fullText {
+			columns = append(columns, database.UserSearchTable+".rank")
+		}
+		columns = append(columns, "julianday(users.created_at)")
+	}
+
+	return strings.Join(append(columns, "users.id"), ", "), nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
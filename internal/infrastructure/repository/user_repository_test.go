@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	domainErrors "github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	domainRepository "github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "test.db")}}
+	db, err := database.NewSQLiteDB(cfg)
+	require.NoError(t, err)
+	return db
+}
+
+// newTestRoles creates roles without permissions
+func newTestRoles(t *testing.T, db *gorm.DB, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		require.NoError(t, db.Create(entity.NewRole(name, "", nil)).Error)
+	}
+}
+
+func TestUserRepository_List(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	newTestRoles(t, db, entity.RoleUser, entity.RoleAdmin)
+
+	// Creation times are written in different zones on purpose
+	jakarta := time.FixedZone("UTC+7", 7*60*60)
+	denver := time.FixedZone("UTC-7", -7*60*60)
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	newUser := func(email, name, role string, active bool, createdAt time.Time) *entity.User {
+		user, _ := entity.NewUser(email, "password123", name)
+		user.SetRoles([]string{role})
+		user.Active = active
+		user.CreatedAt = createdAt
+		require.NoError(t, repo.Create(ctx, user))
+		if !active {
+			require.NoError(t, db.Model(user).Update("active", false).Error)
+		}
+		return user
+	}
+	jane := newUser("jane.doe@example.com", "Jane Doe", "user", true, base.In(jakarta))
+	john := newUser("john.smith@example.com", "John Smith", "admin", true, base.Add(time.Hour).In(denver))
+	zoe := newUser("zoe@other.org", "Zoë Major", "user", false, base.Add(2*time.Hour))
+
+	list := func(t *testing.T, query domainRepository.UserQuery) []*entity.User {
+		t.Helper()
+		if query.Page == 0 {
+			query.Page, query.Limit = 1, 10
+		}
+		users, total, err := repo.List(ctx, query)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(users)), total)
+		return users
+	}
+	emails := func(users []*entity.User) []string {
+		var emails []string
+		for _, user := range users {
+			emails = append(emails, user.Email)
+		}
+		return emails
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		assert.Equal(t, []string{jane.Email, john.Email, zoe.Email}, emails(list(t, domainRepository.UserQuery{})))
+	})
+
+	t.Run("CreatedRange", func(t *testing.T) {
+		// Bounds in any zone select the same instants
+		for _, loc := range []*time.Location{time.UTC, jakarta, denver} {
+			users := list(t, domainRepository.UserQuery{
+				CreatedAfter:  base.Add(30 * time.Minute).In(loc),
+				CreatedBefore: base.Add(time.Hour).In(loc),
+			})
+			assert.Equal(t, []string{john.Email}, emails(users), loc.String())
+		}
+
+		users := list(t, domainRepository.UserQuery{CreatedAfter: base.In(denver)})
+		assert.Equal(t, []string{jane.Email, john.Email, zoe.Email}, emails(users))
+	})
+
+	t.Run("RoleAndActive", func(t *testing.T) {
+		inactive := false
+		assert.Equal(t, []string{john.Email}, emails(list(t, domainRepository.UserQuery{Role: "admin"})))
+		assert.Equal(t, []string{zoe.Email}, emails(list(t, domainRepository.UserQuery{Active: &inactive})))
+		assert.Empty(t, list(t, domainRepository.UserQuery{Role: "admin", Active: &inactive}))
+	})
+
+	t.Run("EmailDomain", func(t *testing.T) {
+		assert.Equal(t, []string{jane.Email, john.Email}, emails(list(t, domainRepository.UserQuery{EmailDomain: "example.com"})))
+		assert.Empty(t, list(t, domainRepository.UserQuery{EmailDomain: "example"}))
+	})
+
+	t.Run("Sort", func(t *testing.T) {
+		users := list(t, domainRepository.UserQuery{Sort: []domainRepository.SortOrder{{Field: "name", Desc: true}, {Field: "created_at"}}})
+		assert.Equal(t, []string{zoe.Email, john.Email, jane.Email}, emails(users))
+
+		users, total, err := repo.List(ctx, domainRepository.UserQuery{Page: 2, Limit: 2, Sort: []domainRepository.SortOrder{{Field: "email", Desc: true}}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		assert.Equal(t, []string{jane.Email}, emails(users))
+	})
+
+	t.Run("SortNotAllowed", func(t *testing.T) {
+		_, _, err := repo.List(ctx, domainRepository.UserQuery{Page: 1, Limit: 10, Sort: []domainRepository.SortOrder{{Field: "password"}}})
+		assert.Equal(t, domainErrors.ErrInvalidInput, err)
+
+		// Users may hold several roles
+		_, _, err = repo.List(ctx, domainRepository.UserQuery{Page: 1, Limit: 10, Sort: []domainRepository.SortOrder{{Field: "role"}}})
+		assert.Equal(t, domainErrors.ErrInvalidInput, err)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		assert.Equal(t, []string{jane.Email}, emails(list(t, domainRepository.UserQuery{Search: "jane doe"})))
+		assert.Equal(t, []string{john.Email}, emails(list(t, domainRepository.UserQuery{Search: "smi"})))
+		assert.Equal(t, []string{jane.Email, john.Email}, emails(list(t, domainRepository.UserQuery{Search: "example", Sort: []domainRepository.SortOrder{{Field: "email"}}})))
+		assert.Equal(t, []string{zoe.Email}, emails(list(t, domainRepository.UserQuery{Search: "other.org", Active: new(bool)})))
+		assert.Empty(t, list(t, domainRepository.UserQuery{Search: `"`}))
+	})
+
+	t.Run("SearchIndexFollowsUpdates", func(t *testing.T) {
+		require.NoError(t, db.Model(jane).Update("name", "Janet Bloggs").Error)
+
+		assert.Equal(t, []string{jane.Email}, emails(list(t, domainRepository.UserQuery{Search: "bloggs"})))
+		assert.Empty(t, list(t, domainRepository.UserQuery{Search: "smith", Role: "user"}))
+	})
+}
+
+func TestUserRepository_Roles(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	newTestRoles(t, db, entity.RoleUser, entity.RoleAdmin, "support")
+
+	user, _ := entity.NewUser("roles@example.com", "password123", "Roles User")
+	require.NoError(t, repo.Create(ctx, user))
+	other, _ := entity.NewUser("other@example.com", "password123", "Other User")
+	require.NoError(t, repo.Create(ctx, other))
+
+	stored, err := repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{entity.RoleUser}, stored.Roles)
+
+	t.Run("SetRoles", func(t *testing.T) {
+		require.NoError(t, repo.SetRoles(ctx, user.ID, []string{"support", entity.RoleAdmin}))
+
+		stored, err := repo.FindByEmail(ctx, user.Email)
+		require.NoError(t, err)
+		assert.Equal(t, []string{entity.RoleAdmin, "support"}, stored.Roles)
+
+		count, err := repo.CountByRole(ctx, entity.RoleUser)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("ListByRole", func(t *testing.T) {
+		users, total, err := repo.List(ctx, domainRepository.UserQuery{Page: 1, Limit: 10, Role: "support"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		require.Len(t, users, 1)
+		assert.Equal(t, []string{entity.RoleAdmin, "support"}, users[0].Roles)
+
+		users, _, err = repo.List(ctx, domainRepository.UserQuery{Page: 1, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.Equal(t, []string{entity.RoleUser}, users[1].Roles)
+	})
+
+	t.Run("UnknownRole", func(t *testing.T) {
+		err := repo.SetRoles(ctx, user.ID, []string{entity.RoleUser, "superuser"})
+		assert.Equal(t, domainErrors.ErrInvalidRole, err)
+
+		// The roles are replaced all at once or not at all
+		stored, _ := repo.FindByID(ctx, user.ID)
+		assert.Equal(t, []string{entity.RoleAdmin, "support"}, stored.Roles)
+
+		newUser, _ := entity.NewUser("ghost@example.com", "password123", "Ghost")
+		newUser.SetRoles([]string{"superuser"})
+		assert.Equal(t, domainErrors.ErrInvalidRole, repo.Create(ctx, newUser))
+		_, err = repo.FindByEmail(ctx, newUser.Email)
+		assert.Equal(t, domainErrors.ErrUserNotFound, err)
+	})
+
+	t.Run("UnknownUser", func(t *testing.T) {
+		assert.Equal(t, domainErrors.ErrUserNotFound, repo.SetRoles(ctx, uuid.New(), []string{entity.RoleUser}))
+	})
+}
+
+func TestUserRepository_IncrementFailedLogins(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+
+	user, _ := entity.NewUser("guess@example.com", "password123", "Guess Target")
+	require.NoError(t, db.Create(user).Error)
+
+	// Parallel failures each get their own count
+	const attempts = 20
+	counts := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count, err := repo.IncrementFailedLogins(ctx, user.ID)
+			assert.NoError(t, err)
+			counts <- count
+		}()
+	}
+	wg.Wait()
+	close(counts)
+
+	seen := make(map[int]bool)
+	for count := range counts {
+		seen[count] = true
+	}
+	assert.Len(t, seen, attempts)
+
+	stored, err := repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, attempts, stored.FailedLogins)
+	assert.Equal(t, user.Name, stored.Name)
+
+	_, err = repo.IncrementFailedLogins(ctx, uuid.New())
+	assert.Equal(t, domainErrors.ErrUserNotFound, err)
+}
+
+func TestUserRepository_ExtendLock(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+
+	user, _ := entity.NewUser("locked@example.com", "password123", "Locked User")
+	require.NoError(t, db.Create(user).Error)
+	lockedUntil := time.Now().Add(15 * time.Minute)
+
+	require.NoError(t, repo.ExtendLock(ctx, user.ID, lockedUntil))
+	// A shorter delay that finishes later does not cut the lock short
+	require.NoError(t, repo.ExtendLock(ctx, user.ID, time.Now().Add(time.Second).UTC()))
+
+	stored, err := repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.IsLocked())
+	assert.WithinDuration(t, lockedUntil, *stored.LockedUntil, time.Millisecond)
+
+	require.NoError(t, repo.ResetFailedLogins(ctx, user.ID))
+	stored, _ = repo.FindByID(ctx, user.ID)
+	assert.False(t, stored.IsLocked())
+	assert.Zero(t, stored.FailedLogins)
+}
+
+func TestUserRepository_Update(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+
+	user, _ := entity.NewUser("update@example.com", "password123", "Update User")
+	require.NoError(t, db.Create(user).Error)
+	stale, err := repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+
+	_, err = repo.IncrementFailedLogins(ctx, user.ID)
+	require.NoError(t, err)
+	require.NoError(t, repo.ExtendLock(ctx, user.ID, time.Now().Add(time.Minute)))
+
+	// A profile change made from a copy read before the failure keeps it
+	stale.Name = "Renamed User"
+	require.NoError(t, repo.Update(ctx, stale))
+
+	stored, err := repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed User", stored.Name)
+	assert.Equal(t, 1, stored.FailedLogins)
+	assert.True(t, stored.IsLocked())
+
+	assert.Equal(t, domainErrors.ErrUserNotFound, repo.Update(ctx, &entity.User{ID: uuid.New()}))
+}
+
+func TestUserRepository_Purge(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	role := entity.NewRole(entity.RoleUser, "", nil)
+	require.NoError(t, db.Create(role).Error)
+
+	newUser := func(email string) *entity.User {
+		user, _ := entity.NewUser(email, "password123", "Purge Test")
+		require.NoError(t, db.Create(user).Error)
+		for _, owned := range []interface{}{
+			entity.NewUserRole(user.ID, role.ID),
+			entity.NewSession(uuid.New(), user.ID, "test", "192.0.2.1", time.Hour),
+			entity.NewRefreshToken(user.ID, uuid.New(), email+"-refresh", time.Hour),
+			entity.NewAPIKey(user.ID, "key", email, email+"-key", nil, nil),
+			entity.NewUserIdentity(user.ID, "https://issuer.test", email, email),
+			entity.NewTOTPFactor(user.ID, "secret"),
+			entity.NewRecoveryCode(user.ID, email+"-code"),
+			entity.NewPasswordHistory(user),
+			entity.NewOneTimeToken(user.ID, "reset", email+"-reset", time.Hour),
+		} {
+			require.NoError(t, db.Create(owned).Error)
+		}
+		return user
+	}
+	countOwned := func(userID uuid.UUID) int64 {
+		var total int64
+		for _, table := range userOwnedTables {
+			var count int64
+			require.NoError(t, db.Model(table).Where("user_id = ?", userID).Count(&count).Error)
+			total += count
+		}
+		return total
+	}
+
+	expired, recent, active := newUser("expired@example.com"), newUser("recent@example.com"), newUser("active@example.com")
+	require.NoError(t, db.Model(expired).Update("deleted_at", time.Now().Add(-48*time.Hour)).Error)
+	require.NoError(t, repo.Delete(ctx, recent.ID))
+	require.Equal(t, int64(len(userOwnedTables)), countOwned(expired.ID))
+
+	purged, err := repo.Purge(ctx, time.Now().Add(-24*time.Hour))
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	assert.Zero(t, countOwned(expired.ID))
+	assert.Equal(t, int64(len(userOwnedTables)), countOwned(recent.ID))
+	assert.Equal(t, int64(len(userOwnedTables)), countOwned(active.ID))
+
+	var remaining int64
+	require.NoError(t, db.Unscoped().Model(&entity.User{}).Count(&remaining).Error)
+	assert.Equal(t, int64(2), remaining)
+}
@@ -0,0 +1,60 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+)
+
+// KeyEncrypter seals signing key material before it is stored, so a copy of
+// the database alone does not reveal the keys. It uses AES-256-GCM with the
+// SHA-256 hash of JWTConfig.KeyEncryptionKey as the key.
+type KeyEncrypter struct {
+	aead cipher.AEAD
+}
+
+func NewKeyEncrypter(cfg *config.Config) (*KeyEncrypter, error) {
+	if cfg.JWT.KeyEncryptionKey == "" {
+		return nil, fmt.Errorf("JWT_KEY_ENCRYPTION_KEY must be set to store signing keys")
+	}
+
+	sum := sha256.Sum256([]byte(cfg.JWT.KeyEncryptionKey))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyEncrypter{aead: aead}, nil
+}
+
+// Seal encrypts the material of the key kid. The kid is authenticated, so
+// sealed material copied to another key fails to open.
+func (e *KeyEncrypter) Seal(kid string, material []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(nonce, nonce, material, []byte(kid)), nil
+}
+
+// Open decrypts material sealed for the key kid
+func (e *KeyEncrypter) Open(kid string, sealed []byte) ([]byte, error) {
+	size := e.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, fmt.Errorf("sealed key material is too short")
+	}
+
+	material, err := e.aead.Open(nil, sealed[:size], sealed[size:], []byte(kid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt key material; is JWT_KEY_ENCRYPTION_KEY the one it was stored with?")
+	}
+	return material, nil
+}
@@ -0,0 +1,91 @@
+package security
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyRing holds the key that signs new tokens together with the keys that are
+// still accepted for verification. Keys are selected by kid and stop
+// verifying once their expiry passes.
+type KeyRing struct {
+	mu      sync.RWMutex
+	active  *SigningKey
+	entries map[string]keyRingEntry
+}
+
+type keyRingEntry struct {
+	key       *SigningKey
+	expiresAt *time.Time
+}
+
+func NewKeyRing(active *SigningKey) *KeyRing {
+	ring := &KeyRing{
+		entries: make(map[string]keyRingEntry),
+	}
+	ring.Replace(active, nil)
+	return ring
+}
+
+// Replace atomically swaps the whole ring contents. verifying maps keys to the
+// time they stop verifying; a nil expiry means no limit.
+func (r *KeyRing) Replace(active *SigningKey, verifying map[*SigningKey]*time.Time) {
+	entries := map[string]keyRingEntry{
+		active.ID: {key: active},
+	}
+	for key, expiresAt := range verifying {
+		if key.ID != active.ID {
+			entries[key.ID] = keyRingEntry{key: key, expiresAt: expiresAt}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.active = active
+	r.entries = entries
+}
+
+// Active returns the key used to sign new tokens
+func (r *KeyRing) Active() *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.active
+}
+
+// Lookup returns the key with the given kid if it may still verify tokens
+func (r *KeyRing) Lookup(kid string) (*SigningKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.entries[kid]
+	if !ok || (entry.expiresAt != nil && time.Now().After(*entry.expiresAt)) {
+		return nil, false
+	}
+	return entry.key, true
+}
+
+// JWKS returns the public keys of every asymmetric key that may still verify tokens
+func (r *KeyRing) JWKS() JWKS {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	jwks := JWKS{Keys: []JWK{}}
+	now := time.Now()
+	for _, entry := range r.entries {
+		if entry.key.Symmetric() || (entry.expiresAt != nil && now.After(*entry.expiresAt)) {
+			continue
+		}
+		if jwk, err := entry.key.PublicJWK(); err == nil {
+			jwks.Keys = append(jwks.Keys, *jwk)
+		}
+	}
+
+	// Stable output keeps HTTP caches and diffs quiet
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+	return jwks
+}
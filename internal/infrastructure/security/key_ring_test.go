@@ -0,0 +1,59 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRing(t *testing.T) {
+	active, err := GenerateSigningKey("ES256")
+	require.NoError(t, err)
+	retired, err := GenerateSigningKey("ES256")
+	require.NoError(t, err)
+	expired, err := GenerateSigningKey("ES256")
+	require.NoError(t, err)
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+
+	ring := NewKeyRing(active)
+	ring.Replace(active, map[*SigningKey]*time.Time{
+		retired: &future,
+		expired: &past,
+	})
+
+	assert.Equal(t, active, ring.Active())
+
+	key, ok := ring.Lookup(retired.ID)
+	assert.True(t, ok)
+	assert.Equal(t, retired, key)
+
+	_, ok = ring.Lookup(expired.ID)
+	assert.False(t, ok)
+
+	jwks := ring.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	for _, jwk := range jwks.Keys {
+		assert.NotEqual(t, expired.ID, jwk.Kid)
+	}
+}
+
+func TestSigningKeyMaterialRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{"HS256", "RS256", "ES384", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, err := GenerateSigningKey(algorithm)
+			require.NoError(t, err)
+
+			material, err := key.Material()
+			require.NoError(t, err)
+
+			restored, err := NewSigningKeyFromMaterial(key.ID, algorithm, material)
+			require.NoError(t, err)
+			assert.Equal(t, key.ID, restored.ID)
+			assert.Equal(t, key.VerifyKey(), restored.VerifyKey())
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
+)
+
+// SigningKey is a JWT signing key identified by its kid. For HMAC algorithms the
+// same secret signs and verifies; for asymmetric algorithms only the public
+// half is ever published.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// LoadSigningKey builds the signing key described by the JWT configuration.
+// HMAC algorithms use JWTConfig.Secret; every other algorithm reads a PEM
+// encoded private key from JWTConfig.PrivateKeyPath.
+func LoadSigningKey(cfg *config.Config) (*SigningKey, error) {
+	method := jwt.GetSigningMethod(cfg.JWT.SigningAlgorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported JWT signing algorithm %q", cfg.JWT.SigningAlgorithm)
+	}
+
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		return NewHMACKey(cfg.JWT.KeyID, method.Alg(), []byte(cfg.JWT.Secret))
+	}
+
+	if cfg.JWT.PrivateKeyPath == "" {
+		return nil, fmt.Errorf("JWT signing algorithm %s requires a private key path", method.Alg())
+	}
+
+	data, err := os.ReadFile(cfg.JWT.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+
+	return ParsePrivateKeyPEM(cfg.JWT.KeyID, method.Alg(), data)
+}
+
+// NewHMACKey creates a symmetric key. When id is empty a kid is derived from a
+// hash of the secret.
+func NewHMACKey(id, algorithm string, secret []byte) (*SigningKey, error) {
+	method, ok := jwt.GetSigningMethod(algorithm).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return nil, fmt.Errorf("%q is not an HMAC algorithm", algorithm)
+	}
+
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("HMAC signing key must not be empty")
+	}
+
+	if id == "" {
+		sum := sha256.Sum256(secret)
+		id = hex.EncodeToString(sum[:8])
+	}
+
+	return &SigningKey{
+		ID:        id,
+		Method:    method,
+		signKey:   secret,
+		verifyKey: secret,
+	}, nil
+}
+
+// ParsePrivateKeyPEM creates an asymmetric key for the given algorithm. When
+// id is empty the RFC 7638 thumbprint of the public key is used as kid.
+func ParsePrivateKeyPEM(id, algorithm string, data []byte) (*SigningKey, error) {
+	method := jwt.GetSigningMethod(algorithm)
+
+	var signer crypto.Signer
+	var err error
+
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		signer, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+	case *jwt.SigningMethodECDSA:
+		var key *ecdsa.PrivateKey
+		key, err = jwt.ParseECPrivateKeyFromPEM(data)
+		if err == nil && key.Curve.Params().BitSize != m.CurveBits {
+			return nil, fmt.Errorf("%s requires a P-%d key", m.Alg(), m.CurveBits)
+		}
+		signer = key
+	case *jwt.SigningMethodEd25519:
+		var key crypto.PrivateKey
+		key, err = jwt.ParseEdPrivateKeyFromPEM(data)
+		if err == nil {
+			signer, _ = key.(crypto.Signer)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported asymmetric signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s private key: %w", algorithm, err)
+	}
+
+	return newAsymmetricKey(id, method, signer)
+}
+
+func newAsymmetricKey(id string, method jwt.SigningMethod, signer crypto.Signer) (*SigningKey, error) {
+	key := &SigningKey{
+		ID:        id,
+		Method:    method,
+		signKey:   signer,
+		verifyKey: signer.Public(),
+	}
+
+	if key.ID == "" {
+		jwk, err := key.PublicJWK()
+		if err != nil {
+			return nil, err
+		}
+		key.ID = jwk.Thumbprint()
+	}
+
+	return key, nil
+}
+
+// Algorithm returns the JWS alg header value of the key
+func (k *SigningKey) Algorithm() string {
+	return k.Method.Alg()
+}
+
+// Symmetric reports whether the key is a shared secret that must never be published
+func (k *SigningKey) Symmetric() bool {
+	_, ok := k.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+// Sign signs the claims and sets the kid header
+func (k *SigningKey) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.Method, claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.signKey)
+}
+
+// VerifyKey returns the key material accepted by the jwt parser for verification
+func (k *SigningKey) VerifyKey() interface{} {
+	return k.verifyKey
+}
+
+// PublicJWK returns the public half of an asymmetric key as a JSON Web Key
+func (k *SigningKey) PublicJWK() (*JWK, error) {
+	jwk := &JWK{
+		Kid: k.ID,
+		Use: "sig",
+		Alg: k.Algorithm(),
+	}
+
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64URL(pub.N.Bytes())
+		jwk.E = encodeBase64URL(bigEndianExponent(pub.E))
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = encodeBase64URL(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64URL(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64URL(pub)
+	default:
+		return nil, fmt.Errorf("key %s has no public JWK representation", k.ID)
+	}
+
+	return jwk, nil
+}
+
+// GenerateSigningKey creates a fresh random key for the given algorithm
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	method := jwt.GetSigningMethod(algorithm)
+
+	var signer crypto.Signer
+	var err error
+
+	switch m := method.(type) {
+	case *jwt.SigningMethodHMAC:
+		secret := make([]byte, m.Hash.Size())
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		return NewHMACKey("", algorithm, secret)
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case *jwt.SigningMethodECDSA:
+		var curve elliptic.Curve
+		switch m.CurveBits {
+		case 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		default:
+			curve = elliptic.P521()
+		}
+		signer, err = ecdsa.GenerateKey(curve, rand.Reader)
+	case *jwt.SigningMethodEd25519:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported JWT signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newAsymmetricKey("", method, signer)
+}
+
+// NewSigningKeyFromMaterial restores a key previously exported with Material
+func NewSigningKeyFromMaterial(id, algorithm string, material []byte) (*SigningKey, error) {
+	if _, ok := jwt.GetSigningMethod(algorithm).(*jwt.SigningMethodHMAC); ok {
+		return NewHMACKey(id, algorithm, material)
+	}
+	return ParsePrivateKeyPEM(id, algorithm, material)
+}
+
+// Material exports the private key: the raw secret for HMAC keys and a PKCS#8
+// PEM block otherwise
+func (k *SigningKey) Material() ([]byte, error) {
+	if secret, ok := k.signKey.([]byte); ok {
+		return secret, nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(k.signKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
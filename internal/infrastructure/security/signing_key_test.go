@@ -0,0 +1,101 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePKCS8(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		algorithm string
+		key       interface{}
+		kty       string
+	}{
+		{"RS256", rsaKey, "RSA"},
+		{"ES256", ecKey, "EC"},
+		{"EdDSA", edKey, "OKP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			key, err := ParsePrivateKeyPEM("", tt.algorithm, encodePKCS8(t, tt.key))
+			require.NoError(t, err)
+			assert.False(t, key.Symmetric())
+
+			signed, err := key.Sign(jwt.MapClaims{"sub": "user"})
+			require.NoError(t, err)
+
+			parsed, err := jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+				assert.Equal(t, key.ID, token.Header["kid"])
+				return key.VerifyKey(), nil
+			}, jwt.WithValidMethods([]string{tt.algorithm}))
+			require.NoError(t, err)
+			assert.True(t, parsed.Valid)
+
+			jwk, err := key.PublicJWK()
+			require.NoError(t, err)
+			assert.Equal(t, tt.kty, jwk.Kty)
+			assert.Equal(t, key.ID, jwk.Kid)
+			assert.Equal(t, jwk.Thumbprint(), key.ID)
+
+			// A verifier that only has the JWK accepts the token too
+			pub, err := jwk.PublicKey()
+			require.NoError(t, err)
+			_, err = jwt.Parse(signed, func(*jwt.Token) (interface{}, error) {
+				return pub, nil
+			}, jwt.WithValidMethods([]string{tt.algorithm}))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("CurveMismatch", func(t *testing.T) {
+		_, err := ParsePrivateKeyPEM("", "ES384", encodePKCS8(t, ecKey))
+		assert.Error(t, err)
+	})
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// Example from RFC 7638 section 3.1
+	jwk := &JWK{
+		Kty: "RSA",
+		E:   "AQAB",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECP" +
+			"ebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY" +
+			"368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0f" +
+			"M4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", jwk.Thumbprint())
+}
+
+func TestNewHMACKey(t *testing.T) {
+	key, err := NewHMACKey("", "HS256", []byte("secret"))
+	require.NoError(t, err)
+	assert.True(t, key.Symmetric())
+	assert.NotEmpty(t, key.ID)
+
+	_, err = key.PublicJWK()
+	assert.Error(t, err)
+
+	_, err = NewHMACKey("", "RS256", []byte("secret"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+	validate      *validator.Validate
+}
+
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		validate:      validator.New(),
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"omitempty,dive,required,max=64"`
+	// ExpiresAt is an RFC 3339 timestamp; the key never expires when empty
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	// Key is the secret to send in the X-API-Key header. It cannot be retrieved again.
+	Key string `json:"key"`
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the active API keys of the authenticated user. Secrets are never returned.
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} APIKeyResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Router /api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	keys, err := h.apiKeyService.List(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, newAPIKeyResponse(key))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Create a personal API key for scripts and CI jobs. The key is shown only once and is accepted in the X-API-Key header or as "Authorization: ApiKey <key>". Scopes are permission names or "account"; a key without scopes is not restricted.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "Key details"
+// @Success 201 {object} CreateAPIKeyResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Router /api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	created, err := h.apiKeyService.Create(r.Context(), userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidInput:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrUserNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusCreated, CreateAPIKeyResponse{
+		APIKeyResponse: newAPIKeyResponse(created.Key),
+		Key:            created.Secret,
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revoke an API key of the authenticated user. Requests using it fail immediately.
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(r.Context(), userID, id); err != nil {
+		switch err {
+		case errors.ErrNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newAPIKeyResponse(key *entity.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// ListAuditEvents godoc
+// @Summary List audit events
+// @Description Get a paginated list of audit events, newest first (requires audit:read)
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {array} entity.AuditEvent
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /admin/audit-events [get]
+func (h *AuditHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	events, total, err := h.auditService.List(r.Context(), page, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	respondWithJSON(w, http.StatusOK, events)
+}
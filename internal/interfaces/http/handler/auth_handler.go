@@ -2,12 +2,16 @@ package handler
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/interfaces/http/middleware"
 )
 
 type AuthHandler struct {
@@ -27,20 +31,49 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required,min=6"`
 }
 
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type ScopedTokenRequest struct {
+	// Scopes are permission names or "account"
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
 type TokenResponse struct {
-	Token string `json:"token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	// Scope lists the scopes the access token is restricted to, if any
+	Scope string `json:"scope,omitempty"`
 }
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return an access and refresh token pair. Users with a second factor receive an MFA challenge instead, to be completed at /auth/mfa/verify.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body LoginRequest true "Login credentials"
 // @Success 200 {object} TokenResponse
+// @Success 202 {object} MFAChallengeResponse
 // @Failure 400 {object} errors.ErrorResponse
 // @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 423 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
@@ -54,41 +87,95 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.authService.Login(r.Context(), req.Email, req.Password)
+	result, err := h.authService.Login(r.Context(), req.Email, req.Password, clientInfo(r))
 	if err != nil {
 		switch err {
 		case errors.ErrInvalidCredential:
 			respondWithError(w, http.StatusUnauthorized, err)
+		case errors.ErrEmailNotVerified:
+			respondWithError(w, http.StatusForbidden, err)
+		case errors.ErrTooManyRequests:
+			respondWithError(w, http.StatusTooManyRequests, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithLoginResult(w, result)
+}
+
+// VerifyMFA godoc
+// @Summary Complete MFA login
+// @Description Exchange the challenge returned by /auth/login and an authenticator or recovery code for a token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body VerifyMFARequest true "MFA challenge and code"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 423 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Router /auth/mfa/verify [post]
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req VerifyMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	tokens, err := h.authService.VerifyMFA(r.Context(), req.MFAToken, req.Code, clientInfo(r))
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidToken, errors.ErrTokenExpired, errors.ErrTokenRevoked, errors.ErrInvalidMFACode,
+			errors.ErrMFANotEnrolled, errors.ErrUnauthorized:
+			respondWithError(w, http.StatusUnauthorized, err)
+		case errors.ErrAccountLocked:
+			respondWithError(w, http.StatusLocked, err)
+		case errors.ErrTooManyRequests:
+			respondWithError(w, http.StatusTooManyRequests, err)
 		default:
 			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
 		}
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, TokenResponse{Token: token})
+	respondWithJSON(w, http.StatusOK, newTokenResponse(tokens))
 }
 
 // RefreshToken godoc
 // @Summary Refresh JWT token
-// @Description Get new JWT token using refresh token
+// @Description Exchange a refresh token for a new token pair. Refresh tokens are single-use; presenting one twice revokes every token issued from the same login.
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Security BearerAuth
+// @Param request body RefreshTokenRequest true "Refresh token"
 // @Success 200 {object} TokenResponse
+// @Failure 400 {object} errors.ErrorResponse
 // @Failure 401 {object} errors.ErrorResponse
 // @Router /auth/refresh [post]
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
-	token := extractToken(r)
-	if token == "" {
-		respondWithError(w, http.StatusUnauthorized, errors.ErrInvalidToken)
+	var req RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
 		return
 	}
 
-	newToken, err := h.authService.RefreshToken(token)
+	tokens, err := h.authService.RefreshToken(r.Context(), req.RefreshToken)
 	if err != nil {
 		switch err {
-		case errors.ErrInvalidToken, errors.ErrTokenExpired:
+		case errors.ErrInvalidToken, errors.ErrTokenExpired, errors.ErrTokenRevoked, errors.ErrTokenReused, errors.ErrUnauthorized:
 			respondWithError(w, http.StatusUnauthorized, err)
 		default:
 			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
@@ -96,24 +183,217 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, TokenResponse{Token: newToken})
+	respondWithJSON(w, http.StatusOK, newTokenResponse(tokens))
+}
+
+// Logout godoc
+// @Summary Logout current session
+// @Description Revoke the presented access token and the refresh tokens of its session
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {object} errors.ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), claims); err != nil {
+		switch err {
+		case errors.ErrInvalidToken:
+			respondWithError(w, http.StatusUnauthorized, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Logout all sessions
+// @Description Revoke every access and refresh token of the authenticated user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {object} errors.ErrorResponse
+// @Router /auth/logout/all [post]
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	if err := h.authService.LogoutAll(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ScopedToken godoc
+// @Summary Issue a down-scoped token
+// @Description Exchange the presented token for an access token restricted to the given scopes, for example a read-only token for a dashboard. A restricted token can only be narrowed further. The new token expires no later than the presented one and cannot be refreshed.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ScopedTokenRequest true "Requested scopes"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /auth/token/scoped [post]
+func (h *AuthHandler) ScopedToken(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req ScopedTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	tokens, err := h.authService.Downscope(r.Context(), claims, req.Scopes)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidScope:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrForbidden:
+			respondWithError(w, http.StatusForbidden, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, newTokenResponse(tokens))
+}
+
+// LogoutUser godoc
+// @Summary Logout all sessions of a user
+// @Description Revoke every access and refresh token of the given user (requires users:logout)
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /users/{id}/logout [post]
+func (h *AuthHandler) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.authService.LogoutAll(r.Context(), id); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens. Empty when tokens are signed with a shared secret.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} security.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, h.authService.JWKS())
 }
 
 // Helper functions
 
-func extractToken(r *http.Request) string {
-	bearerToken := r.Header.Get("Authorization")
-	strArr := strings.Split(bearerToken, " ")
-	if len(strArr) == 2 {
-		return strArr[1]
+// currentUserID returns the id of the authenticated user from the token
+// subject. ok is false for OAuth clients, which are not users.
+func currentUserID(r *http.Request) (uuid.UUID, bool) {
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok || !principal.IsUser() {
+		return uuid.Nil, false
+	}
+	return principal.UserID, true
+}
+
+// clientInfo identifies the caller for throttling and auditing. Only the
+// connection address is trusted; forwarding headers can be spoofed.
+func clientInfo(r *http.Request) service.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return service.ClientInfo{
+		IP:        ip,
+		UserAgent: r.UserAgent(),
 	}
-	return ""
+}
+
+func newTokenResponse(tokens *service.TokenPair) TokenResponse {
+	return TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		Scope:        strings.Join(tokens.Scopes, " "),
+	}
+}
+
+// respondWithLoginResult writes the token pair, or the MFA challenge when a
+// second factor is still required
+func respondWithLoginResult(w http.ResponseWriter, result *service.LoginResult) {
+	if result.MFAToken != "" {
+		respondWithJSON(w, http.StatusAccepted, MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    result.MFAToken,
+			ExpiresIn:   int64(result.MFAExpiresIn.Seconds()),
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newTokenResponse(result.Tokens))
 }
 
 func respondWithError(w http.ResponseWriter, code int, err error) {
 	respondWithJSON(w, code, errors.NewErrorResponse(code, err.Error()))
 }
 
+// respondWithPasswordError reports each password policy rule the password
+// broke under the request field. It returns false for any other error.
+func respondWithPasswordError(w http.ResponseWriter, field string, err error) bool {
+	policyErr, ok := err.(*errors.PasswordPolicyError)
+	if !ok {
+		return false
+	}
+
+	respondWithJSON(w, http.StatusBadRequest, errors.NewValidationErrorResponse(
+		http.StatusBadRequest, policyErr.Error(), map[string][]string{field: policyErr.Reasons},
+	))
+	return true
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
 	w.Header().Set("Content-Type", "application/json")
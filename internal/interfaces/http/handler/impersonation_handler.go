@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/interfaces/http/middleware"
+)
+
+type ImpersonationHandler struct {
+	impersonationService service.ImpersonationService
+}
+
+func NewImpersonationHandler(impersonationService service.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: impersonationService,
+	}
+}
+
+// StartImpersonation godoc
+// @Summary Impersonate user
+// @Description Issue a short-lived access token to act as another user for support purposes (requires users:impersonate). The token carries the admin in its act claim, cannot be refreshed and cannot change passwords or roles. Users whose role grants a permission the admin lacks cannot be impersonated.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /auth/impersonate/{id} [post]
+func (h *ImpersonationHandler) StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	tokens, err := h.impersonationService.Start(r.Context(), claims, id, clientInfo(r))
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidInput:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrInvalidToken:
+			respondWithError(w, http.StatusUnauthorized, err)
+		case errors.ErrForbidden, errors.ErrImpersonating:
+			respondWithError(w, http.StatusForbidden, err)
+		case errors.ErrUserNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, newTokenResponse(tokens))
+}
+
+// StopImpersonation godoc
+// @Summary Stop impersonating
+// @Description Revoke the impersonation token used for the request
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Router /auth/impersonate/stop [post]
+func (h *ImpersonationHandler) StopImpersonation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	if err := h.impersonationService.Stop(r.Context(), claims, clientInfo(r)); err != nil {
+		switch err {
+		case errors.ErrNotImpersonating:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrInvalidToken:
+			respondWithError(w, http.StatusUnauthorized, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
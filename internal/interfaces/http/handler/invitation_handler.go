@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type InvitationHandler struct {
+	invitationService service.InvitationService
+	validate          *validator.Validate
+}
+
+func NewInvitationHandler(invitationService service.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+		validate:          validator.New(),
+	}
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+	// Name and Password are required unless the address already has an account
+	Name     string `json:"name" validate:"omitempty,max=100"`
+	Password string `json:"password"`
+}
+
+type InvitationResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	InvitedBy      uuid.UUID  `json:"invited_by"`
+	// Status is pending, accepted, revoked or expired
+	Status     string     `json:"status"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ListInvitations godoc
+// @Summary List invitations
+// @Description List invitations, newest first (requires users:invite). Within an organization only its invitations are listed.
+// @Tags invitations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} InvitationResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /invitations [get]
+func (h *InvitationHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.invitationService.List(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	response := make([]InvitationResponse, 0, len(invitations))
+	for _, invitation := range invitations {
+		response = append(response, newInvitationResponse(invitation))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// CreateInvitation godoc
+// @Summary Invite user
+// @Description Email an invitation to join with the given role (requires users:invite; roles other than the default one also require users:role). Invitations made within an organization add the invitee to it.
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateInvitationRequest true "Invitation details"
+// @Success 201 {object} InvitationResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /invitations [post]
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	invitation, err := h.invitationService.Create(r.Context(), userID, req.Email, req.Role)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidRole:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrForbidden:
+			respondWithError(w, http.StatusForbidden, err)
+		case errors.ErrUserNotFound:
+			respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, newInvitationResponse(invitation))
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke invitation
+// @Description Revoke a pending invitation so its link no longer works (requires users:invite)
+// @Tags invitations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Invitation ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /invitations/{id} [delete]
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.invitationService.Revoke(r.Context(), id); err != nil {
+		switch err {
+		case errors.ErrInvitationNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation godoc
+// @Summary Accept invitation
+// @Description Redeem an invitation link. Creates an account from name and password, or attaches the existing account with the invited address. A password that breaks the password policy is rejected with the reasons under fields.password.
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Param request body AcceptInvitationRequest true "Invitation token and account details"
+// @Success 200 {object} entity.User
+// @Failure 400 {object} errors.ValidationErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Router /auth/invitations/accept [post]
+func (h *InvitationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	user, err := h.invitationService.Accept(r.Context(), req.Token, req.Name, req.Password)
+	if err != nil {
+		if respondWithPasswordError(w, "password", err) {
+			return
+		}
+		switch err {
+		case errors.ErrInvalidInput:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrInvalidToken, errors.ErrTokenExpired:
+			respondWithError(w, http.StatusUnauthorized, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+func newInvitationResponse(invitation *entity.Invitation) InvitationResponse {
+	status := "pending"
+	switch {
+	case invitation.IsAccepted():
+		status = "accepted"
+	case invitation.IsRevoked():
+		status = "revoked"
+	case invitation.IsExpired():
+		status = "expired"
+	}
+
+	return InvitationResponse{
+		ID:             invitation.ID,
+		Email:          invitation.Email,
+		Role:           invitation.Role,
+		OrganizationID: invitation.OrganizationID,
+		InvitedBy:      invitation.InvitedBy,
+		Status:         status,
+		ExpiresAt:      invitation.ExpiresAt,
+		AcceptedAt:     invitation.AcceptedAt,
+		CreatedAt:      invitation.CreatedAt,
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type KeyHandler struct {
+	keyService service.KeyService
+}
+
+func NewKeyHandler(keyService service.KeyService) *KeyHandler {
+	return &KeyHandler{
+		keyService: keyService,
+	}
+}
+
+type PromoteKeyRequest struct {
+	// GracePeriod is a Go duration such as "24h"; the configured default applies when empty
+	GracePeriod string `json:"grace_period"`
+}
+
+// ListKeys godoc
+// @Summary List signing keys
+// @Description List all JWT signing keys and their rotation status (requires keys:manage)
+// @Tags keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.SigningKey
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /admin/keys [get]
+func (h *KeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keyService.List(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+// CreateKey godoc
+// @Summary Create signing key
+// @Description Generate a pending signing key. It is published in the JWKS but does not sign until promoted (requires keys:manage).
+// @Tags keys
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} entity.SigningKey
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /admin/keys [post]
+func (h *KeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.keyService.Create(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, key)
+}
+
+// PromoteKey godoc
+// @Summary Promote signing key
+// @Description Make the key sign new tokens. The previous key keeps verifying for the grace period (requires keys:manage).
+// @Tags keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param kid path string true "Key ID"
+// @Param request body PromoteKeyRequest false "Promotion options"
+// @Success 200 {object} entity.SigningKey
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /admin/keys/{kid}/promote [post]
+func (h *KeyHandler) PromoteKey(w http.ResponseWriter, r *http.Request) {
+	var req PromoteKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+			return
+		}
+	}
+
+	var gracePeriod time.Duration
+	if req.GracePeriod != "" {
+		var err error
+		if gracePeriod, err = time.ParseDuration(req.GracePeriod); err != nil || gracePeriod < 0 {
+			respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+			return
+		}
+	}
+
+	key, err := h.keyService.Promote(r.Context(), chi.URLParam(r, "kid"), gracePeriod)
+	if err != nil {
+		switch err {
+		case errors.ErrSigningKeyNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrSigningKeyExpired:
+			respondWithError(w, http.StatusConflict, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, key)
+}
+
+// RetireKey godoc
+// @Summary Retire signing key
+// @Description Immediately stop a non-active key from verifying tokens (requires keys:manage)
+// @Tags keys
+// @Produce json
+// @Security BearerAuth
+// @Param kid path string true "Key ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Router /admin/keys/{kid}/retire [post]
+func (h *KeyHandler) RetireKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.keyService.Retire(r.Context(), chi.URLParam(r, "kid")); err != nil {
+		switch err {
+		case errors.ErrSigningKeyNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrSigningKeyActive:
+			respondWithError(w, http.StatusConflict, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
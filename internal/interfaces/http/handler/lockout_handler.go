@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type LockoutHandler struct {
+	lockoutService service.LockoutService
+}
+
+func NewLockoutHandler(lockoutService service.LockoutService) *LockoutHandler {
+	return &LockoutHandler{
+		lockoutService: lockoutService,
+	}
+}
+
+// GetLock godoc
+// @Summary Get account lock status
+// @Description Show whether logins are refused for the user after failed attempts (requires users:unlock)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} service.LockStatus
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /users/{id}/lock [get]
+func (h *LockoutHandler) GetLock(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	status, err := h.lockoutService.Status(r.Context(), id)
+	if err != nil {
+		switch err {
+		case errors.ErrUserNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, status)
+}
+
+// Unlock godoc
+// @Summary Unlock account
+// @Description Clear failed login attempts and lift any lock on the user (requires users:unlock)
+// @Tags users
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /users/{id}/lock [delete]
+func (h *LockoutHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.lockoutService.Unlock(r.Context(), id); err != nil {
+		switch err {
+		case errors.ErrUserNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService service.MagicLinkService
+	validate         *validator.Validate
+}
+
+func NewMagicLinkHandler(magicLinkService service.MagicLinkService) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+		validate:         validator.New(),
+	}
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type VerifyMagicLinkRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// RequestMagicLink godoc
+// @Summary Request login link
+// @Description Email a single-use, short-lived login link. The response is the same whether or not the address belongs to an account.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body MagicLinkRequest true "Account email"
+// @Success 202 {object} MessageResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Router /auth/magic-link [post]
+func (h *MagicLinkHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	if err := h.magicLinkService.Request(r.Context(), req.Email, clientInfo(r)); err != nil {
+		switch err {
+		case errors.ErrMagicLinkDisabled:
+			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrTooManyRequests:
+			respondWithError(w, http.StatusTooManyRequests, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, MessageResponse{
+		Message: "If an account exists for this email, a login link has been sent.",
+	})
+}
+
+// VerifyMagicLink godoc
+// @Summary Log in with login link
+// @Description Exchange the token from a login link for an access and refresh token pair. Users with a second factor receive an MFA challenge instead, to be completed at /auth/mfa/verify.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body VerifyMagicLinkRequest true "Token from the login link"
+// @Success 200 {object} TokenResponse
+// @Success 202 {object} MFAChallengeResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 423 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Router /auth/magic-link/verify [post]
+func (h *MagicLinkHandler) VerifyMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req VerifyMagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	result, err := h.magicLinkService.Verify(r.Context(), req.Token, clientInfo(r))
+	if err != nil {
+		switch err {
+		case errors.ErrMagicLinkDisabled:
+			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrInvalidToken, errors.ErrTokenExpired, errors.ErrUnauthorized:
+			respondWithError(w, http.StatusUnauthorized, err)
+		case errors.ErrEmailNotVerified:
+			respondWithError(w, http.StatusForbidden, err)
+		case errors.ErrAccountLocked:
+			respondWithError(w, http.StatusLocked, err)
+		case errors.ErrTooManyRequests:
+			respondWithError(w, http.StatusTooManyRequests, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithLoginResult(w, result)
+}
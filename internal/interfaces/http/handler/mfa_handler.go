@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type MFAHandler struct {
+	mfaService service.MFAService
+	validate   *validator.Validate
+}
+
+func NewMFAHandler(mfaService service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+		validate:   validator.New(),
+	}
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+// EnrollTOTP godoc
+// @Summary Start TOTP enrolment
+// @Description Generate an authenticator secret and recovery codes. They are shown only once; the factor is enforced after confirmation.
+// @Tags mfa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} TOTPEnrollmentResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Router /auth/mfa/totp [post]
+func (h *MFAHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	enrollment, err := h.mfaService.EnrollTOTP(r.Context(), userID)
+	if err != nil {
+		switch err {
+		case errors.ErrMFAAlreadyEnabled:
+			respondWithError(w, http.StatusConflict, err)
+		case errors.ErrUserNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, TOTPEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+		RecoveryCodes:   enrollment.RecoveryCodes,
+	})
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm TOTP enrolment
+// @Description Verify the first code from the authenticator app to enable the factor
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFACodeRequest true "Authenticator code"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Router /auth/mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	if err := h.mfaService.ConfirmTOTP(r.Context(), userID, req.Code); err != nil {
+		switch err {
+		case errors.ErrInvalidMFACode, errors.ErrMFANotEnrolled:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrMFAAlreadyEnabled:
+			respondWithError(w, http.StatusConflict, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DisableTOTP godoc
+// @Summary Disable TOTP
+// @Description Remove the authenticator and recovery codes after verifying a current code
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFACodeRequest true "Authenticator or recovery code"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Router /auth/mfa/totp [delete]
+func (h *MFAHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	if err := h.mfaService.DisableTOTP(r.Context(), userID, req.Code); err != nil {
+		switch err {
+		case errors.ErrInvalidMFACode, errors.ErrMFANotEnrolled:
+			respondWithError(w, http.StatusBadRequest, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+// grantTypeClientCredentials is the only grant the token endpoint supports
+const grantTypeClientCredentials = "client_credentials"
+
+type OAuthHandler struct {
+	clientService service.OAuthClientService
+	validate      *validator.Validate
+}
+
+func NewOAuthHandler(clientService service.OAuthClientService) *OAuthHandler {
+	return &OAuthHandler{
+		clientService: clientService,
+		validate:      validator.New(),
+	}
+}
+
+type CreateOAuthClientRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+}
+
+type OAuthClientResponse struct {
+	// ClientID is the client_id to authenticate with at the token endpoint
+	ClientID  uuid.UUID `json:"client_id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	CreatedBy uuid.UUID `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateOAuthClientResponse struct {
+	OAuthClientResponse
+	// ClientSecret cannot be retrieved again
+	ClientSecret string `json:"client_secret"`
+}
+
+// OAuthTokenResponse is the token response of RFC 6749 section 5.1
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthErrorResponse is the error response of RFC 6749 section 5.2
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// Token godoc
+// @Summary OAuth 2.0 token endpoint
+// @Description Issue an access token to a registered client with the client_credentials grant (RFC 6749 section 4.4). Clients authenticate with HTTP Basic or the client_id and client_secret form fields. The token carries every allowed scope unless a space separated subset is requested.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "Must be client_credentials"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Param scope formData string false "Requested scopes"
+// @Success 200 {object} OAuthTokenResponse
+// @Failure 400 {object} OAuthErrorResponse
+// @Failure 401 {object} OAuthErrorResponse
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_request", errors.ErrInvalidInput)
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != grantTypeClientCredentials {
+		respondWithOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", errors.ErrUnsupportedGrantType)
+		return
+	}
+
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID == "" || secret == "" {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", errors.ErrInvalidClient)
+		return
+	}
+
+	tokens, err := h.clientService.ClientCredentials(r.Context(), clientID, secret, strings.Fields(r.PostForm.Get("scope")))
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidClient:
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", err)
+		case errors.ErrInvalidScope:
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_scope", err)
+		default:
+			respondWithOAuthError(w, http.StatusInternalServerError, "server_error", errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	respondWithJSON(w, http.StatusOK, OAuthTokenResponse{
+		AccessToken: tokens.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(tokens.ExpiresIn.Seconds()),
+		Scope:       strings.Join(tokens.Scopes, " "),
+	})
+}
+
+// ListClients godoc
+// @Summary List OAuth clients
+// @Description List the registered OAuth clients (requires clients:manage). Secrets are never returned.
+// @Tags oauth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} OAuthClientResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /admin/clients [get]
+func (h *OAuthHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.clientService.List(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	response := make([]OAuthClientResponse, 0, len(clients))
+	for _, client := range clients {
+		response = append(response, newOAuthClientResponse(client))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// CreateClient godoc
+// @Summary Register OAuth client
+// @Description Register a service that obtains tokens with the client_credentials grant (requires clients:manage). Scopes are the permissions the client may request. The secret is shown only once.
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateOAuthClientRequest true "Client details"
+// @Success 201 {object} CreateOAuthClientResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /admin/clients [post]
+func (h *OAuthHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req CreateOAuthClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	created, err := h.clientService.Create(r.Context(), req.Name, req.Scopes, userID)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidScope:
+			respondWithError(w, http.StatusBadRequest, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusCreated, CreateOAuthClientResponse{
+		OAuthClientResponse: newOAuthClientResponse(created.Client),
+		ClientSecret:        created.Secret,
+	})
+}
+
+// DeleteClient godoc
+// @Summary Remove OAuth client
+// @Description Remove an OAuth client and revoke the tokens issued to it (requires clients:manage)
+// @Tags oauth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Client ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /admin/clients/{id} [delete]
+func (h *OAuthHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.clientService.Delete(r.Context(), id); err != nil {
+		switch err {
+		case errors.ErrClientNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newOAuthClientResponse(client *entity.OAuthClient) OAuthClientResponse {
+	return OAuthClientResponse{
+		ClientID:  client.ID,
+		Name:      client.Name,
+		Scopes:    client.ScopeList(),
+		CreatedBy: client.CreatedBy,
+		CreatedAt: client.CreatedAt,
+	}
+}
+
+func respondWithOAuthError(w http.ResponseWriter, code int, errorCode string, err error) {
+	respondWithJSON(w, code, OAuthErrorResponse{
+		Error:            errorCode,
+		ErrorDescription: err.Error(),
+	})
+}
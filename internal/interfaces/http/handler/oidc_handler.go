@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type OIDCHandler struct {
+	oidcService service.OIDCService
+}
+
+func NewOIDCHandler(oidcService service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+	}
+}
+
+// Login godoc
+// @Summary Start single sign-on
+// @Description Redirect to the OpenID Connect provider's login page
+// @Tags auth
+// @Success 302 "Redirect to the identity provider"
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 502 {object} errors.ErrorResponse
+// @Router /auth/oidc/login [get]
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, err := h.oidcService.Begin(r.Context())
+	if err != nil {
+		switch err {
+		case errors.ErrExternalLoginDisabled:
+			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrExternalLoginFailed:
+			respondWithError(w, http.StatusBadGateway, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback godoc
+// @Summary Complete single sign-on
+// @Description Redirect target of the OpenID Connect provider. Links the external identity to a user and returns a token pair, or an MFA challenge when the user has a second factor.
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login redirect"
+// @Success 200 {object} TokenResponse
+// @Success 202 {object} MFAChallengeResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Router /auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// The provider reports a denied or failed login through the error parameter
+	if query.Get("error") != "" {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrExternalLoginFailed)
+		return
+	}
+
+	if query.Get("state") == "" || query.Get("code") == "" {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	result, err := h.oidcService.Complete(r.Context(), query.Get("state"), query.Get("code"), clientInfo(r))
+	if err != nil {
+		switch err {
+		case errors.ErrExternalLoginDisabled:
+			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrInvalidToken:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrExternalLoginFailed, errors.ErrUnauthorized:
+			respondWithError(w, http.StatusUnauthorized, err)
+		case errors.ErrUserAlreadyExists:
+			respondWithError(w, http.StatusConflict, err)
+		case errors.ErrEmailNotVerified:
+			respondWithError(w, http.StatusForbidden, err)
+		case errors.ErrAccountLocked:
+			respondWithError(w, http.StatusLocked, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithLoginResult(w, result)
+}
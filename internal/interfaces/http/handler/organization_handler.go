@@ -0,0 +1,356 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type OrganizationHandler struct {
+	organizationService service.OrganizationService
+	validate            *validator.Validate
+}
+
+func NewOrganizationHandler(organizationService service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+		validate:            validator.New(),
+	}
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type SwitchOrganizationRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type InviteMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type OrganizationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MemberResponse struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Email    string    `json:"email"`
+	Name     string    `json:"name"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// ListOrganizations godoc
+// @Summary List organizations
+// @Description List the organizations the authenticated user belongs to, with their role in each
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} OrganizationResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Router /orgs [get]
+func (h *OrganizationHandler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	memberships, err := h.organizationService.ListForUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	response := make([]OrganizationResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		response = append(response, newOrganizationResponse(membership.Organization, membership.Role))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// CreateOrganization godoc
+// @Summary Create organization
+// @Description Create an organization owned by the authenticated user. It becomes the user's active organization if they have none; refresh the token pair to receive it in the tid claim.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateOrganizationRequest true "Organization details"
+// @Success 201 {object} OrganizationResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Router /orgs [post]
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	var req CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	organization, err := h.organizationService.Create(r.Context(), userID, req.Name)
+	if err != nil {
+		switch err {
+		case errors.ErrUserNotFound:
+			respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, newOrganizationResponse(organization, entity.OrgRoleOwner))
+}
+
+// SwitchOrganization godoc
+// @Summary Switch active organization
+// @Description Make the organization the active one of the authenticated user and exchange the refresh token for a token pair scoped to it
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param request body SwitchOrganizationRequest true "Refresh token of the current session"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /orgs/{id}/switch [post]
+func (h *OrganizationHandler) SwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	var req SwitchOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	tokens, err := h.organizationService.Switch(r.Context(), userID, id, req.RefreshToken)
+	if err != nil {
+		switch err {
+		case errors.ErrOrganizationNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrInvalidToken, errors.ErrTokenExpired, errors.ErrTokenRevoked, errors.ErrTokenReused,
+			errors.ErrUnauthorized, errors.ErrUserNotFound:
+			respondWithError(w, http.StatusUnauthorized, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newTokenResponse(tokens))
+}
+
+// ListMembers godoc
+// @Summary List organization members
+// @Description List the members of an organization the authenticated user belongs to
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Success 200 {array} MemberResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /orgs/{id}/members [get]
+func (h *OrganizationHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	memberships, err := h.organizationService.ListMembers(r.Context(), userID, id)
+	if err != nil {
+		switch err {
+		case errors.ErrOrganizationNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	response := make([]MemberResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		response = append(response, newMemberResponse(membership))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// InviteMember godoc
+// @Summary Invite organization member
+// @Description Email an invitation to join the organization with the role (requires the owner or admin role in it; only owners may invite owners). The address becomes a member once the invitation is accepted; the response is the same whether or not it belongs to an account.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param request body InviteMemberRequest true "Invitee details"
+// @Success 202 {object} MessageResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /orgs/{id}/members [post]
+func (h *OrganizationHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	var req InviteMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	if err := h.organizationService.InviteMember(r.Context(), userID, id, req.Email, req.Role); err != nil {
+		switch err {
+		case errors.ErrInvalidRole:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrForbidden:
+			respondWithError(w, http.StatusForbidden, err)
+		case errors.ErrOrganizationNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, MessageResponse{
+		Message: "An invitation to join the organization has been sent.",
+	})
+}
+
+// RemoveMember godoc
+// @Summary Remove organization member
+// @Description Remove a member from the organization. Members may remove themselves; removing others requires the owner or admin role. Tokens of the removed user scoped to the organization are revoked.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param userID path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Router /orgs/{id}/members/{userID} [delete]
+func (h *OrganizationHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(r.Context(), actorID, id, userID); err != nil {
+		switch err {
+		case errors.ErrForbidden:
+			respondWithError(w, http.StatusForbidden, err)
+		case errors.ErrOrganizationNotFound, errors.ErrNotMember:
+			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrLastOwner:
+			respondWithError(w, http.StatusConflict, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newOrganizationResponse(organization *entity.Organization, role string) OrganizationResponse {
+	return OrganizationResponse{
+		ID:        organization.ID,
+		Name:      organization.Name,
+		Role:      role,
+		CreatedAt: organization.CreatedAt,
+	}
+}
+
+func newMemberResponse(membership *entity.Membership) MemberResponse {
+	response := MemberResponse{
+		UserID:   membership.UserID,
+		Role:     membership.Role,
+		JoinedAt: membership.CreatedAt,
+	}
+	if membership.User != nil {
+		response.Email = membership.User.Email
+		response.Name = membership.User.Name
+	}
+	return response
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/policy"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type PolicyHandler struct {
+	engine      *policy.Engine
+	userService service.UserService
+	validate    *validator.Validate
+}
+
+func NewPolicyHandler(engine *policy.Engine, userService service.UserService) *PolicyHandler {
+	return &PolicyHandler{
+		engine:      engine,
+		userService: userService,
+		validate:    validator.New(),
+	}
+}
+
+type ExplainPolicyRequest struct {
+	Action string `json:"action" validate:"required"`
+	// SubjectID evaluates as the access token the user gets from a password
+	// login; Subject attributes are applied on top
+	SubjectID *uuid.UUID        `json:"subject_id"`
+	Subject   policy.Attributes `json:"subject"`
+	// ResourceID loads the attributes of an existing user as the resource;
+	// Resource attributes are applied on top
+	ResourceID *uuid.UUID        `json:"resource_id"`
+	Resource   policy.Attributes `json:"resource"`
+}
+
+type ExplainPolicyResponse struct {
+	Request  policy.Request  `json:"request"`
+	Decision policy.Decision `json:"decision"`
+}
+
+// Explain godoc
+// @Summary Explain a policy decision
+// @Description Evaluate the authorization policy for a subject, action and resource without performing the action, and show the outcome of every rule (requires policy:read)
+// @Tags policy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ExplainPolicyRequest true "Authorization question"
+// @Success 200 {object} ExplainPolicyResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /admin/policy/explain [post]
+func (h *PolicyHandler) Explain(w http.ResponseWriter, r *http.Request) {
+	var req ExplainPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	subject, err := h.subjectAttributes(r, req.SubjectID, req.Subject)
+	if err != nil {
+		respondWithUserLookupError(w, err)
+		return
+	}
+
+	resource, err := h.userAttributes(r, req.ResourceID, req.Resource)
+	if err != nil {
+		respondWithUserLookupError(w, err)
+		return
+	}
+
+	question := policy.Request{
+		Subject:  subject,
+		Action:   req.Action,
+		Resource: resource,
+	}
+	respondWithJSON(w, http.StatusOK, ExplainPolicyResponse{
+		Request:  question,
+		Decision: h.engine.Explain(question),
+	})
+}
+
+// subjectAttributes describes the user as the policy middleware describes
+// their requests, if an id is given, and applies the explicit attributes on
+// top
+func (h *PolicyHandler) subjectAttributes(r *http.Request, id *uuid.UUID, overrides policy.Attributes) (policy.Attributes, error) {
+	attrs := policy.Attributes{}
+	if id != nil {
+		user, err := h.userService.GetByID(r.Context(), *id)
+		if err != nil {
+			return nil, err
+		}
+		attrs = service.SubjectAttributes(service.UserClaims(user, []string{service.AuthMethodPassword}))
+	}
+
+	for name, value := range overrides {
+		attrs[name] = value
+	}
+	return attrs, nil
+}
+
+// userAttributes loads the user's attributes, if an id is given, and
+// applies the explicit attributes on top
+func (h *PolicyHandler) userAttributes(r *http.Request, id *uuid.UUID, overrides policy.Attributes) (policy.Attributes, error) {
+	attrs := policy.Attributes{}
+	if id != nil {
+		user, err := h.userService.GetByID(r.Context(), *id)
+		if err != nil {
+			return nil, err
+		}
+		attrs = policy.UserAttributes(user)
+	}
+
+	for name, value := range overrides {
+		attrs[name] = value
+	}
+	return attrs, nil
+}
+
+func respondWithUserLookupError(w http.ResponseWriter, err error) {
+	switch err {
+	case errors.ErrUserNotFound:
+		respondWithError(w, http.StatusNotFound, err)
+	default:
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+	}
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type RoleHandler struct {
+	roleService service.RoleService
+	validate    *validator.Validate
+}
+
+func NewRoleHandler(roleService service.RoleService) *RoleHandler {
+	return &RoleHandler{
+		roleService: roleService,
+		validate:    validator.New(),
+	}
+}
+
+type CreateRoleRequest struct {
+	// Name is lower case letters, digits, "_" and "-", starting with a letter
+	Name        string   `json:"name" validate:"required,min=2,max=50"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+type UpdateRoleDefinitionRequest struct {
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Description List all roles with their permissions (requires roles:manage)
+// @Tags roles
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.Role
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /admin/roles [get]
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleService.List(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, roles)
+}
+
+// GetRole godoc
+// @Summary Get role
+// @Description Get a role and its permissions (requires roles:manage)
+// @Tags roles
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Success 200 {object} entity.Role
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /admin/roles/{id} [get]
+func (h *RoleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	role, err := h.roleService.GetByID(r.Context(), id)
+	if err != nil {
+		switch err {
+		case errors.ErrRoleNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, role)
+}
+
+// CreateRole godoc
+// @Summary Create role
+// @Description Create a role granting the given permissions (requires roles:manage)
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateRoleRequest true "Role definition"
+// @Success 201 {object} entity.Role
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Router /admin/roles [post]
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req CreateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	role, err := h.roleService.Create(r.Context(), req.Name, req.Description, req.Permissions)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidRole, errors.ErrInvalidPermission:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrRoleAlreadyExists:
+			respondWithError(w, http.StatusConflict, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, role)
+}
+
+// UpdateRole godoc
+// @Summary Update role
+// @Description Replace the description and permissions of a role. The admin role always has every permission. (requires roles:manage)
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Param request body UpdateRoleDefinitionRequest true "Role definition"
+// @Success 200 {object} entity.Role
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /admin/roles/{id} [put]
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	var req UpdateRoleDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	role, err := h.roleService.Update(r.Context(), id, req.Description, req.Permissions)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidPermission:
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.ErrRoleProtected:
+			respondWithError(w, http.StatusForbidden, err)
+		case errors.ErrRoleNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, role)
+}
+
+// DeleteRole godoc
+// @Summary Delete role
+// @Description Delete a custom role that is not assigned to any user (requires roles:manage)
+// @Tags roles
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 409 {object} errors.ErrorResponse
+// @Router /admin/roles/{id} [delete]
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.roleService.Delete(r.Context(), id); err != nil {
+		switch err {
+		case errors.ErrRoleProtected:
+			respondWithError(w, http.StatusForbidden, err)
+		case errors.ErrRoleNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrRoleInUse:
+			respondWithError(w, http.StatusConflict, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPermissions godoc
+// @Summary List permissions
+// @Description List the permissions that can be granted to roles (requires roles:manage)
+// @Tags roles
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.Permission
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /admin/permissions [get]
+func (h *RoleHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.roleService.ListPermissions(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, permissions)
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/interfaces/http/middleware"
+)
+
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session of the token making the request
+	Current bool `json:"current"`
+}
+
+// ListMySessions godoc
+// @Summary List my sessions
+// @Description List the devices the authenticated user is logged in on, most recently active first
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} SessionResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Router /me/sessions [get]
+func (h *SessionHandler) ListMySessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	h.listSessions(w, r, userID)
+}
+
+// RevokeMySession godoc
+// @Summary Revoke my session
+// @Description Log out one device of the authenticated user. Its refresh token stops working and its access tokens are rejected.
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeMySession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	h.revokeSession(w, r, userID, chi.URLParam(r, "id"))
+}
+
+// ListUserSessions godoc
+// @Summary List user sessions
+// @Description List the devices a user is logged in on, most recently active first (requires users:read)
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {array} SessionResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /users/{id}/sessions [get]
+func (h *SessionHandler) ListUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	h.listSessions(w, r, userID)
+}
+
+// RevokeUserSession godoc
+// @Summary Revoke user session
+// @Description Log a user out of one device (requires users:logout)
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param sessionID path string true "Session ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /users/{id}/sessions/{sessionID} [delete]
+func (h *SessionHandler) RevokeUserSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	h.revokeSession(w, r, userID, chi.URLParam(r, "sessionID"))
+}
+
+func (h *SessionHandler) listSessions(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	sessions, err := h.sessionService.List(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		return
+	}
+
+	var currentID string
+	if principal, ok := middleware.PrincipalFromContext(r.Context()); ok {
+		currentID = principal.SessionID
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, newSessionResponse(session, currentID))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *SessionHandler) revokeSession(w http.ResponseWriter, r *http.Request, userID uuid.UUID, rawSessionID string) {
+	sessionID, err := uuid.Parse(rawSessionID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.sessionService.Revoke(r.Context(), userID, sessionID); err != nil {
+		switch err {
+		case errors.ErrSessionNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newSessionResponse(session *entity.Session, currentID string) SessionResponse {
+	return SessionResponse{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    session.ID.String() == currentID,
+	}
+}
@@ -2,14 +2,20 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/repository"
 )
 
 type UserHandler struct {
@@ -26,7 +32,7 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 
 type CreateUserRequest struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
 	Name     string `json:"name" validate:"required"`
 }
 
@@ -36,22 +42,28 @@ type UpdateUserRequest struct {
 
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" validate:"required"`
-	NewPassword string `json:"new_password" validate:"required,min=6"`
+	NewPassword string `json:"new_password" validate:"required"`
 }
 
-type UpdateRoleRequest struct {
-	Role string `json:"role" validate:"required,oneof=admin user"`
+type UpdateRolesRequest struct {
+	Roles []string `json:"roles" validate:"required,min=1,dive,required"`
+}
+
+type DeletedUserResponse struct {
+	*entity.User
+	DeletedAt time.Time `json:"deleted_at"`
 }
 
 // CreateUser godoc
 // @Summary Create new user
-// @Description Create a new user account
+// @Description Create a new user account. Disabled in invite-only mode. A password that breaks the password policy is rejected with the reasons under fields.password.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param request body CreateUserRequest true "User creation request"
 // @Success 201 {object} entity.User
-// @Failure 400 {object} errors.ErrorResponse
+// @Failure 400 {object} errors.ValidationErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
 // @Failure 409 {object} errors.ErrorResponse
 // @Router /users [post]
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -68,7 +80,12 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.userService.Create(r.Context(), req.Email, req.Password, req.Name)
 	if err != nil {
+		if respondWithPasswordError(w, "password", err) {
+			return
+		}
 		switch err {
+		case errors.ErrSignupDisabled:
+			respondWithError(w, http.StatusForbidden, err)
 		case errors.ErrUserAlreadyExists:
 			respondWithError(w, http.StatusConflict, err)
 		default:
@@ -88,6 +105,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} entity.User
+// @Failure 403 {object} errors.ErrorResponse
 // @Failure 404 {object} errors.ErrorResponse
 // @Router /users/{id} [get]
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
@@ -97,18 +115,7 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.GetByID(r.Context(), id)
-	if err != nil {
-		switch err {
-		case errors.ErrUserNotFound:
-			respondWithError(w, http.StatusNotFound, err)
-		default:
-			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
-		}
-		return
-	}
-
-	respondWithJSON(w, http.StatusOK, user)
+	h.getUser(w, r, id)
 }
 
 // UpdateUser godoc
@@ -121,6 +128,7 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 // @Param request body UpdateUserRequest true "User update request"
 // @Success 200 {object} entity.User
 // @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
 // @Failure 404 {object} errors.ErrorResponse
 // @Router /users/{id} [put]
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
@@ -130,52 +138,133 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	h.updateUser(w, r, id)
+}
+
+// DeleteUser godoc
+// @Summary Delete user
+// @Description Delete user by ID. The user can be restored until the retention period ends. Its sessions, tokens and API keys are revoked.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /users/{id} [delete]
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
 		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
 		return
 	}
 
-	if err := h.validate.Struct(req); err != nil {
-		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+	h.deleteUser(w, r, id)
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description Get a paginated list of users matching the filters (requires users:read). Invalid filters are rejected with the reasons under fields.<parameter>.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param q query string false "Words that must all appear in the name or email address"
+// @Param role query string false "Name of a role the user holds"
+// @Param active query bool false "Whether the account is active"
+// @Param created_after query string false "Earliest creation time (RFC 3339)"
+// @Param created_before query string false "Latest creation time (RFC 3339)"
+// @Param email_domain query string false "Domain of the email address"
+// @Param sort query string false "Comma separated fields of name, email, created_at and updated_at, each optionally followed by :asc or :desc"
+// @Success 200 {array} entity.User
+// @Failure 400 {object} errors.ValidationErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /users [get]
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query, fields := parseUserQuery(r.URL.Query())
+	if len(fields) > 0 {
+		respondWithJSON(w, http.StatusBadRequest, errors.NewValidationErrorResponse(
+			http.StatusBadRequest, errors.ErrValidation.Error(), fields,
+		))
 		return
 	}
 
-	user, err := h.userService.Update(r.Context(), id, req.Name)
+	users, total, err := h.userService.List(r.Context(), query)
 	if err != nil {
 		switch err {
-		case errors.ErrUserNotFound:
-			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrInvalidInput:
+			respondWithError(w, http.StatusBadRequest, err)
 		default:
 			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
 		}
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, user)
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	respondWithJSON(w, http.StatusOK, users)
 }
 
-// DeleteUser godoc
-// @Summary Delete user
-// @Description Delete user by ID
+// ChangePassword godoc
+// @Summary Change user password
+// @Description Change user's password. A new password that breaks the password policy is rejected with the reasons under fields.new_password.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
+// @Param request body ChangePasswordRequest true "Password change request"
 // @Success 204 "No Content"
+// @Failure 400 {object} errors.ValidationErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
 // @Failure 404 {object} errors.ErrorResponse
-// @Router /users/{id} [delete]
-func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+// @Router /users/{id}/password [put]
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
 		return
 	}
 
-	if err := h.userService.Delete(r.Context(), id); err != nil {
+	h.changePassword(w, r, id)
+}
+
+// UpdateRoles godoc
+// @Summary Update user roles
+// @Description Replace the roles of a user with roles managed under /admin/roles; the user has the permissions of all of them (requires users:role)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body UpdateRolesRequest true "Roles update request"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /users/{id}/roles [put]
+func (h *UserHandler) UpdateRoles(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	var req UpdateRolesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	if err := h.userService.UpdateRoles(r.Context(), id, req.Roles); err != nil {
 		switch err {
 		case errors.ErrUserNotFound:
 			respondWithError(w, http.StatusNotFound, err)
+		case errors.ErrInvalidRole:
+			respondWithError(w, http.StatusBadRequest, err)
 		default:
 			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
 		}
@@ -185,50 +274,172 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// ListUsers godoc
-// @Summary List users
-// @Description Get paginated list of users
+// ListDeletedUsers godoc
+// @Summary List deleted users
+// @Description Get a paginated list of deleted users that can still be restored, most recently deleted first (requires users:restore)
 // @Tags users
-// @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param page query int false "Page number"
 // @Param limit query int false "Items per page"
-// @Success 200 {array} entity.User
-// @Router /users [get]
-func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+// @Success 200 {array} DeletedUserResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /admin/users/deleted [get]
+func (h *UserHandler) ListDeletedUsers(w http.ResponseWriter, r *http.Request) {
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 
-	users, total, err := h.userService.List(r.Context(), page, limit)
+	users, total, err := h.userService.ListDeleted(r.Context(), page, limit)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
 		return
 	}
 
+	response := make([]DeletedUserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, DeletedUserResponse{User: user, DeletedAt: user.DeletedAt.Time})
+	}
+
 	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
-	respondWithJSON(w, http.StatusOK, users)
+	respondWithJSON(w, http.StatusOK, response)
 }
 
-// ChangePassword godoc
-// @Summary Change user password
-// @Description Change user's password
+// RestoreUser godoc
+// @Summary Restore deleted user
+// @Description Undo the deletion of a user that has not been purged yet (requires users:restore)
 // @Tags users
-// @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "User ID"
-// @Param request body ChangePasswordRequest true "Password change request"
-// @Success 204 "No Content"
+// @Success 200 {object} entity.User
 // @Failure 400 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
 // @Failure 404 {object} errors.ErrorResponse
-// @Router /users/{id}/password [put]
-func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+// @Router /admin/users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
 		return
 	}
 
-	var req ChangePasswordRequest
+	user, err := h.userService.Restore(r.Context(), id)
+	if err != nil {
+		switch err {
+		case errors.ErrUserNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// GetMe godoc
+// @Summary Get current user
+// @Description Get the account of the authenticated user
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.User
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /me [get]
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	h.getUser(w, r, userID)
+}
+
+// UpdateMe godoc
+// @Summary Update current user
+// @Description Update the profile of the authenticated user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateUserRequest true "User update request"
+// @Success 200 {object} entity.User
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /me [patch]
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	h.updateUser(w, r, userID)
+}
+
+// ChangeMyPassword godoc
+// @Summary Change current user password
+// @Description Change the password of the authenticated user. Not allowed with an impersonation token. A new password that breaks the password policy is rejected with the reasons under fields.new_password.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ChangePasswordRequest true "Password change request"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ValidationErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /me/password [put]
+func (h *UserHandler) ChangeMyPassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	h.changePassword(w, r, userID)
+}
+
+// DeleteMe godoc
+// @Summary Delete current user
+// @Description Delete the account of the authenticated user. An admin can restore it until the retention period ends. Its sessions, tokens and API keys are revoked.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Router /me [delete]
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+		return
+	}
+
+	h.deleteUser(w, r, userID)
+}
+
+func (h *UserHandler) getUser(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	user, err := h.userService.GetByID(r.Context(), id)
+	if err != nil {
+		switch err {
+		case errors.ErrUserNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var req UpdateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
 		return
@@ -239,41 +450,36 @@ func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.userService.ChangePassword(r.Context(), id, req.OldPassword, req.NewPassword); err != nil {
+	user, err := h.userService.Update(r.Context(), id, req.Name)
+	if err != nil {
 		switch err {
 		case errors.ErrUserNotFound:
 			respondWithError(w, http.StatusNotFound, err)
-		case errors.ErrInvalidPassword:
-			respondWithError(w, http.StatusBadRequest, err)
 		default:
 			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
 		}
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	respondWithJSON(w, http.StatusOK, user)
 }
 
-// UpdateRole godoc
-// @Summary Update user role
-// @Description Update user's role
-// @Tags users
-// @Accept json
-// @Produce json
-// @Param id path string true "User ID"
-// @Param request body UpdateRoleRequest true "Role update request"
-// @Success 204 "No Content"
-// @Failure 400 {object} errors.ErrorResponse
-// @Failure 404 {object} errors.ErrorResponse
-// @Router /users/{id}/role [put]
-func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
-	id, err := uuid.Parse(chi.URLParam(r, "id"))
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+func (h *UserHandler) deleteUser(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	if err := h.userService.Delete(r.Context(), id); err != nil {
+		switch err {
+		case errors.ErrUserNotFound:
+			respondWithError(w, http.StatusNotFound, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
 		return
 	}
 
-	var req UpdateRoleRequest
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) changePassword(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	var req ChangePasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
 		return
@@ -284,11 +490,14 @@ func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.userService.UpdateRole(r.Context(), id, req.Role); err != nil {
+	if err := h.userService.ChangePassword(r.Context(), id, req.OldPassword, req.NewPassword); err != nil {
+		if respondWithPasswordError(w, "new_password", err) {
+			return
+		}
 		switch err {
 		case errors.ErrUserNotFound:
 			respondWithError(w, http.StatusNotFound, err)
-		case errors.ErrInvalidRole:
+		case errors.ErrInvalidPassword:
 			respondWithError(w, http.StatusBadRequest, err)
 		default:
 			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
@@ -298,3 +507,61 @@ func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// parseUserQuery reads the user list filters from the query string, with the
+// reasons each invalid parameter was rejected
+func parseUserQuery(values url.Values) (repository.UserQuery, map[string][]string) {
+	fields := make(map[string][]string)
+	query := repository.UserQuery{
+		Search:      values.Get("q"),
+		Role:        values.Get("role"),
+		EmailDomain: values.Get("email_domain"),
+	}
+	query.Page, _ = strconv.Atoi(values.Get("page"))
+	query.Limit, _ = strconv.Atoi(values.Get("limit"))
+
+	if raw := values.Get("active"); raw != "" {
+		active, err := strconv.ParseBool(raw)
+		if err != nil {
+			fields["active"] = append(fields["active"], "must be true or false")
+		} else {
+			query.Active = &active
+		}
+	}
+
+	for name, bound := range map[string]*time.Time{
+		"created_after":  &query.CreatedAfter,
+		"created_before": &query.CreatedBefore,
+	} {
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			fields[name] = append(fields[name], "must be an RFC 3339 time")
+			continue
+		}
+		*bound = t
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			field, direction, _ := strings.Cut(strings.TrimSpace(part), ":")
+			if !repository.UserSortFields[field] {
+				fields["sort"] = append(fields["sort"], fmt.Sprintf("cannot sort by %q", field))
+				continue
+			}
+			switch direction {
+			case "", "asc":
+				query.Sort = append(query.Sort, repository.SortOrder{Field: field})
+			case "desc":
+				query.Sort = append(query.Sort, repository.SortOrder{Field: field, Desc: true})
+			default:
+				fields["sort"] = append(fields["sort"], fmt.Sprintf("direction of %s must be asc or desc", field))
+			}
+		}
+	}
+
+	return query, fields
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+type VerificationHandler struct {
+	verificationService service.EmailVerificationService
+	validate            *validator.Validate
+}
+
+func NewVerificationHandler(verificationService service.EmailVerificationService) *VerificationHandler {
+	return &VerificationHandler{
+		verificationService: verificationService,
+		validate:            validator.New(),
+	}
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Confirm the email address using the token from the verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Verification token"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *VerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	if err := h.verificationService.Verify(r.Context(), req.Token); err != nil {
+		switch err {
+		case errors.ErrInvalidToken, errors.ErrTokenExpired:
+			respondWithError(w, http.StatusBadRequest, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification godoc
+// @Summary Resend verification email
+// @Description Send a new verification link. Requests for the same account are throttled.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ResendVerificationRequest true "Account email"
+// @Success 202 {object} MessageResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Router /auth/verify-email/resend [post]
+func (h *VerificationHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrInvalidInput)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.ErrValidation)
+		return
+	}
+
+	if err := h.verificationService.Resend(r.Context(), req.Email); err != nil {
+		switch err {
+		case errors.ErrTooManyRequests:
+			respondWithError(w, http.StatusTooManyRequests, err)
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, MessageResponse{
+		Message: "If the account exists and is not yet verified, a verification email has been sent.",
+	})
+}
@@ -3,37 +3,68 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
 	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/tenant"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
 type contextKey string
 
+const claimsContextKey = contextKey("claims")
+
+// apiKeyHeader carries a personal API key as an alternative to the
+// Authorization header
+const apiKeyHeader = "X-API-Key"
+
 type AuthMiddleware struct {
-	authService service.AuthService
+	authService         service.AuthService
+	apiKeyService       service.APIKeyService
+	roleService         service.RoleService
+	organizationService service.OrganizationService
 }
 
-func NewAuthMiddleware(authService service.AuthService) *AuthMiddleware {
+func NewAuthMiddleware(
+	authService service.AuthService,
+	apiKeyService service.APIKeyService,
+	roleService service.RoleService,
+	organizationService service.OrganizationService,
+) *AuthMiddleware {
 	return &AuthMiddleware{
-		authService: authService,
+		authService:         authService,
+		apiKeyService:       apiKeyService,
+		roleService:         roleService,
+		organizationService: organizationService,
 	}
 }
 
-// Authenticate verifies the JWT token in the request header
+// Authenticate verifies the JWT token or API key in the request headers
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := extractAPIKey(r); key != "" {
+			claims, err := m.apiKeyService.Authenticate(r.Context(), key)
+			if err != nil {
+				respondWithError(w, http.StatusUnauthorized, err)
+				return
+			}
+
+			m.serve(w, r, next, claims)
+			return
+		}
+
 		token := extractToken(r)
 		if token == "" {
 			respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
 			return
 		}
 
-		jwtToken, err := m.authService.ValidateToken(token)
+		jwtToken, err := m.authService.ValidateToken(r.Context(), token)
 		if err != nil {
 			respondWithError(w, http.StatusUnauthorized, err)
 			return
@@ -45,24 +76,65 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		// Add claims to request context
-		ctx := context.WithValue(r.Context(), contextKey("claims"), claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		m.serve(w, r, next, claims)
 	})
 }
 
-// RequireRole checks if the authenticated user has the required role
-func (m *AuthMiddleware) RequireRole(role string) func(http.Handler) http.Handler {
+// serve adds the claims to the request context and calls next. Tokens keep
+// naming an organization after the user leaves it, so membership of the
+// organization is checked on every request.
+func (m *AuthMiddleware) serve(w http.ResponseWriter, r *http.Request, next http.Handler, claims jwt.MapClaims) {
+	ctx, err := withClaims(r.Context(), claims)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	principal, _ := PrincipalFromContext(ctx)
+	if organizationID, ok := tenant.FromContext(ctx); ok && principal.IsUser() {
+		err := m.organizationService.CheckMembership(ctx, organizationID, principal.UserID)
+		switch err {
+		case nil:
+		case errors.ErrNotMember:
+			respondWithError(w, http.StatusUnauthorized, errors.ErrTokenRevoked)
+			return
+		default:
+			respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+			return
+		}
+	}
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequirePermission checks that one of the roles of the authenticated user
+// grants the permission. Role permissions are resolved on every request, so
+// permission changes apply to tokens that were already issued.
+func (m *AuthMiddleware) RequirePermission(permission string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			claims, ok := r.Context().Value(contextKey("claims")).(jwt.MapClaims)
+			claims, ok := ClaimsFromContext(r.Context())
 			if !ok {
 				respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
 				return
 			}
 
-			userRole, ok := claims["role"].(string)
-			if !ok || userRole != role {
+			// OAuth clients have no role; they hold the permissions in their scope
+			if _, ok := service.ClaimClientID(claims); ok {
+				if !service.TokenAllowsScope(claims, permission) {
+					respondWithError(w, http.StatusForbidden, errors.ErrForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			granted, err := m.roleService.HasPermission(r.Context(), service.ClaimRoles(claims), permission)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+				return
+			}
+			if !granted {
 				respondWithError(w, http.StatusForbidden, errors.ErrForbidden)
 				return
 			}
@@ -72,8 +144,117 @@ func (m *AuthMiddleware) RequireRole(role string) func(http.Handler) http.Handle
 	}
 }
 
+// RequireScope checks that the token is not restricted to scopes that
+// exclude the given one. Tokens without a scope claim pass.
+func (m *AuthMiddleware) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+				return
+			}
+
+			if !service.TokenAllowsScope(claims, scope) {
+				// RFC 6750 section 3.1
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+				respondWithError(w, http.StatusForbidden, fmt.Errorf("%w: %s", errors.ErrInsufficientScope, scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireMFA checks that the token was issued after a second factor was
+// verified. OAuth clients authenticate without a person present and are
+// exempt.
+func (m *AuthMiddleware) RequireMFA(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+			return
+		}
+
+		if _, ok := service.ClaimClientID(claims); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		methods, _ := claims["amr"].([]interface{})
+		for _, method := range methods {
+			if method == service.AuthMethodOTP {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		respondWithError(w, http.StatusForbidden, errors.ErrMFARequired)
+	})
+}
+
+// DenyImpersonation rejects requests made with an impersonation token. It
+// guards operations that must be performed by the account owner, such as
+// changing credentials or privileges.
+func (m *AuthMiddleware) DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := PrincipalFromContext(r.Context())
+		if !ok {
+			respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+			return
+		}
+
+		if principal.IsImpersonated() {
+			respondWithError(w, http.StatusForbidden, errors.ErrImpersonating)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClaimsFromContext returns the JWT claims stored by Authenticate
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(jwt.MapClaims)
+	return claims, ok
+}
+
+// ClientFromContext returns the id of the OAuth client that authenticated
+// the request. ok is false when the caller is a user.
+func ClientFromContext(ctx context.Context) (uuid.UUID, bool) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok || principal.IsUser() {
+		return uuid.Nil, false
+	}
+	return principal.ClientID, true
+}
+
 // Helper functions
 
+// withClaims stores the claims and the principal they describe in ctx and
+// scopes data access to the organization named by the tenant claim, if any
+func withClaims(ctx context.Context, claims jwt.MapClaims) (context.Context, error) {
+	principal, err := newPrincipal(claims)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, claimsContextKey, claims)
+	ctx = context.WithValue(ctx, principalContextKey, principal)
+
+	tid, ok := claims[service.TenantClaim].(string)
+	if !ok {
+		return ctx, nil
+	}
+
+	organizationID, err := uuid.Parse(tid)
+	if err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+	return tenant.WithID(ctx, organizationID), nil
+}
+
 func extractToken(r *http.Request) string {
 	bearerToken := r.Header.Get("Authorization")
 	strArr := strings.Split(bearerToken, " ")
@@ -83,6 +264,20 @@ func extractToken(r *http.Request) string {
 	return ""
 }
 
+// extractAPIKey returns a key sent in the X-API-Key header or with the
+// ApiKey authorization scheme
+func extractAPIKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+
+	scheme, key, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "ApiKey") {
+		return key
+	}
+	return ""
+}
+
 func respondWithError(w http.ResponseWriter, code int, err error) {
 	response := errors.NewErrorResponse(code, err.Error())
 	w.Header().Set("Content-Type", "application/json")
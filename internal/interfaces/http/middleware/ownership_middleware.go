@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+// OwnerResolver returns the id of the user who owns the resource addressed by
+// the request. It may return errors.ErrInvalidInput for a malformed
+// reference and errors.ErrNotFound or errors.ErrUserNotFound for a missing
+// resource.
+type OwnerResolver func(r *http.Request) (uuid.UUID, error)
+
+// OwnerFromURLParam resolves the owner from a path parameter holding a user
+// id, for resources that are users themselves
+func OwnerFromURLParam(name string) OwnerResolver {
+	return func(r *http.Request) (uuid.UUID, error) {
+		id, err := uuid.Parse(chi.URLParam(r, name))
+		if err != nil {
+			return uuid.Nil, errors.ErrInvalidInput
+		}
+		return id, nil
+	}
+}
+
+// RequireOwnerOr lets the owner of the resource through and sends everyone
+// else through the fallback middleware, typically the admin checks. Without
+// a fallback, only the owner is allowed.
+func (m *AuthMiddleware) RequireOwnerOr(owner OwnerResolver, fallback ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		var other http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			respondWithError(w, http.StatusForbidden, errors.ErrForbidden)
+		})
+		if len(fallback) > 0 {
+			other = chi.Chain(fallback...).Handler(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+				return
+			}
+
+			ownerID, err := owner(r)
+			if err != nil {
+				switch err {
+				case errors.ErrInvalidInput:
+					respondWithError(w, http.StatusBadRequest, err)
+				case errors.ErrNotFound, errors.ErrUserNotFound:
+					respondWithError(w, http.StatusNotFound, err)
+				default:
+					respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+				}
+				return
+			}
+
+			if principal.IsUser() && principal.UserID == ownerID {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			other.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/policy"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// ResourceLoader returns the attributes of the resource addressed by the
+// request, with the same errors as OwnerResolver
+type ResourceLoader func(r *http.Request) (policy.Attributes, error)
+
+type PolicyMiddleware struct {
+	engine      *policy.Engine
+	userService service.UserService
+}
+
+func NewPolicyMiddleware(engine *policy.Engine, userService service.UserService) *PolicyMiddleware {
+	return &PolicyMiddleware{
+		engine:      engine,
+		userService: userService,
+	}
+}
+
+// Authorize evaluates the policy for the action on the resource. Denials
+// are logged with the deciding rule; clients only see ErrForbidden. A nil
+// loader evaluates the action without resource attributes.
+func (m *PolicyMiddleware) Authorize(action string, resource ResourceLoader) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				respondWithError(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+				return
+			}
+
+			req := policy.Request{
+				Subject:  service.SubjectAttributes(claims),
+				Action:   action,
+				Resource: policy.Attributes{},
+			}
+			if resource != nil {
+				attrs, err := resource(r)
+				if err != nil {
+					switch err {
+					case errors.ErrInvalidInput:
+						respondWithError(w, http.StatusBadRequest, err)
+					case errors.ErrUnauthorized:
+						respondWithError(w, http.StatusUnauthorized, err)
+					case errors.ErrNotFound, errors.ErrUserNotFound:
+						respondWithError(w, http.StatusNotFound, err)
+					default:
+						respondWithError(w, http.StatusInternalServerError, errors.ErrInternalServer)
+					}
+					return
+				}
+				req.Resource = attrs
+			}
+
+			decision := m.engine.Evaluate(req)
+			if !decision.Allowed {
+				log.Info().
+					Interface("subject", req.Subject["id"]).
+					Str("action", action).
+					Str("rule", decision.Rule).
+					Msg(decision.Reason)
+				respondWithError(w, http.StatusForbidden, errors.ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserFromURLParam loads the user whose id is in the path parameter
+func (m *PolicyMiddleware) UserFromURLParam(name string) ResourceLoader {
+	return func(r *http.Request) (policy.Attributes, error) {
+		id, err := uuid.Parse(chi.URLParam(r, name))
+		if err != nil {
+			return nil, errors.ErrInvalidInput
+		}
+
+		user, err := m.userService.GetByID(r.Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		return policy.UserAttributes(user), nil
+	}
+}
+
+// CurrentUser loads the authenticated user, for routes that act on the
+// caller's own account. OAuth clients are not users and are rejected with
+// errors.ErrUnauthorized.
+func (m *PolicyMiddleware) CurrentUser() ResourceLoader {
+	return func(r *http.Request) (policy.Attributes, error) {
+		principal, ok := PrincipalFromContext(r.Context())
+		if !ok || !principal.IsUser() {
+			return nil, errors.ErrUnauthorized
+		}
+
+		user, err := m.userService.GetByID(r.Context(), principal.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return policy.UserAttributes(user), nil
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/service"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/errors"
+)
+
+const principalContextKey = contextKey("principal")
+
+// Principal is the caller authenticated by Authenticate, read from the
+// token claims
+type Principal struct {
+	// UserID is the token subject. It is uuid.Nil for OAuth clients.
+	UserID uuid.UUID
+	// ClientID is the OAuth client the token was issued to, if any
+	ClientID uuid.UUID
+	Email    string
+	Roles    []string
+	// SessionID is the login session of the token; empty for API keys
+	SessionID string
+	// ActorID is the admin acting through an impersonation token, if any
+	ActorID uuid.UUID
+	// Claims are the raw token claims, for services that take them
+	Claims jwt.MapClaims
+}
+
+// IsUser reports whether the principal is a user rather than an OAuth client
+func (p *Principal) IsUser() bool {
+	return p.UserID != uuid.Nil
+}
+
+// IsImpersonated reports whether an admin is acting as the user
+func (p *Principal) IsImpersonated() bool {
+	return p.ActorID != uuid.Nil
+}
+
+// PrincipalFromContext returns the caller authenticated by Authenticate
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey).(*Principal)
+	return principal, ok
+}
+
+func newPrincipal(claims jwt.MapClaims) (*Principal, error) {
+	principal := &Principal{Claims: claims}
+	principal.Email, _ = claims["email"].(string)
+	principal.Roles = service.ClaimRoles(claims)
+	principal.SessionID, _ = claims["sid"].(string)
+
+	actorID, _, err := service.ClaimActorID(claims)
+	if err != nil {
+		return nil, err
+	}
+	principal.ActorID = actorID
+
+	if clientID, ok := service.ClaimClientID(claims); ok {
+		principal.ClientID = clientID
+		return principal, nil
+	}
+
+	sub, err := claims.GetSubject()
+	if err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+	if principal.UserID, err = uuid.Parse(sub); err != nil {
+		return nil, errors.ErrInvalidToken
+	}
+	return principal, nil
+}
@@ -5,6 +5,9 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httprate"
+	"github.com/mrfansi/go-api-boilerplate/internal/application/policy"
+	"github.com/mrfansi/go-api-boilerplate/internal/domain/entity"
+	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/config"
 	"github.com/mrfansi/go-api-boilerplate/internal/infrastructure/container"
 	"github.com/mrfansi/go-api-boilerplate/internal/interfaces/http/handler"
 	"github.com/mrfansi/go-api-boilerplate/internal/interfaces/http/middleware"
@@ -20,27 +23,111 @@ func NewRouter(c *container.Container) (http.Handler, error) {
 	var (
 		authHandler      *handler.AuthHandler
 		userHandler      *handler.UserHandler
+		keyHandler       *handler.KeyHandler
+		mfaHandler       *handler.MFAHandler
+		passwordHandler  *handler.PasswordHandler
+		verifyHandler    *handler.VerificationHandler
+		lockoutHandler   *handler.LockoutHandler
+		oidcHandler      *handler.OIDCHandler
+		apiKeyHandler    *handler.APIKeyHandler
+		roleHandler      *handler.RoleHandler
+		policyHandler    *handler.PolicyHandler
+		orgHandler       *handler.OrganizationHandler
+		inviteHandler    *handler.InvitationHandler
+		imperHandler     *handler.ImpersonationHandler
+		auditHandler     *handler.AuditHandler
+		oauthHandler     *handler.OAuthHandler
+		sessionHandler   *handler.SessionHandler
+		magicLinkHandler *handler.MagicLinkHandler
 		authMiddleware   *middleware.AuthMiddleware
+		policyMiddleware *middleware.PolicyMiddleware
 		loggerMiddleware *middleware.LoggerMiddleware
 		corsMiddleware   *middleware.CorsMiddleware
+		cfg              *config.Config
 	)
 
 	if err := c.Resolve(func(
 		ah *handler.AuthHandler,
 		uh *handler.UserHandler,
+		kh *handler.KeyHandler,
+		mh *handler.MFAHandler,
+		ph *handler.PasswordHandler,
+		vh *handler.VerificationHandler,
+		loh *handler.LockoutHandler,
+		oh *handler.OIDCHandler,
+		akh *handler.APIKeyHandler,
+		rh *handler.RoleHandler,
+		plh *handler.PolicyHandler,
+		orh *handler.OrganizationHandler,
+		ih *handler.InvitationHandler,
+		imh *handler.ImpersonationHandler,
+		auh *handler.AuditHandler,
+		oah *handler.OAuthHandler,
+		sh *handler.SessionHandler,
+		mlh *handler.MagicLinkHandler,
 		am *middleware.AuthMiddleware,
+		pm *middleware.PolicyMiddleware,
 		lm *middleware.LoggerMiddleware,
 		cm *middleware.CorsMiddleware,
+		conf *config.Config,
 	) {
 		authHandler = ah
 		userHandler = uh
+		keyHandler = kh
+		mfaHandler = mh
+		passwordHandler = ph
+		verifyHandler = vh
+		lockoutHandler = loh
+		oidcHandler = oh
+		apiKeyHandler = akh
+		roleHandler = rh
+		policyHandler = plh
+		orgHandler = orh
+		inviteHandler = ih
+		imperHandler = imh
+		auditHandler = auh
+		oauthHandler = oah
+		sessionHandler = sh
+		magicLinkHandler = mlh
 		authMiddleware = am
+		policyMiddleware = pm
 		loggerMiddleware = lm
 		corsMiddleware = cm
+		cfg = conf
 	}); err != nil {
 		return nil, err
 	}
 
+	// Privileged routes require a verified second factor when configured.
+	// Each permission doubles as the scope a restricted token needs.
+	requirePermission := func(permission string) []func(http.Handler) http.Handler {
+		chain := []func(http.Handler) http.Handler{
+			authMiddleware.RequireScope(permission),
+			authMiddleware.RequirePermission(permission),
+		}
+		if cfg.MFA.RequireForAdmins {
+			chain = append(chain, authMiddleware.RequireMFA)
+		}
+		return chain
+	}
+
+	// Users manage their own account; acting on others needs the permission.
+	// Owners still need the scope, so a read-only token stays read-only.
+	ownerOr := func(permission string) func(http.Handler) http.Handler {
+		return chi.Chain(
+			authMiddleware.RequireScope(permission),
+			authMiddleware.RequireOwnerOr(middleware.OwnerFromURLParam("id"), requirePermission(permission)...),
+		).Handler
+	}
+
+	// Credentials and privileges are only changed by the account owner
+	ownerOnly := authMiddleware.DenyImpersonation
+
+	// The attribute policy is checked after the role permissions
+	targetUser := policyMiddleware.UserFromURLParam("id")
+	currentUser := policyMiddleware.CurrentUser()
+	authorize := policyMiddleware.Authorize
+
 	// Global middleware
 	r.Use(corsMiddleware.Cors)
 	r.Use(loggerMiddleware.Logger)
@@ -52,6 +139,12 @@ func NewRouter(c *container.Container) (http.Handler, error) {
 		w.Write([]byte("OK"))
 	})
 
+	// Public signing keys
+	r.Get("/.well-known/jwks.json", authHandler.JWKS)
+
+	// OAuth 2.0 token endpoint for registered clients
+	r.Post("/oauth/token", oauthHandler.Token)
+
 	// Metrics endpoint
 	r.Handle("/metrics", promhttp.Handler())
 
@@ -65,32 +158,170 @@ func NewRouter(c *container.Container) (http.Handler, error) {
 		// Public routes
 		r.Group(func(r chi.Router) {
 			r.Post("/auth/login", authHandler.Login)
-			r.Post("/users", userHandler.CreateUser) // Moved to public routes
+			r.Post("/auth/refresh", authHandler.RefreshToken)
+			r.Post("/auth/mfa/verify", authHandler.VerifyMFA)
+			r.Post("/auth/password/forgot", passwordHandler.ForgotPassword)
+			r.Post("/auth/password/reset", passwordHandler.ResetPassword)
+			r.Post("/auth/verify-email", verifyHandler.VerifyEmail)
+			r.Post("/auth/verify-email/resend", verifyHandler.ResendVerification)
+			r.Post("/auth/magic-link", magicLinkHandler.RequestMagicLink)
+			r.Post("/auth/magic-link/verify", magicLinkHandler.VerifyMagicLink)
+			r.Get("/auth/oidc/login", oidcHandler.Login)
+			r.Get("/auth/oidc/callback", oidcHandler.Callback)
+			r.Post("/auth/invitations/accept", inviteHandler.AcceptInvitation)
+		})
+
+		// User routes
+		r.Route("/users", func(r chi.Router) {
+			// Public signup; it shares the subrouter so the protected routes
+			// below don't shadow it
+			r.Post("/", userHandler.CreateUser)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.Authenticate)
+
+				r.With(requirePermission(entity.PermissionUsersRead)...).
+					With(authorize(policy.ActionUsersList, nil)).
+					Get("/", userHandler.ListUsers)
+
+				r.Route("/{id}", func(r chi.Router) {
+					r.With(ownerOr(entity.PermissionUsersRead), authorize(policy.ActionUsersRead, targetUser)).
+						Get("/", userHandler.GetUser)
+					r.With(ownerOr(entity.PermissionUsersWrite), authorize(policy.ActionUsersUpdate, targetUser)).
+						Put("/", userHandler.UpdateUser)
+					r.With(ownerOr(entity.PermissionUsersDelete), authorize(policy.ActionUsersDelete, targetUser)).
+						Delete("/", userHandler.DeleteUser)
+					r.With(ownerOnly, ownerOr(entity.PermissionUsersWrite), authorize(policy.ActionUsersChangePassword, targetUser)).
+						Put("/password", userHandler.ChangePassword)
+
+					r.With(ownerOnly).
+						With(requirePermission(entity.PermissionUsersRole)...).
+						With(authorize(policy.ActionUsersUpdateRole, targetUser)).
+						Put("/roles", userHandler.UpdateRoles)
+					r.With(requirePermission(entity.PermissionUsersLogout)...).
+						With(authorize(policy.ActionUsersLogout, targetUser)).
+						Post("/logout", authHandler.LogoutUser)
+					r.With(requirePermission(entity.PermissionUsersRead)...).
+						With(authorize(policy.ActionUsersRead, targetUser)).
+						Get("/sessions", sessionHandler.ListUserSessions)
+					r.With(requirePermission(entity.PermissionUsersLogout)...).
+						With(authorize(policy.ActionUsersLogout, targetUser)).
+						Delete("/sessions/{sessionID}", sessionHandler.RevokeUserSession)
+					r.With(requirePermission(entity.PermissionUsersUnlock)...).
+						With(authorize(policy.ActionUsersUnlock, targetUser)).
+						Get("/lock", lockoutHandler.GetLock)
+					r.With(requirePermission(entity.PermissionUsersUnlock)...).
+						With(authorize(policy.ActionUsersUnlock, targetUser)).
+						Delete("/lock", lockoutHandler.Unlock)
+				})
+			})
 		})
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(authMiddleware.Authenticate)
 
-			// Auth routes
-			r.Post("/auth/refresh", authHandler.RefreshToken)
+			// Auth routes; any token may revoke or narrow itself
+			r.Post("/auth/logout", authHandler.Logout)
+			r.Post("/auth/token/scoped", authHandler.ScopedToken)
 
-			// User routes
-			r.Route("/users", func(r chi.Router) {
-				r.Get("/", userHandler.ListUsers)
+			// Impersonation routes; impersonation tokens can't start another one
+			r.With(ownerOnly).
+				With(requirePermission(entity.PermissionUsersImpersonate)...).
+				Post("/auth/impersonate/{id}", imperHandler.StartImpersonation)
+			r.Post("/auth/impersonate/stop", imperHandler.StopImpersonation)
 
-				r.Route("/{id}", func(r chi.Router) {
-					r.Get("/", userHandler.GetUser)
-					r.Put("/", userHandler.UpdateUser)
-					r.Delete("/", userHandler.DeleteUser)
-					r.Put("/password", userHandler.ChangePassword)
-
-					// Admin only routes
-					r.Group(func(r chi.Router) {
-						r.Use(authMiddleware.RequireRole("admin"))
-						r.Put("/role", userHandler.UpdateRole)
-					})
+			// Current user routes; the checks an owner passes under /users/{id}
+			r.With(authMiddleware.RequireScope(entity.PermissionUsersRead), authorize(policy.ActionUsersRead, currentUser)).
+				Get("/me", userHandler.GetMe)
+			r.With(authMiddleware.RequireScope(entity.PermissionUsersWrite), authorize(policy.ActionUsersUpdate, currentUser)).
+				Patch("/me", userHandler.UpdateMe)
+			r.With(authMiddleware.RequireScope(entity.PermissionUsersDelete), authorize(policy.ActionUsersDelete, currentUser)).
+				Delete("/me", userHandler.DeleteMe)
+			r.With(ownerOnly, authMiddleware.RequireScope(entity.PermissionUsersWrite), authorize(policy.ActionUsersChangePassword, currentUser)).
+				Put("/me/password", userHandler.ChangeMyPassword)
+
+			// Self-service account routes
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequireScope(entity.ScopeAccount))
+
+				r.With(ownerOnly).Post("/auth/logout/all", authHandler.LogoutAll)
+				r.With(ownerOnly).Post("/auth/mfa/totp", mfaHandler.EnrollTOTP)
+				r.With(ownerOnly).Post("/auth/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+				r.With(ownerOnly).Delete("/auth/mfa/totp", mfaHandler.DisableTOTP)
+
+				// Session routes
+				r.Route("/me/sessions", func(r chi.Router) {
+					r.Get("/", sessionHandler.ListMySessions)
+					r.Delete("/{id}", sessionHandler.RevokeMySession)
+				})
+
+				// API key routes
+				r.Route("/api-keys", func(r chi.Router) {
+					r.Get("/", apiKeyHandler.ListAPIKeys)
+					r.With(ownerOnly).Post("/", apiKeyHandler.CreateAPIKey)
+					r.Delete("/{id}", apiKeyHandler.RevokeAPIKey)
+				})
+
+				// Organization routes; roles within an organization are checked by the service
+				r.Route("/orgs", func(r chi.Router) {
+					r.Get("/", orgHandler.ListOrganizations)
+					r.Post("/", orgHandler.CreateOrganization)
+					r.Post("/{id}/switch", orgHandler.SwitchOrganization)
+					r.Get("/{id}/members", orgHandler.ListMembers)
+					r.Post("/{id}/members", orgHandler.InviteMember)
+					r.Delete("/{id}/members/{userID}", orgHandler.RemoveMember)
+				})
+			})
+
+			// Invitation routes
+			r.Route("/invitations", func(r chi.Router) {
+				r.Use(requirePermission(entity.PermissionUsersInvite)...)
+
+				r.Get("/", inviteHandler.ListInvitations)
+				r.Post("/", inviteHandler.CreateInvitation)
+				r.Delete("/{id}", inviteHandler.RevokeInvitation)
+			})
+
+			// Admin routes
+			r.Route("/admin", func(r chi.Router) {
+				r.Route("/keys", func(r chi.Router) {
+					r.Use(requirePermission(entity.PermissionKeysManage)...)
+
+					r.Get("/", keyHandler.ListKeys)
+					r.Post("/", keyHandler.CreateKey)
+					r.Post("/{kid}/promote", keyHandler.PromoteKey)
+					r.Post("/{kid}/retire", keyHandler.RetireKey)
+				})
+
+				r.Route("/clients", func(r chi.Router) {
+					r.Use(requirePermission(entity.PermissionClientsManage)...)
+
+					r.Get("/", oauthHandler.ListClients)
+					r.Post("/", oauthHandler.CreateClient)
+					r.Delete("/{id}", oauthHandler.DeleteClient)
 				})
+
+				r.Route("/roles", func(r chi.Router) {
+					r.Use(requirePermission(entity.PermissionRolesManage)...)
+
+					r.Get("/", roleHandler.ListRoles)
+					r.Post("/", roleHandler.CreateRole)
+					r.Get("/{id}", roleHandler.GetRole)
+					r.Put("/{id}", roleHandler.UpdateRole)
+					r.Delete("/{id}", roleHandler.DeleteRole)
+				})
+
+				r.Route("/users", func(r chi.Router) {
+					r.Use(requirePermission(entity.PermissionUsersRestore)...)
+
+					r.With(authorize(policy.ActionUsersList, nil)).Get("/deleted", userHandler.ListDeletedUsers)
+					r.With(authorize(policy.ActionUsersRestore, nil)).Post("/{id}/restore", userHandler.RestoreUser)
+				})
+
+				r.With(requirePermission(entity.PermissionRolesManage)...).Get("/permissions", roleHandler.ListPermissions)
+				r.With(requirePermission(entity.PermissionPolicyRead)...).Post("/policy/explain", policyHandler.Explain)
+				r.With(requirePermission(entity.PermissionAuditRead)...).Get("/audit-events", auditHandler.ListAuditEvents)
 			})
 		})
 	})
@@ -0,0 +1,201 @@
+// Package password hashes and verifies passwords. New hashes use the
+// configured algorithm; hashes of every supported algorithm remain
+// verifiable so stored hashes can be upgraded as users log in.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported algorithms
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+var (
+	// ErrMismatch is returned when a password does not match its hash
+	ErrMismatch = errors.New("password does not match")
+	// ErrUnsupportedHash is returned for hashes of an unknown format
+	ErrUnsupportedHash = errors.New("unsupported password hash")
+	// ErrUnsupportedAlgorithm is returned for an unknown configured algorithm
+	ErrUnsupportedAlgorithm = errors.New("unsupported password hashing algorithm")
+)
+
+// Argon2idParams are the cost parameters of argon2id (RFC 9106)
+type Argon2idParams struct {
+	// Memory is in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Config selects the algorithm for new hashes and its parameters
+type Config struct {
+	Algorithm  string
+	Argon2id   Argon2idParams
+	BcryptCost int
+}
+
+// DefaultConfig hashes with argon2id using the second recommended option of
+// RFC 9106, section 4
+func DefaultConfig() Config {
+	return Config{
+		Algorithm: Argon2id,
+		Argon2id: Argon2idParams{
+			Memory:      64 * 1024,
+			Iterations:  3,
+			Parallelism: 4,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+		BcryptCost: bcrypt.DefaultCost,
+	}
+}
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes of any supported algorithm
+type Hasher struct {
+	config Config
+}
+
+func NewHasher(cfg Config) (*Hasher, error) {
+	switch cfg.Algorithm {
+	case Argon2id:
+		p := cfg.Argon2id
+		if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 || p.SaltLength == 0 || p.KeyLength == 0 {
+			return nil, fmt.Errorf("argon2id parameters must be positive")
+		}
+	case Bcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, cfg.Algorithm)
+	}
+
+	return &Hasher{config: cfg}, nil
+}
+
+// Hash encodes the password with the configured algorithm. Argon2id hashes
+// use the PHC string format; bcrypt hashes use its modular crypt format.
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.config.Algorithm == Bcrypt {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.config.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	}
+
+	p := h.config.Argon2id
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return encodeArgon2id(p, salt, key), nil
+}
+
+// Verify returns ErrMismatch when the password does not match the hash
+func (h *Hasher) Verify(encoded, password string) error {
+	switch {
+	case isArgon2id(encoded):
+		p, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return err
+		}
+		candidate := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+		if subtle.ConstantTimeCompare(key, candidate) != 1 {
+			return ErrMismatch
+		}
+		return nil
+	case isBcrypt(encoded):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return ErrMismatch
+		}
+		return err
+	default:
+		return ErrUnsupportedHash
+	}
+}
+
+// NeedsRehash reports whether the hash was made with another algorithm or
+// with parameters other than the configured ones
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	switch h.config.Algorithm {
+	case Bcrypt:
+		if !isBcrypt(encoded) {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.config.BcryptCost
+	default:
+		if !isArgon2id(encoded) {
+			return true
+		}
+		p, _, _, err := decodeArgon2id(encoded)
+		return err != nil || p != h.config.Argon2id
+	}
+}
+
+func isArgon2id(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// encodeArgon2id formats $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+// with unpadded standard base64, as in the PHC string format
+func encodeArgon2id(p Argon2idParams, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var p Argon2idParams
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrUnsupportedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil ||
+		p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return p, nil, nil, ErrUnsupportedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrUnsupportedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, ErrUnsupportedHash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,103 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testConfig keeps argon2id cheap; the format is the same at any cost
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Argon2id.Memory = 1024
+	cfg.Argon2id.Iterations = 1
+	cfg.Argon2id.Parallelism = 1
+	cfg.BcryptCost = bcrypt.MinCost
+	return cfg
+}
+
+func TestHasher_Argon2id(t *testing.T) {
+	h, err := NewHasher(testConfig())
+	require.NoError(t, err)
+
+	encoded, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	assert.NoError(t, h.Verify(encoded, "correct horse"))
+	assert.Equal(t, ErrMismatch, h.Verify(encoded, "battery staple"))
+	assert.False(t, h.NeedsRehash(encoded))
+
+	// The same password never hashes to the same string
+	again, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, encoded, again)
+}
+
+func TestHasher_ReferenceVector(t *testing.T) {
+	// Hash produced by the argon2 reference implementation:
+	// echo -n password | argon2 somesalt -id -t 2 -m 16 -p 1 -l 32
+	encoded := "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+
+	h, err := NewHasher(testConfig())
+	require.NoError(t, err)
+	assert.NoError(t, h.Verify(encoded, "password"))
+	assert.Equal(t, ErrMismatch, h.Verify(encoded, "Password"))
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	cfg := testConfig()
+	h, err := NewHasher(cfg)
+	require.NoError(t, err)
+
+	t.Run("Bcrypt", func(t *testing.T) {
+		legacy, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+		require.NoError(t, err)
+
+		assert.NoError(t, h.Verify(string(legacy), "secret"))
+		assert.Equal(t, ErrMismatch, h.Verify(string(legacy), "wrong"))
+		assert.True(t, h.NeedsRehash(string(legacy)))
+	})
+
+	t.Run("RaisedCost", func(t *testing.T) {
+		encoded, err := h.Hash("secret")
+		require.NoError(t, err)
+
+		stronger := cfg
+		stronger.Argon2id.Iterations = 2
+		upgraded, err := NewHasher(stronger)
+		require.NoError(t, err)
+
+		assert.NoError(t, upgraded.Verify(encoded, "secret"))
+		assert.True(t, upgraded.NeedsRehash(encoded))
+	})
+
+	t.Run("BcryptConfigured", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Algorithm = Bcrypt
+		b, err := NewHasher(cfg)
+		require.NoError(t, err)
+
+		encoded, err := b.Hash("secret")
+		require.NoError(t, err)
+		assert.NoError(t, b.Verify(encoded, "secret"))
+		assert.False(t, b.NeedsRehash(encoded))
+
+		argon, _ := h.Hash("secret")
+		assert.NoError(t, b.Verify(argon, "secret"))
+		assert.True(t, b.NeedsRehash(argon))
+	})
+}
+
+func TestHasher_Invalid(t *testing.T) {
+	_, err := NewHasher(Config{Algorithm: "md5"})
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+
+	h, err := NewHasher(testConfig())
+	require.NoError(t, err)
+	assert.Equal(t, ErrUnsupportedHash, h.Verify("plaintext", "plaintext"))
+	assert.Equal(t, ErrUnsupportedHash, h.Verify("$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5", "secret"))
+}